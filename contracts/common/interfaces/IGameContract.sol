@@ -32,6 +32,12 @@ interface IGameContract {
         Team team;
     }
 
+    struct VoteInput {
+        address player;
+        uint32 chainId;
+        Team team;
+    }
+
     // Events
     event GameCreated(
         uint256 indexed gameId,
@@ -57,6 +63,8 @@ interface IGameContract {
 
     function addVote(address player, uint32 chainId, Team team) external;
 
+    function addVotes(VoteInput[] calldata votes) external;
+
     function endGame(GameResult result) external;
 
     // View Functions
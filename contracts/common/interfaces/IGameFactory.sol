@@ -38,6 +38,11 @@ interface IGameFactory {
         IGameContract.Team team
     ) external;
 
+    function addVotes(
+        uint256 gameId,
+        IGameContract.VoteInput[] calldata votes
+    ) external;
+
     // View Functions
     function getGameContractAddress(
         uint256 gameId
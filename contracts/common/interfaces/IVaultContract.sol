@@ -15,6 +15,12 @@ interface IVaultContract {
         bool isSupported;
     }
 
+    struct RewardTransfer {
+        address recipient;
+        uint256 amount;
+        uint256 destinationChainId;
+    }
+
     // Events
     event StakeDeposited(
         address indexed playerAddress,
@@ -30,6 +36,23 @@ interface IVaultContract {
         uint64 nonce
     );
 
+    event MintedStakesCredited(uint256 amount, uint256 newTotal);
+
+    event RewardTransferSucceeded(
+        uint256 indexed gameId,
+        uint256 index,
+        address indexed recipient,
+        uint256 amount
+    );
+
+    event RewardTransferFailed(
+        uint256 indexed gameId,
+        uint256 index,
+        address indexed recipient,
+        uint256 amount,
+        bytes reason
+    );
+
     // Core Functions
     function stake(
         address playerAddress,
@@ -37,6 +60,15 @@ interface IVaultContract {
         uint256 amount
     ) external;
 
+    function stakeWithSignature(
+        address playerAddress,
+        uint256 gameId,
+        uint256 amount,
+        uint256 nonce,
+        uint256 deadline,
+        bytes calldata signature
+    ) external;
+
     function transferRewardsCrossChain(
         uint256 gameId,
         uint256 amount,
@@ -46,6 +78,13 @@ interface IVaultContract {
         uint256 maxFee
     ) external;
 
+    function transferRewardsBatch(
+        uint256 gameId,
+        RewardTransfer[] calldata transfers,
+        bool useFastTransfer,
+        uint256 maxFee
+    ) external returns (bool[] memory succeeded);
+
     // View Functions
     function getTotalStakes() external view returns (uint256);
 
@@ -53,6 +92,8 @@ interface IVaultContract {
 
     function getAuthorizedBackend() external view returns (address);
 
+    function getPermit2() external view returns (address);
+
     function getTokenMessengerV2() external view returns (address);
 
     function getMessageTransmitterV2() external view returns (address);
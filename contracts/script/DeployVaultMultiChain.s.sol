@@ -17,6 +17,10 @@ contract DeployVaultMultiChain is Script {
 
     mapping(uint256 => NetworkConfig) public networkConfigs;
 
+    // Canonical Permit2 deployment, override with PERMIT2_ADDRESS
+    address constant CANONICAL_PERMIT2 =
+        0x000000000022D473030F116dDEE9F6B43aC78BA3;
+
     function setUp() public {
         _initializeNetworkConfigs();
     }
@@ -150,13 +154,17 @@ contract DeployVaultMultiChain is Script {
         console.log("Domain ID:", config.domainId);
         console.log("Authorized Backend:", authorizedBackend);
 
+        address permit2 = vm.envOr("PERMIT2_ADDRESS", CANONICAL_PERMIT2);
+        console.log("Permit2:", permit2);
+
         vm.startBroadcast(deployerPrivateKey);
 
         VaultContract vault = new VaultContract(
             authorizedBackend,
             config.usdcAddress,
             config.tokenMessengerV2,
-            config.messageTransmitterV2
+            config.messageTransmitterV2,
+            permit2
         );
 
         vm.stopBroadcast();
@@ -181,6 +189,7 @@ contract DeployVaultMultiChain is Script {
             vault.getMessageTransmitterV2() == config.messageTransmitterV2,
             "MessageTransmitter mismatch"
         );
+        require(vault.getPermit2() == permit2, "Permit2 mismatch");
         require(
             vault.isChainSupported(config.chainId),
             "Chain not supported in config"
@@ -202,13 +211,15 @@ contract DeployVaultMultiChain is Script {
         console.log("Deploying VaultContract on", config.name);
 
         uint256 deployerPrivateKey = vm.envUint("PRIVATE_KEY");
+        address permit2 = vm.envOr("PERMIT2_ADDRESS", CANONICAL_PERMIT2);
         vm.startBroadcast(deployerPrivateKey);
 
         VaultContract vault = new VaultContract(
             authorizedBackend,
             config.usdcAddress,
             config.tokenMessengerV2,
-            config.messageTransmitterV2
+            config.messageTransmitterV2,
+            permit2
         );
 
         vm.stopBroadcast();
@@ -47,6 +47,29 @@ contract GameContract is IGameContract {
         uint32 chainId,
         Team team
     ) external override onlyFactory {
+        _addVote(player, chainId, team);
+    }
+
+    function addVotes(
+        VoteInput[] calldata votes
+    ) external override onlyFactory {
+        for (uint256 i = 0; i < votes.length; i++) {
+            _addVote(votes[i].player, votes[i].chainId, votes[i].team);
+        }
+    }
+
+    function endGame(GameResult result) external override onlyFactory {
+        require(gameState == GameState.Active, "Game is not active");
+
+        gameState = GameState.Finished;
+        gameResult = result;
+
+        emit GameFinished(gameId, result, block.timestamp);
+    }
+
+    // ============ INTERNAL FUNCTIONS ============
+
+    function _addVote(address player, uint32 chainId, Team team) private {
         if (users[player].totalVotes == 0) {
             // Add new user
             users[player] = UserInfo({
@@ -62,15 +85,6 @@ contract GameContract is IGameContract {
         emit Vote(gameId, player, team, chainId);
     }
 
-    function endGame(GameResult result) external override onlyFactory {
-        require(gameState == GameState.Active, "Game is not active");
-
-        gameState = GameState.Finished;
-        gameResult = result;
-
-        emit GameFinished(gameId, result, block.timestamp);
-    }
-
     // ============ VIEW FUNCTIONS ============
 
     function getGameInfo() external view override returns (GameInfo memory) {
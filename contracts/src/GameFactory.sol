@@ -113,6 +113,18 @@ contract GameFactory is IGameFactory {
         IGameContract(gameContract).addVote(player, chainId, team);
     }
 
+    function addVotes(
+        uint256 gameId,
+        IGameContract.VoteInput[] calldata votes
+    ) external override onlyAuthorizedBackend gameExistsModifier(gameId) {
+        require(votes.length > 0, "No votes to add");
+        address gameContract = gameContracts[gameId];
+        require(gameContract != address(0), "Game contract not found");
+
+        // Record a whole round of votes in one call
+        IGameContract(gameContract).addVotes(votes);
+    }
+
     // ============ VIEW FUNCTIONS ============
 
     function getGameContractAddress(
@@ -19,6 +19,32 @@ interface IERC20 {
     function approve(address spender, uint256 amount) external returns (bool);
 }
 
+// Uniswap Permit2 SignatureTransfer Interface
+interface ISignatureTransfer {
+    struct TokenPermissions {
+        address token;
+        uint256 amount;
+    }
+
+    struct PermitTransferFrom {
+        TokenPermissions permitted;
+        uint256 nonce;
+        uint256 deadline;
+    }
+
+    struct SignatureTransferDetails {
+        address to;
+        uint256 requestedAmount;
+    }
+
+    function permitTransferFrom(
+        PermitTransferFrom calldata permit,
+        SignatureTransferDetails calldata transferDetails,
+        address owner,
+        bytes calldata signature
+    ) external;
+}
+
 // Circle CCTP V2 TokenMessenger Interface
 interface ITokenMessengerV2 {
     function depositForBurn(
@@ -56,6 +82,7 @@ contract VaultContract is IVaultContract {
     address public immutable USDC_CONTRACT_ADDRESS;
     address public immutable TOKEN_MESSENGER_V2;
     address public immutable MESSAGE_TRANSMITTER_V2;
+    address public immutable PERMIT2;
 
     uint256 public totalStakes;
 
@@ -77,7 +104,8 @@ contract VaultContract is IVaultContract {
         address _authorizedBackend,
         address _usdcContractAddress,
         address _tokenMessengerV2,
-        address _messageTransmitterV2
+        address _messageTransmitterV2,
+        address _permit2
     ) {
         require(
             _authorizedBackend != address(0),
@@ -95,11 +123,13 @@ contract VaultContract is IVaultContract {
             _messageTransmitterV2 != address(0),
             "MessageTransmitter V2 cannot be zero address"
         );
+        require(_permit2 != address(0), "Permit2 cannot be zero address");
 
         AUTHORIZED_BACKEND = _authorizedBackend;
         USDC_CONTRACT_ADDRESS = _usdcContractAddress;
         TOKEN_MESSENGER_V2 = _tokenMessengerV2;
         MESSAGE_TRANSMITTER_V2 = _messageTransmitterV2;
+        PERMIT2 = _permit2;
 
         _initializeChainConfigs();
     }
@@ -229,6 +259,42 @@ contract VaultContract is IVaultContract {
         emit StakeDeposited(playerAddress, gameId, amount, totalStakes);
     }
 
+    // Only the backend may submit, since the signature does not cover the game the stake is for
+    function stakeWithSignature(
+        address playerAddress,
+        uint256 gameId,
+        uint256 amount,
+        uint256 nonce,
+        uint256 deadline,
+        bytes calldata signature
+    ) external override onlyAuthorizedBackend {
+        require(amount > 0, "Stake amount must be greater than 0");
+        require(playerAddress != address(0), "Player address cannot be zero");
+
+        // Permit2 checks the player's signature over exactly this amount, nonce and deadline with this vault as spender
+        ISignatureTransfer(PERMIT2).permitTransferFrom(
+            ISignatureTransfer.PermitTransferFrom({
+                permitted: ISignatureTransfer.TokenPermissions({
+                    token: USDC_CONTRACT_ADDRESS,
+                    amount: amount
+                }),
+                nonce: nonce,
+                deadline: deadline
+            }),
+            ISignatureTransfer.SignatureTransferDetails({
+                to: address(this),
+                requestedAmount: amount
+            }),
+            playerAddress,
+            signature
+        );
+
+        // Update vault total stakes
+        totalStakes += amount;
+
+        emit StakeDeposited(playerAddress, gameId, amount, totalStakes);
+    }
+
     function transferRewardsCrossChain(
         uint256 gameId,
         uint256 amount,
@@ -237,7 +303,89 @@ contract VaultContract is IVaultContract {
         bool useFastTransfer,
         uint256 maxFee
     ) external onlyAuthorizedBackend {
+        _transferRewardsCrossChain(
+            gameId,
+            amount,
+            destinationChainId,
+            recipient,
+            useFastTransfer,
+            maxFee
+        );
+    }
+
+    function transferRewardsBatch(
+        uint256 gameId,
+        RewardTransfer[] calldata transfers,
+        bool useFastTransfer,
+        uint256 maxFee
+    ) external onlyAuthorizedBackend returns (bool[] memory succeeded) {
+        require(transfers.length > 0, "No transfers to make");
+        succeeded = new bool[](transfers.length);
+
+        // A failing recipient is reported and skipped instead of reverting the whole batch
+        for (uint256 i = 0; i < transfers.length; i++) {
+            RewardTransfer calldata transfer = transfers[i];
+            try
+                this.transferRewardFromBatch(
+                    gameId,
+                    transfer.amount,
+                    transfer.destinationChainId,
+                    transfer.recipient,
+                    useFastTransfer,
+                    maxFee
+                )
+            {
+                succeeded[i] = true;
+                emit RewardTransferSucceeded(
+                    gameId,
+                    i,
+                    transfer.recipient,
+                    transfer.amount
+                );
+            } catch (bytes memory reason) {
+                emit RewardTransferFailed(
+                    gameId,
+                    i,
+                    transfer.recipient,
+                    transfer.amount,
+                    reason
+                );
+            }
+        }
+
+        return succeeded;
+    }
+
+    // External so transferRewardsBatch can catch the revert of a single transfer
+    function transferRewardFromBatch(
+        uint256 gameId,
+        uint256 amount,
+        uint256 destinationChainId,
+        address recipient,
+        bool useFastTransfer,
+        uint256 maxFee
+    ) external {
+        require(msg.sender == address(this), "Only callable by the vault");
+        _transferRewardsCrossChain(
+            gameId,
+            amount,
+            destinationChainId,
+            recipient,
+            useFastTransfer,
+            maxFee
+        );
+    }
+
+    function _transferRewardsCrossChain(
+        uint256 gameId,
+        uint256 amount,
+        uint256 destinationChainId,
+        address recipient,
+        bool useFastTransfer,
+        uint256 maxFee
+    ) private {
         require(amount > 0, "Reward amount must be greater than 0");
+        _creditMintedStakes();
         require(totalStakes >= amount, "Insufficient total stakes");
         require(recipient != address(0), "Recipient cannot be zero address");
 
@@ -278,6 +426,16 @@ contract VaultContract is IVaultContract {
         );
     }
 
+    // Stakes gathered from other chains are minted here by CCTP without passing through stake()
+    function _creditMintedStakes() private {
+        uint256 balance = IERC20(USDC_CONTRACT_ADDRESS).balanceOf(address(this));
+        if (balance > totalStakes) {
+            uint256 credited = balance - totalStakes;
+            totalStakes = balance;
+            emit MintedStakesCredited(credited, totalStakes);
+        }
+    }
+
     function getTotalStakes() external view override returns (uint256) {
         return totalStakes;
     }
@@ -291,6 +449,10 @@ contract VaultContract is IVaultContract {
         return AUTHORIZED_BACKEND;
     }
 
+    function getPermit2() external view returns (address) {
+        return PERMIT2;
+    }
+
     function getTokenMessengerV2() external view returns (address) {
         return TOKEN_MESSENGER_V2;
     }
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Session resume error codes sent to clients
+const (
+	ErrorResumeInvalid = "resume_invalid" // The token is malformed or was not signed by this server
+	ErrorResumeExpired = "resume_expired" // The token or the session it names has expired
+)
+
+// SessionTokens issues and verifies the HMAC-signed tokens a reconnecting client resumes its session with
+type SessionTokens struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewSessionTokens creates a token issuer, signing with a random secret when none is configured
+func NewSessionTokens(secret string, ttl time.Duration) (*SessionTokens, error) {
+	key := []byte(secret)
+	if len(key) == 0 {
+		// Sessions live in memory, so tokens only need to outlive the process when the secret is shared
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate session secret: %w", err)
+		}
+	}
+	return &SessionTokens{secret: key, ttl: ttl}, nil
+}
+
+// Issue returns a resume token for a session
+func (t *SessionTokens) Issue(sessionID string) string {
+	payload := sessionID + "|" + strconv.FormatInt(time.Now().Add(t.ttl).Unix(), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(t.sign(payload))
+}
+
+// Verify checks a resume token's signature and expiry and returns the session it names
+func (t *SessionTokens) Verify(token string) (string, error) {
+	encodedPayload, encodedMAC, found := strings.Cut(token, ".")
+	if !found {
+		return "", &Error{Code: ErrorResumeInvalid, Message: "resume token is malformed"}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return "", &Error{Code: ErrorResumeInvalid, Message: "resume token is malformed"}
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil || !hmac.Equal(mac, t.sign(string(payload))) {
+		return "", &Error{Code: ErrorResumeInvalid, Message: "resume token was not issued by this server"}
+	}
+
+	sessionID, expiry, found := strings.Cut(string(payload), "|")
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if !found || err != nil {
+		return "", &Error{Code: ErrorResumeInvalid, Message: "resume token is malformed"}
+	}
+	if time.Now().Unix() > expiresAt {
+		return "", &Error{Code: ErrorResumeExpired, Message: "resume token expired"}
+	}
+	return sessionID, nil
+}
+
+// sign returns the HMAC-SHA256 of a token payload
+func (t *SessionTokens) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionTokensVerify(t *testing.T) {
+	tokens, err := NewSessionTokens("secret", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := tokens.Issue("session-1")
+	payload, mac, _ := strings.Cut(token, ".")
+
+	// forge signs a payload with the issuer's MAC of another payload
+	forge := func(payload string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + mac
+	}
+	expired, _ := NewSessionTokens("secret", -time.Second)
+	otherServer, _ := NewSessionTokens("other secret", time.Hour)
+	restarted, _ := NewSessionTokens("secret", time.Hour)
+
+	tests := []struct {
+		name     string
+		token    string
+		verifier *SessionTokens
+		wantCode string
+		wantID   string
+	}{
+		{name: "valid", token: token, wantID: "session-1"},
+		{name: "shared secret after restart", token: token, verifier: restarted, wantID: "session-1"},
+		{name: "tampered session", token: forge("session-2|" + strings.Split(decode(t, payload), "|")[1]), wantCode: ErrorResumeInvalid},
+		{name: "tampered expiry", token: forge("session-1|9999999999"), wantCode: ErrorResumeInvalid},
+		{name: "tampered signature", token: payload + "." + base64.RawURLEncoding.EncodeToString([]byte("mac")), wantCode: ErrorResumeInvalid},
+		{name: "other server", token: otherServer.Issue("session-1"), wantCode: ErrorResumeInvalid},
+		{name: "expired", token: expired.Issue("session-1"), wantCode: ErrorResumeExpired},
+		{name: "malformed", token: "session-1", wantCode: ErrorResumeInvalid},
+		{name: "malformed payload", token: "!!." + mac, wantCode: ErrorResumeInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := tokens
+			if tt.verifier != nil {
+				verifier = tt.verifier
+			}
+			sessionID, err := verifier.Verify(tt.token)
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("Verify() error = %q, want %q", code, tt.wantCode)
+			}
+			if sessionID != tt.wantID {
+				t.Fatalf("Verify() = %q, want %q", sessionID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestSessionTokensWithoutSecretDoNotOutliveProcess(t *testing.T) {
+	first, err := NewSessionTokens("", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewSessionTokens("", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := second.Verify(first.Issue("session-1")); errorCode(err) != ErrorResumeInvalid {
+		t.Fatalf("Verify() error = %v, want %s for a token of another random secret", err, ErrorResumeInvalid)
+	}
+}
+
+// decode returns a base64url-decoded token part
+func decode(t *testing.T, part string) string {
+	t.Helper()
+	raw, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(raw)
+}
@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"blockchess/internal/client"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Authentication error codes sent to clients
+const (
+	ErrorRequired    = "auth_required"          // The message needs a signed-in wallet
+	ErrorNoChallenge = "auth_no_challenge"      // A login was sent without a pending challenge
+	ErrorExpired     = "auth_expired"           // The challenge expired before it was signed
+	ErrorInvalid     = "auth_invalid_signature" // The signature is malformed or not made by the challenged address
+)
+
+// Error is a failed sign-in with a code clients can act on
+type Error struct {
+	Code    string
+	Message string
+}
+
+// Error returns the message of the failure
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Config controls the Sign-In with Ethereum messages and resumable sessions
+type Config struct {
+	Domain       string        // Domain the user signs in to, wallets warn when it is not the site asking for the signature
+	URI          string        // URI of the signed resource
+	Statement    string        // Human-readable statement shown in the wallet
+	ChallengeTTL time.Duration // How long a challenge can be signed
+
+	// Resumable sessions
+	SessionSecret string        // HMAC key of resume tokens, random per process when empty
+	SessionTTL    time.Duration // How long a resume token is valid
+	ResumeWindow  time.Duration // How long a disconnected session can be resumed
+	ReplayEvents  int           // Events kept per game for resumed sessions
+}
+
+// LoadConfig loads the sign-in and session settings from environment variables
+func LoadConfig() Config {
+	return Config{
+		Domain:       client.GetEnv("AUTH_DOMAIN", ""),
+		URI:          client.GetEnv("AUTH_URI", ""),
+		Statement:    client.GetEnv("AUTH_STATEMENT", "Sign in to BlockChess to vote and stake with this wallet."),
+		ChallengeTTL: time.Duration(client.GetEnvInt("AUTH_CHALLENGE_SECONDS", 300)) * time.Second,
+
+		SessionSecret: client.GetEnv("SESSION_SECRET", ""),
+		SessionTTL:    time.Duration(client.GetEnvInt("SESSION_TOKEN_SECONDS", 86400)) * time.Second,
+		ResumeWindow:  time.Duration(client.GetEnvInt("SESSION_RESUME_SECONDS", 30)) * time.Second,
+		ReplayEvents:  client.GetEnvInt("SESSION_REPLAY_EVENTS", 256),
+	}
+}
+
+// UseServerAddress fills a missing domain and URI with the address the server listens on,
+// returning false when both were configured
+func (c *Config) UseServerAddress(addr string) bool {
+	if c.Domain != "" && c.URI != "" {
+		return false
+	}
+	host := addr
+	if strings.HasPrefix(host, ":") {
+		host = "localhost" + host
+	}
+	if c.Domain == "" {
+		c.Domain = host
+	}
+	if c.URI == "" {
+		c.URI = "http://" + c.Domain
+	}
+	return true
+}
+
+// Validate checks that the sign-in domain and URI are configured, they must never come from the connecting site
+func (c Config) Validate() error {
+	if c.Domain == "" || c.URI == "" {
+		return fmt.Errorf("AUTH_DOMAIN and AUTH_URI must be set")
+	}
+	parsed, err := url.Parse(c.URI)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return fmt.Errorf("AUTH_URI %q is not an absolute URI", c.URI)
+	}
+	return nil
+}
+
+// Challenge is an EIP-4361 message issued to one connection for signing
+type Challenge struct {
+	Address   common.Address
+	ChainID   uint64
+	Nonce     string
+	Message   string
+	ExpiresAt time.Time
+}
+
+// NewChallenge creates an EIP-4361 message with a fresh nonce for an address to sign
+func NewChallenge(config Config, address common.Address, chainID uint64) (*Challenge, error) {
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	issuedAt := time.Now().UTC()
+	expiresAt := issuedAt.Add(config.ChallengeTTL)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s wants you to sign in with your Ethereum account:\n%s\n\n", config.Domain, address.Hex())
+	if config.Statement != "" {
+		fmt.Fprintf(&b, "%s\n\n", config.Statement)
+	}
+	fmt.Fprintf(&b, "URI: %s\nVersion: 1\nChain ID: %d\nNonce: %s\nIssued At: %s\nExpiration Time: %s",
+		config.URI, chainID, nonce, issuedAt.Format(time.RFC3339), expiresAt.Format(time.RFC3339))
+
+	return &Challenge{
+		Address:   address,
+		ChainID:   chainID,
+		Nonce:     nonce,
+		Message:   b.String(),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Verify checks that a personal_sign signature over the challenge was made by the challenged address
+func (c *Challenge) Verify(signature string) (common.Address, error) {
+	if time.Now().After(c.ExpiresAt) {
+		return common.Address{}, &Error{Code: ErrorExpired, Message: "sign-in message expired, please request a new one"}
+	}
+
+	if !strings.HasPrefix(signature, "0x") {
+		signature = "0x" + signature
+	}
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return common.Address{}, &Error{Code: ErrorInvalid, Message: "sign-in signature must be 65 bytes of hex"}
+	}
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+
+	pubKey, err := crypto.SigToPub(accounts.TextHash([]byte(c.Message)), sig)
+	if err != nil {
+		return common.Address{}, &Error{Code: ErrorInvalid, Message: fmt.Sprintf("failed to recover sign-in signer: %v", err)}
+	}
+
+	if signer := crypto.PubkeyToAddress(*pubKey); signer != c.Address {
+		return common.Address{}, &Error{
+			Code:    ErrorInvalid,
+			Message: fmt.Sprintf("sign-in message was signed by %s, not by %s", signer.Hex(), c.Address.Hex()),
+		}
+	}
+	return c.Address, nil
+}
+
+// newNonce returns a random alphanumeric EIP-4361 nonce
+func newNonce() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// personalSign signs a message the way a wallet's personal_sign does, with V of 27 or 28
+func personalSign(t *testing.T, key *ecdsa.PrivateKey, message string) string {
+	t.Helper()
+	sig, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig[64] += 27
+	return hexutil.Encode(sig)
+}
+
+// errorCode returns the code of an auth Error, or the error text for anything else
+func errorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	var authErr *Error
+	if errors.As(err, &authErr) {
+		return authErr.Code
+	}
+	return err.Error()
+}
+
+func TestChallengeVerify(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	otherKey, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	config := Config{Domain: "blockchess.example", URI: "https://blockchess.example", Statement: "Sign in", ChallengeTTL: time.Minute}
+
+	tests := []struct {
+		name     string
+		ttl      time.Duration
+		sign     func(challenge *Challenge) string
+		wantCode string
+	}{
+		{
+			name: "valid",
+			sign: func(challenge *Challenge) string { return personalSign(t, key, challenge.Message) },
+		},
+		{
+			name: "signature without prefix",
+			sign: func(challenge *Challenge) string {
+				return strings.TrimPrefix(personalSign(t, key, challenge.Message), "0x")
+			},
+		},
+		{
+			name: "wrong domain",
+			sign: func(challenge *Challenge) string {
+				return personalSign(t, key, strings.Replace(challenge.Message, "blockchess.example wants", "evil.example wants", 1))
+			},
+			wantCode: ErrorInvalid,
+		},
+		{
+			name: "wrong URI",
+			sign: func(challenge *Challenge) string {
+				return personalSign(t, key, strings.Replace(challenge.Message, "URI: https://blockchess.example", "URI: https://evil.example", 1))
+			},
+			wantCode: ErrorInvalid,
+		},
+		{
+			name: "replayed signature of another challenge",
+			sign: func(challenge *Challenge) string {
+				previous, err := NewChallenge(config, address, 84532)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return personalSign(t, key, previous.Message)
+			},
+			wantCode: ErrorInvalid,
+		},
+		{
+			name:     "other signer",
+			sign:     func(challenge *Challenge) string { return personalSign(t, otherKey, challenge.Message) },
+			wantCode: ErrorInvalid,
+		},
+		{
+			name:     "malformed",
+			sign:     func(challenge *Challenge) string { return "0x1234" },
+			wantCode: ErrorInvalid,
+		},
+		{
+			name:     "expired",
+			ttl:      -time.Second,
+			sign:     func(challenge *Challenge) string { return personalSign(t, key, challenge.Message) },
+			wantCode: ErrorExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := config
+			if tt.ttl != 0 {
+				config.ChallengeTTL = tt.ttl
+			}
+			challenge, err := NewChallenge(config, address, 84532)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			signer, err := challenge.Verify(tt.sign(challenge))
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("Verify() error = %q, want %q", code, tt.wantCode)
+			}
+			if tt.wantCode == "" && signer != address {
+				t.Fatalf("Verify() = %s, want %s", signer.Hex(), address.Hex())
+			}
+		})
+	}
+}
+
+func TestChallengeMessageFollowsEIP4361(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	challenge, err := NewChallenge(Config{Domain: "blockchess.example", URI: "https://blockchess.example/play", ChallengeTTL: time.Minute}, address, 84532)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(challenge.Message, "\n")
+	if want := "blockchess.example wants you to sign in with your Ethereum account:"; lines[0] != want {
+		t.Fatalf("first line %q, want %q", lines[0], want)
+	}
+	if lines[1] != address.Hex() {
+		t.Fatalf("second line %q, want the checksummed address %s", lines[1], address.Hex())
+	}
+	for _, want := range []string{"URI: https://blockchess.example/play", "Version: 1", "Chain ID: 84532", "Nonce: " + challenge.Nonce} {
+		if !strings.Contains(challenge.Message, "\n"+want+"\n") {
+			t.Fatalf("message is missing %q:\n%s", want, challenge.Message)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "configured", config: Config{Domain: "blockchess.example", URI: "https://blockchess.example"}},
+		{name: "missing domain", config: Config{URI: "https://blockchess.example"}, wantErr: true},
+		{name: "missing URI", config: Config{Domain: "blockchess.example"}, wantErr: true},
+		{name: "relative URI", config: Config{Domain: "blockchess.example", URI: "/play"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package cctp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Attestation statuses reported by the attestation service
+const (
+	AttestationComplete = "complete"
+	AttestationPending  = "pending_confirmations"
+)
+
+// Attestation is one message returned by the attestation service for a burn transaction
+type Attestation struct {
+	Message     string `json:"message"`
+	Attestation string `json:"attestation"`
+	Status      string `json:"status"`
+	EventNonce  string `json:"eventNonce"`
+}
+
+// Ready reports whether the message has a signed attestation that can be submitted
+func (a Attestation) Ready() bool {
+	return a.Status == AttestationComplete && strings.HasPrefix(a.Attestation, "0x")
+}
+
+// AttestationClient queries Circle's attestation service, or a compatible mock, for CCTP messages
+type AttestationClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewAttestationClient creates an attestation client for the service at baseURL
+func NewAttestationClient(baseURL string) *AttestationClient {
+	return &AttestationClient{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Messages returns the messages and attestations of a burn transaction. It returns no messages
+// while the service has not indexed the transaction yet.
+func (c *AttestationClient) Messages(sourceDomain uint32, txHash common.Hash) ([]Attestation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	url := fmt.Sprintf("%s/v2/messages/%d?transactionHash=%s", c.baseURL, sourceDomain, txHash.Hex())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attestation request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attestation service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("attestation service returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Messages []Attestation `json:"messages"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode attestation response: %w", err)
+	}
+	return result.Messages, nil
+}
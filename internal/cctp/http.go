@@ -0,0 +1,38 @@
+package cctp
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleTransfers serves every tracked transfer, optionally filtered by the gameId query parameter
+func (r *Relayer) HandleTransfers(w http.ResponseWriter, req *http.Request) {
+	gameID := req.URL.Query().Get("gameId")
+
+	transfers := []Transfer{}
+	for _, transfer := range r.Transfers() {
+		if gameID == "" || transfer.GameID == gameID {
+			transfers = append(transfers, transfer)
+		}
+	}
+	writeJSON(w, transfers)
+}
+
+// HandleRetryTransfer resets the failed transfer in the {transferId} route variable
+func (r *Relayer) HandleRetryTransfer(w http.ResponseWriter, req *http.Request) {
+	if err := r.Retry(mux.Vars(req)["transferId"]); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("Warning: Failed to encode CCTP response: %v", err)
+	}
+}
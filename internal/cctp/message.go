@@ -0,0 +1,32 @@
+package cctp
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// messageHeaderLength is the size of the fixed CCTP V2 message header preceding the message body
+const messageHeaderLength = 148
+
+// MessageHeader holds the routing fields of a CCTP V2 message
+type MessageHeader struct {
+	Version           uint32
+	SourceDomain      uint32
+	DestinationDomain uint32
+	Nonce             [32]byte // Zero in MessageSent events, assigned by the attestation service
+}
+
+// ParseMessageHeader decodes the header of a raw CCTP V2 message
+func ParseMessageHeader(message []byte) (MessageHeader, error) {
+	if len(message) < messageHeaderLength {
+		return MessageHeader{}, fmt.Errorf("CCTP message too short: %d bytes", len(message))
+	}
+
+	header := MessageHeader{
+		Version:           binary.BigEndian.Uint32(message[0:4]),
+		SourceDomain:      binary.BigEndian.Uint32(message[4:8]),
+		DestinationDomain: binary.BigEndian.Uint32(message[8:12]),
+	}
+	copy(header.Nonce[:], message[12:44])
+	return header, nil
+}
@@ -0,0 +1,522 @@
+package cctp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"blockchess/internal/client"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	relayerTickInterval = 5 * time.Second  // How often due transfers are picked up
+	receiveStuckAfter   = 30 * time.Minute // When a pending receiveMessage transaction needs an operator
+)
+
+// TransferStatus is the relaying state of a cross-chain transfer
+type TransferStatus string
+
+const (
+	TransferAwaitingAttestation TransferStatus = "awaiting_attestation" // Burned, waiting for the attestation service
+	TransferAttested            TransferStatus = "attested"             // Attested, receiveMessage not sent yet
+	TransferReceiving           TransferStatus = "receiving"            // receiveMessage sent on the destination chain
+	TransferMinted              TransferStatus = "minted"               // USDC minted to the recipient
+	TransferFailed              TransferStatus = "failed"               // Gave up, needs an operator
+)
+
+// Transfer tracks one CCTP message from its burn on the source chain until it is minted on the destination chain
+type Transfer struct {
+	ID             string         `json:"id"` // burnTxHash:messageIndex
+	GameID         string         `json:"gameId"`
+	SourceChainID  uint64         `json:"sourceChainId"`
+	DestChainID    uint64         `json:"destChainId"`
+	SourceDomain   uint32         `json:"sourceDomain"`
+	BurnTxHash     string         `json:"burnTxHash"`
+	MessageIndex   int            `json:"messageIndex"` // Position among the messages sent by the burn transaction
+	Message        string         `json:"message,omitempty"`
+	Attestation    string         `json:"attestation,omitempty"`
+	Status         TransferStatus `json:"status"`
+	ReceiveTxHash  string         `json:"receiveTxHash,omitempty"`
+	ReceiveTxNonce uint64         `json:"receiveTxNonce,omitempty"`
+	ReceiveSentAt  int64          `json:"receiveSentAt,omitempty"`
+	Attempts       int            `json:"attempts"`
+	LastError      string         `json:"lastError,omitempty"`
+	NextAttemptAt  int64          `json:"nextAttemptAt"`
+	CreatedAt      int64          `json:"createdAt"`
+	UpdatedAt      int64          `json:"updatedAt"`
+	MintedAt       int64          `json:"mintedAt,omitempty"`
+}
+
+// RelayerConfig controls attestation polling and retries
+type RelayerConfig struct {
+	AttestationURL string        // Base URL of the attestation service
+	PollInterval   time.Duration // Delay between attestation checks
+	MaxAttempts    int           // Failed attempts before a transfer needs an operator
+	RetryBase      time.Duration // Delay after the first failure, doubled on every retry
+	RetryMax       time.Duration // Upper bound for the retry delay
+}
+
+// LoadRelayerConfig loads the relayer settings from environment variables
+func LoadRelayerConfig() RelayerConfig {
+	return RelayerConfig{
+		AttestationURL: client.GetEnv("CCTP_ATTESTATION_URL", "https://iris-api-sandbox.circle.com"),
+		PollInterval:   time.Duration(client.GetEnvInt("CCTP_ATTESTATION_POLL_SECONDS", 15)) * time.Second,
+		MaxAttempts:    client.GetEnvInt("CCTP_RELAY_MAX_ATTEMPTS", 8),
+		RetryBase:      time.Duration(client.GetEnvInt("CCTP_RELAY_RETRY_BASE_SECONDS", 10)) * time.Second,
+		RetryMax:       time.Duration(client.GetEnvInt("CCTP_RELAY_RETRY_MAX_SECONDS", 600)) * time.Second,
+	}
+}
+
+// backoff returns the delay before the next attempt after the given number of failures
+func (c RelayerConfig) backoff(attempts int) time.Duration {
+	delay := c.RetryBase
+	for i := 1; i < attempts && delay < c.RetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, c.RetryMax)
+}
+
+// messageReceiver submits attested messages on a destination chain, implemented by client.MessageTransmitter
+type messageReceiver interface {
+	SendReceiveMessage(message, attestation []byte) (*types.Transaction, error)
+	IsNonceUsed(nonce [32]byte) (bool, error)
+	TransactionState(txHash common.Hash, nonce uint64) (client.TxState, common.Hash, error)
+}
+
+// Relayer delivers CCTP transfers by fetching attestations and calling receiveMessage on the destination chain
+type Relayer struct {
+	clients     *client.Clients
+	attestation *AttestationClient
+	config      RelayerConfig
+
+	mu        sync.Mutex
+	path      string
+	transfers map[string]Transfer // transferID -> transfer
+	running   map[string]bool     // transferID -> true while a goroutine processes the transfer
+
+	transmitters   map[uint64]messageReceiver // chainID -> MessageTransmitter
+	transmittersMu sync.Mutex
+}
+
+// NewRelayer loads the transfers stored at path. An empty path keeps transfers in memory only.
+func NewRelayer(clients *client.Clients, path string, config RelayerConfig) (*Relayer, error) {
+	r := &Relayer{
+		clients:      clients,
+		attestation:  NewAttestationClient(config.AttestationURL),
+		config:       config,
+		path:         path,
+		transfers:    make(map[string]Transfer),
+		running:      make(map[string]bool),
+		transmitters: make(map[uint64]messageReceiver),
+	}
+	if path == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CCTP transfers: %w", err)
+	}
+
+	var transfers []Transfer
+	if err := json.Unmarshal(data, &transfers); err != nil {
+		return nil, fmt.Errorf("failed to parse CCTP transfers: %w", err)
+	}
+	for _, transfer := range transfers {
+		r.transfers[transfer.ID] = transfer
+	}
+	return r, nil
+}
+
+// Track starts relaying every CCTP message sent by a confirmed burn transaction.
+// The i-th message goes to destChainIDs[i], or to the last chain listed when there are fewer chains than messages.
+func (r *Relayer) Track(gameID string, sourceChainID uint64, destChainIDs []uint64, burnTxHash common.Hash) error {
+	if len(destChainIDs) == 0 {
+		return fmt.Errorf("no destination chain for burn %s", burnTxHash.Hex())
+	}
+
+	ethClient, err := r.clients.GetClientByChainID(sourceChainID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	receipt, err := ethClient.TransactionReceipt(ctx, burnTxHash)
+	if err != nil {
+		return fmt.Errorf("failed to get receipt for burn %s: %w", burnTxHash.Hex(), err)
+	}
+
+	messages, err := client.ExtractSentMessages(receipt)
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		log.Printf("Transaction %s sent no CCTP messages, nothing to relay", burnTxHash.Hex())
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().Unix()
+	for i, message := range messages {
+		header, err := ParseMessageHeader(message)
+		if err != nil {
+			return err
+		}
+
+		destChainID := destChainIDs[min(i, len(destChainIDs)-1)]
+		id := fmt.Sprintf("%s:%d", burnTxHash.Hex(), i)
+		if _, exists := r.transfers[id]; exists {
+			continue
+		}
+		r.transfers[id] = Transfer{
+			ID:            id,
+			GameID:        gameID,
+			SourceChainID: sourceChainID,
+			DestChainID:   destChainID,
+			SourceDomain:  header.SourceDomain,
+			BurnTxHash:    burnTxHash.Hex(),
+			MessageIndex:  i,
+			Status:        TransferAwaitingAttestation,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		log.Printf("Tracking CCTP transfer %s from chain %d to chain %d for game %s",
+			id, sourceChainID, destChainID, gameID)
+	}
+	return r.persistUnsafe()
+}
+
+// Transfers returns all tracked transfers, oldest first
+func (r *Relayer) Transfers() []Transfer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transfers := make([]Transfer, 0, len(r.transfers))
+	for _, transfer := range r.transfers {
+		transfers = append(transfers, transfer)
+	}
+	sort.Slice(transfers, func(i, j int) bool {
+		if transfers[i].CreatedAt != transfers[j].CreatedAt {
+			return transfers[i].CreatedAt < transfers[j].CreatedAt
+		}
+		return transfers[i].ID < transfers[j].ID
+	})
+	return transfers
+}
+
+// BurnStatus returns the relaying state of the messages sent by a burn transaction, minted only once all of them are.
+// It reports false when the burn is not tracked.
+func (r *Relayer) BurnStatus(burnTxHash common.Hash) (TransferStatus, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status, tracked := TransferMinted, false
+	for _, transfer := range r.transfers {
+		if transfer.BurnTxHash != burnTxHash.Hex() {
+			continue
+		}
+		tracked = true
+		switch transfer.Status {
+		case TransferMinted:
+		case TransferFailed:
+			return TransferFailed, true
+		default:
+			status = transfer.Status
+		}
+	}
+	return status, tracked
+}
+
+// Retry resets a failed transfer so the relayer picks it up again
+func (r *Relayer) Retry(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transfer, exists := r.transfers[id]
+	if !exists {
+		return fmt.Errorf("transfer %s not found", id)
+	}
+	if transfer.Status != TransferFailed {
+		return fmt.Errorf("transfer %s is %s, only failed transfers can be retried", id, transfer.Status)
+	}
+
+	switch {
+	case transfer.ReceiveTxHash != "":
+		transfer.Status = TransferReceiving
+	case transfer.Attestation != "":
+		transfer.Status = TransferAttested
+	default:
+		transfer.Status = TransferAwaitingAttestation
+	}
+	transfer.Attempts = 0
+	transfer.NextAttemptAt = 0
+	transfer.UpdatedAt = time.Now().Unix()
+	r.transfers[id] = transfer
+
+	log.Printf("Operator retrying CCTP transfer %s", id)
+	return r.persistUnsafe()
+}
+
+// Run relays due transfers until the server stops
+func (r *Relayer) Run() {
+	ticker := time.NewTicker(relayerTickInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, id := range r.due(time.Now()) {
+			go r.process(id)
+		}
+	}
+}
+
+// due returns the transfers ready for another attempt
+func (r *Relayer) due(now time.Time) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ids []string
+	for id, transfer := range r.transfers {
+		if transfer.Status == TransferMinted || transfer.Status == TransferFailed || r.running[id] {
+			continue
+		}
+		if transfer.NextAttemptAt <= now.Unix() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// process advances a transfer by one step and schedules its next attempt
+func (r *Relayer) process(id string) {
+	r.mu.Lock()
+	transfer, exists := r.transfers[id]
+	if !exists || r.running[id] {
+		r.mu.Unlock()
+		return
+	}
+	r.running[id] = true
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		delete(r.running, id)
+		r.mu.Unlock()
+	}()
+
+	var err error
+	switch transfer.Status {
+	case TransferAwaitingAttestation:
+		err = r.fetchAttestation(&transfer)
+	case TransferAttested, TransferReceiving:
+		err = r.receive(&transfer)
+	default:
+		return
+	}
+
+	now := time.Now()
+	transfer.UpdatedAt = now.Unix()
+	if err != nil {
+		transfer.Attempts++
+		transfer.LastError = err.Error()
+		if transfer.Attempts >= r.config.MaxAttempts {
+			transfer.Status = TransferFailed
+			log.Printf("Error: CCTP transfer %s needs an operator after %d attempts: %v", id, transfer.Attempts, err)
+		} else {
+			delay := r.config.backoff(transfer.Attempts)
+			transfer.NextAttemptAt = now.Add(delay).Unix()
+			log.Printf("Warning: CCTP transfer %s failed (attempt %d), retrying in %s: %v", id, transfer.Attempts, delay, err)
+		}
+	} else if transfer.Status != TransferMinted {
+		transfer.NextAttemptAt = now.Add(r.config.PollInterval).Unix()
+	}
+
+	r.save(transfer)
+}
+
+// fetchAttestation polls the attestation service and stores the attested message once it is ready
+func (r *Relayer) fetchAttestation(transfer *Transfer) error {
+	attestations, err := r.attestation.Messages(transfer.SourceDomain, common.HexToHash(transfer.BurnTxHash))
+	if err != nil {
+		return err
+	}
+	if transfer.MessageIndex >= len(attestations) {
+		return nil
+	}
+
+	attestation := attestations[transfer.MessageIndex]
+	if !attestation.Ready() {
+		return nil
+	}
+
+	// Submit the attested message, the one in the MessageSent event has no nonce yet
+	transfer.Message = attestation.Message
+	transfer.Attestation = attestation.Attestation
+	transfer.Status = TransferAttested
+	transfer.Attempts = 0
+	transfer.LastError = ""
+	log.Printf("CCTP transfer %s attested", transfer.ID)
+
+	return r.receive(transfer)
+}
+
+// receive reconciles a sent receiveMessage transaction, or sends one when the message was not received yet
+func (r *Relayer) receive(transfer *Transfer) error {
+	transmitter, err := r.getTransmitter(transfer.DestChainID)
+	if err != nil {
+		return err
+	}
+
+	if transfer.ReceiveTxHash != "" {
+		state, txHash, err := transmitter.TransactionState(common.HexToHash(transfer.ReceiveTxHash), transfer.ReceiveTxNonce)
+		if err != nil {
+			return err
+		}
+		transfer.ReceiveTxHash = txHash.Hex() // Follow fee-bumped replacements
+
+		switch state {
+		case client.TxSucceeded:
+			r.markMinted(transfer)
+			return nil
+		case client.TxPending:
+			if time.Since(time.Unix(transfer.ReceiveSentAt, 0)) > receiveStuckAfter {
+				return fmt.Errorf("receive transaction %s pending for over %s", transfer.ReceiveTxHash, receiveStuckAfter)
+			}
+			return nil
+		default:
+			// Reverted or dropped, fall through so a used nonce is detected before resending
+			log.Printf("Warning: Receive transaction %s of CCTP transfer %s %s", transfer.ReceiveTxHash, transfer.ID, state)
+			transfer.ReceiveTxHash, transfer.ReceiveTxNonce, transfer.ReceiveSentAt = "", 0, 0
+			transfer.Status = TransferAttested
+		}
+	}
+
+	message, err := hexutil.Decode(transfer.Message)
+	if err != nil {
+		return fmt.Errorf("invalid attested message: %w", err)
+	}
+	attestation, err := hexutil.Decode(transfer.Attestation)
+	if err != nil {
+		return fmt.Errorf("invalid attestation: %w", err)
+	}
+	header, err := ParseMessageHeader(message)
+	if err != nil {
+		return err
+	}
+
+	// Another relayer may already have delivered the message
+	used, err := transmitter.IsNonceUsed(header.Nonce)
+	if err != nil {
+		return err
+	}
+	if used {
+		r.markMinted(transfer)
+		return nil
+	}
+
+	tx, err := transmitter.SendReceiveMessage(message, attestation)
+	if err != nil {
+		return err
+	}
+
+	// Persist the hash before waiting so a restart reconciles instead of resending
+	transfer.ReceiveTxHash = tx.Hash().Hex()
+	transfer.ReceiveTxNonce = tx.Nonce()
+	transfer.ReceiveSentAt = time.Now().Unix()
+	transfer.Status = TransferReceiving
+	r.save(*transfer)
+	return nil
+}
+
+// markMinted records that a transfer's USDC reached its recipient
+func (r *Relayer) markMinted(transfer *Transfer) {
+	transfer.Status = TransferMinted
+	transfer.MintedAt = time.Now().Unix()
+	transfer.LastError = ""
+	log.Printf("CCTP transfer %s minted on chain %d", transfer.ID, transfer.DestChainID)
+}
+
+// getTransmitter returns the MessageTransmitter for a destination chain, creating it on first use
+func (r *Relayer) getTransmitter(chainID uint64) (messageReceiver, error) {
+	r.transmittersMu.Lock()
+	defer r.transmittersMu.Unlock()
+
+	if transmitter, exists := r.transmitters[chainID]; exists {
+		return transmitter, nil
+	}
+
+	ethClient, err := r.clients.GetClientByChainID(chainID)
+	if err != nil {
+		return nil, err
+	}
+	txManager, err := r.clients.GetTxManager(chainID)
+	if err != nil {
+		return nil, err
+	}
+	transmitter, err := client.NewMessageTransmitter(ethClient, txManager, chainID)
+	if err != nil {
+		return nil, err
+	}
+	r.transmitters[chainID] = transmitter
+	return transmitter, nil
+}
+
+// save persists a transfer, logging instead of failing the caller
+func (r *Relayer) save(transfer Transfer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.transfers[transfer.ID] = transfer
+	if err := r.persistUnsafe(); err != nil {
+		log.Printf("Warning: Failed to save CCTP transfer %s: %v", transfer.ID, err)
+	}
+}
+
+// persistUnsafe atomically rewrites the transfers file (caller must hold the lock)
+func (r *Relayer) persistUnsafe() error {
+	if r.path == "" {
+		return nil
+	}
+
+	transfers := make([]Transfer, 0, len(r.transfers))
+	for _, transfer := range r.transfers {
+		transfers = append(transfers, transfer)
+	}
+	sort.Slice(transfers, func(i, j int) bool {
+		return transfers[i].ID < transfers[j].ID
+	})
+
+	data, err := json.MarshalIndent(transfers, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode CCTP transfers: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create CCTP transfers directory: %w", err)
+	}
+	tmpPath := r.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write CCTP transfers: %w", err)
+	}
+	if err := os.Rename(tmpPath, r.path); err != nil {
+		return fmt.Errorf("failed to replace CCTP transfers: %w", err)
+	}
+	return nil
+}
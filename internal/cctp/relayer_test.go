@@ -0,0 +1,138 @@
+package cctp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"blockchess/internal/client"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// fakeReceiver records receiveMessage submissions and reports the state of the last one
+type fakeReceiver struct {
+	sent  int
+	state client.TxState
+}
+
+func (f *fakeReceiver) SendReceiveMessage(message, attestation []byte) (*types.Transaction, error) {
+	f.sent++
+	return types.NewTx(&types.LegacyTx{Nonce: uint64(f.sent)}), nil
+}
+
+func (f *fakeReceiver) IsNonceUsed(nonce [32]byte) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeReceiver) TransactionState(txHash common.Hash, nonce uint64) (client.TxState, common.Hash, error) {
+	return f.state, txHash, nil
+}
+
+// attestationStub answers each attestation request with the next of the given HTTP statuses and message statuses,
+// repeating the last one
+func attestationStub(t *testing.T, message string, responses ...string) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		response := responses[min(calls, len(responses)-1)]
+		calls++
+		mu.Unlock()
+
+		switch response {
+		case "not_found":
+			http.NotFound(w, r)
+		case "error":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			attestation := Attestation{Message: message, Status: response, Attestation: "PENDING"}
+			if response == AttestationComplete {
+				attestation.Attestation = "0x" + "ab"
+			}
+			json.NewEncoder(w).Encode(map[string]any{"messages": []Attestation{attestation}})
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRelayerWaitsForAttestationAndReceivesOnce(t *testing.T) {
+	message := hexutil.Encode(make([]byte, messageHeaderLength))
+	server := attestationStub(t, message, "not_found", AttestationPending, "error", "error", AttestationComplete)
+
+	receiver := &fakeReceiver{state: client.TxPending}
+	config := RelayerConfig{PollInterval: 15 * time.Second, MaxAttempts: 5, RetryBase: 10 * time.Second, RetryMax: time.Minute}
+	r := &Relayer{
+		attestation:  NewAttestationClient(server.URL),
+		config:       config,
+		transfers:    map[string]Transfer{"burn:0": {ID: "burn:0", DestChainID: 84532, Status: TransferAwaitingAttestation}},
+		running:      make(map[string]bool),
+		transmitters: map[uint64]messageReceiver{84532: receiver},
+	}
+	// step processes the transfer and reports whether its next attempt was scheduled the given time later
+	step := func() (Transfer, func(wait time.Duration) bool) {
+		before := time.Now().Unix()
+		r.process("burn:0")
+		after := time.Now().Unix()
+		transfer := r.transfers["burn:0"]
+		return transfer, func(wait time.Duration) bool {
+			seconds := int64(wait / time.Second)
+			return transfer.NextAttemptAt >= before+seconds && transfer.NextAttemptAt <= after+seconds
+		}
+	}
+
+	// Not indexed yet and pending attestations are polled without counting as failures
+	for range 2 {
+		if transfer, scheduledIn := step(); transfer.Status != TransferAwaitingAttestation || transfer.Attempts != 0 || !scheduledIn(config.PollInterval) {
+			t.Fatalf("transfer %s with %d attempts retried at %d, want awaiting attestation polled in %s", transfer.Status, transfer.Attempts, transfer.NextAttemptAt, config.PollInterval)
+		}
+	}
+
+	// Service errors back off exponentially
+	for attempt, want := range []time.Duration{10 * time.Second, 20 * time.Second} {
+		if transfer, scheduledIn := step(); transfer.Attempts != attempt+1 || !scheduledIn(want) {
+			t.Fatalf("failure %d: %d attempts retried at %d, want %d in %s", attempt+1, transfer.Attempts, transfer.NextAttemptAt, attempt+1, want)
+		}
+	}
+
+	transfer, _ := step()
+	if transfer.Status != TransferReceiving || transfer.Attempts != 0 || receiver.sent != 1 {
+		t.Fatalf("transfer %s with %d attempts after %d receives, want receiving after 1", transfer.Status, transfer.Attempts, receiver.sent)
+	}
+
+	// A pending receive is reconciled instead of resent
+	if transfer, _ := step(); transfer.Status != TransferReceiving || receiver.sent != 1 {
+		t.Fatalf("transfer %s after %d receives, want still receiving after 1", transfer.Status, receiver.sent)
+	}
+	receiver.state = client.TxSucceeded
+	if transfer, _ := step(); transfer.Status != TransferMinted || receiver.sent != 1 {
+		t.Fatalf("transfer %s after %d receives, want minted after 1", transfer.Status, receiver.sent)
+	}
+}
+
+func TestRelayerFailsAfterMaxAttempts(t *testing.T) {
+	server := attestationStub(t, "", "error")
+	r := &Relayer{
+		attestation:  NewAttestationClient(server.URL),
+		config:       RelayerConfig{MaxAttempts: 2, RetryBase: time.Second, RetryMax: time.Second},
+		transfers:    map[string]Transfer{"burn:0": {ID: "burn:0", Status: TransferAwaitingAttestation}},
+		running:      make(map[string]bool),
+		transmitters: make(map[uint64]messageReceiver),
+	}
+
+	r.process("burn:0")
+	r.process("burn:0")
+	if transfer := r.transfers["burn:0"]; transfer.Status != TransferFailed || transfer.Attempts != 2 {
+		t.Fatalf("transfer %s with %d attempts, want failed after 2", transfer.Status, transfer.Attempts)
+	}
+	if ids := r.due(time.Now().Add(time.Hour)); len(ids) != 0 {
+		t.Fatalf("due() = %v, failed transfers must wait for an operator", ids)
+	}
+}
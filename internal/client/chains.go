@@ -0,0 +1,199 @@
+package client
+
+import (
+	"blockchess/internal/money"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// defaultChainsPath is the chain registry used when CHAINS_CONFIG_PATH is not set
+const defaultChainsPath = "config/chains.json"
+
+// USDCConfig describes the USDC token on a chain
+type USDCConfig struct {
+	Address  string `json:"address"`
+	Decimals uint8  `json:"decimals"`
+}
+
+// ChainConfig represents configuration for a specific blockchain
+type ChainConfig struct {
+	ChainID              uint64     `json:"chainId"`
+	Name                 string     `json:"name"`
+	Enabled              bool       `json:"enabled"`
+	RPCUrls              []string   `json:"rpcUrls"` // Tried in order, ${VAR} is expanded from the environment
+	USDC                 USDCConfig `json:"usdc"`
+	VaultContractAddress string     `json:"vaultAddress,omitempty"`
+	EnvVaultKey          string     `json:"vaultEnv,omitempty"` // Environment variable key for vault address
+	Permit2Address       string     `json:"permit2Address,omitempty"`
+	CCTPDomain           uint32     `json:"cctpDomain"`
+	Confirmations        uint64     `json:"confirmations"` // Blocks a transaction needs on top before it is final
+}
+
+// chainRegistryFile is the layout of the chain registry file
+type chainRegistryFile struct {
+	Chains []ChainConfig `json:"chains"`
+}
+
+// loadSupportedChains loads the enabled chains from the registry file and applies environment overrides
+func loadSupportedChains() map[uint64]ChainConfig {
+	loadEnv()
+
+	path := os.Getenv("CHAINS_CONFIG_PATH")
+	if path == "" {
+		path = defaultChainsPath
+	}
+
+	chains, err := LoadChainRegistry(path)
+	if err != nil {
+		log.Printf("Warning: Failed to load chain registry, no chains are available: %v", err)
+		return make(map[uint64]ChainConfig)
+	}
+	return chains
+}
+
+// LoadChainRegistry reads a chain registry file, applies environment overrides and returns the enabled chains
+func LoadChainRegistry(path string) (map[uint64]ChainConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chain registry %s: %w", path, err)
+	}
+
+	var file chainRegistryFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse chain registry %s: %w", path, err)
+	}
+
+	chains := make(map[uint64]ChainConfig)
+	for _, config := range file.Chains {
+		if config.ChainID == 0 {
+			log.Printf("Warning: Skipping chain %q without a chain ID in %s", config.Name, path)
+			continue
+		}
+		if _, exists := chains[config.ChainID]; exists {
+			return nil, fmt.Errorf("chain %d is listed twice in %s", config.ChainID, path)
+		}
+
+		applyChainOverrides(&config)
+		if !config.Enabled {
+			continue
+		}
+		if err := validateChainConfig(config); err != nil {
+			log.Printf("Warning: Disabling chain %d: %v", config.ChainID, err)
+			continue
+		}
+		chains[config.ChainID] = config
+	}
+
+	log.Printf("Loaded %d enabled chains from %s", len(chains), path)
+	return chains, nil
+}
+
+// applyChainOverrides applies CHAIN_*_<chainId> environment variables and resolves RPC URLs and the vault address
+func applyChainOverrides(config *ChainConfig) {
+	suffix := "_" + strconv.FormatUint(config.ChainID, 10)
+
+	if value := os.Getenv("CHAIN_ENABLED" + suffix); value != "" {
+		config.Enabled = value == "true" || value == "1"
+	}
+	if value := os.Getenv("CHAIN_RPC_URLS" + suffix); value != "" {
+		config.RPCUrls = strings.Split(value, ",")
+	}
+	if value := os.Getenv("CHAIN_USDC_ADDRESS" + suffix); value != "" {
+		config.USDC.Address = value
+	}
+	config.USDC.Decimals = uint8(GetEnvInt("CHAIN_USDC_DECIMALS"+suffix, int(config.USDC.Decimals)))
+	if value := os.Getenv("CHAIN_PERMIT2_ADDRESS" + suffix); value != "" {
+		config.Permit2Address = value
+	}
+	config.CCTPDomain = uint32(GetEnvInt("CHAIN_CCTP_DOMAIN"+suffix, int(config.CCTPDomain)))
+	config.Confirmations = uint64(GetEnvInt("CHAIN_CONFIRMATIONS"+suffix, int(config.Confirmations)))
+
+	// The vault address comes from the chain override, then the chain's own variable, then the file
+	if value := os.Getenv("CHAIN_VAULT_ADDRESS" + suffix); value != "" {
+		config.VaultContractAddress = value
+	} else if config.EnvVaultKey != "" && os.Getenv(config.EnvVaultKey) != "" {
+		config.VaultContractAddress = os.Getenv(config.EnvVaultKey)
+	}
+
+	var rpcURLs []string
+	for _, rawURL := range config.RPCUrls {
+		rpcURL, ok := expandRPCURL(strings.TrimSpace(rawURL))
+		if !ok {
+			log.Printf("Warning: Skipping RPC endpoint of chain %d with unset variables: %s", config.ChainID, rawURL)
+			continue
+		}
+		rpcURLs = append(rpcURLs, rpcURL)
+	}
+	config.RPCUrls = rpcURLs
+}
+
+// expandRPCURL replaces ${VAR} references with environment values, reporting false when one is unset
+func expandRPCURL(rawURL string) (string, bool) {
+	complete := rawURL != ""
+	expanded := os.Expand(rawURL, func(name string) string {
+		value := os.Getenv(name)
+		if value == "" {
+			complete = false
+		}
+		return value
+	})
+	return expanded, complete
+}
+
+// validateChainConfig checks that an enabled chain can be used
+func validateChainConfig(config ChainConfig) error {
+	if len(config.RPCUrls) == 0 {
+		return fmt.Errorf("no RPC endpoint configured")
+	}
+	if config.USDC.Address != "" && !common.IsHexAddress(config.USDC.Address) {
+		return fmt.Errorf("invalid USDC address %s", config.USDC.Address)
+	}
+	if config.VaultContractAddress != "" && !common.IsHexAddress(config.VaultContractAddress) {
+		return fmt.Errorf("invalid vault address %s", config.VaultContractAddress)
+	}
+	if config.Permit2Address != "" && !common.IsHexAddress(config.Permit2Address) {
+		return fmt.Errorf("invalid Permit2 address %s", config.Permit2Address)
+	}
+	return nil
+}
+
+// GetUSDCAddress returns the USDC token address for a specific chain ID
+func GetUSDCAddress(chainID uint64) string {
+	return supportedChains[chainID].USDC.Address
+}
+
+// GetUSDCDecimals returns the decimals of the USDC token on a specific chain ID, 6 if the registry does not set them
+func GetUSDCDecimals(chainID uint64) uint8 {
+	if decimals := supportedChains[chainID].USDC.Decimals; decimals != 0 {
+		return decimals
+	}
+	return money.USDCDecimals
+}
+
+// USDCUnits converts an amount to base units of the USDC token on a specific chain ID
+func USDCUnits(chainID uint64, amount money.Amount) (*big.Int, error) {
+	return amount.BigIntFor(GetUSDCDecimals(chainID))
+}
+
+// USDCAmount converts base units of the USDC token on a specific chain ID to an amount
+func USDCAmount(chainID uint64, units *big.Int) (money.Amount, error) {
+	return money.FromBig(units, GetUSDCDecimals(chainID))
+}
+
+// GetCCTPDomain returns the CCTP domain of a specific chain ID
+func GetCCTPDomain(chainID uint64) (uint32, bool) {
+	config, exists := supportedChains[chainID]
+	return config.CCTPDomain, exists
+}
+
+// GetConfirmations returns the number of confirmations a transaction needs on a specific chain ID
+func GetConfirmations(chainID uint64) uint64 {
+	return supportedChains[chainID].Confirmations
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
 	"strconv"
 	"sync"
 
@@ -11,110 +12,30 @@ import (
 	"github.com/joho/godotenv"
 )
 
-// ChainConfig represents configuration for a specific blockchain
-type ChainConfig struct {
-	RPCUrl               string
-	VaultContractAddress string
-	Permit2Address       string // Add Permit2 contract address
-	Name                 string
-	EnvVaultKey          string // Environment variable key for vault address
-}
-
 // Clients holds all blockchain clients for different chains
 type Clients struct {
 	clients    map[uint64]*ethclient.Client
-	privateKey string // Store the private key
+	pools      map[uint64]*RPCPool   // chainID -> RPC endpoints behind the chain's client
+	txManagers map[uint64]*TxManager // chainID -> shared transaction manager for the backend signer
+	signers    map[uint64]Signer     // chainID -> signer of the backend's transactions
+	disabled   map[uint64]string     // chainID -> why the preflight disabled the chain
+	preflight  []PreflightReport
 	mu         sync.RWMutex
 }
 
 // NewClients creates a new Clients instance
 func NewClients() *Clients {
 	return &Clients{
-		clients: make(map[uint64]*ethclient.Client),
+		clients:    make(map[uint64]*ethclient.Client),
+		pools:      make(map[uint64]*RPCPool),
+		txManagers: make(map[uint64]*TxManager),
+		signers:    make(map[uint64]Signer),
+		disabled:   make(map[uint64]string),
 	}
 }
 
-// supportedChains defines all supported blockchain configurations
-var supportedChains = map[uint64]ChainConfig{
-	// Ethereum Sepolia
-	11155111: {
-		RPCUrl:      "https://eth-sepolia.g.alchemy.com/v2/22r8dairb21cjlw7",
-		Name:        "Ethereum Sepolia",
-		EnvVaultKey: "ANVIL_ETHEREUM_SEPOLIA_VAULT_CONTRACT_ADDRESS",
-	},
-	// Avalanche Fuji
-	43113: {
-		RPCUrl:      "https://avax-fuji.g.alchemy.com/v2/22r8dairb21cjlw7",
-		Name:        "Avalanche Fuji",
-		EnvVaultKey: "AVALANCHE_FUJI_VAULT_ADDRESS",
-	},
-	// OP Sepolia
-	11155420: {
-		RPCUrl:      "http://127.0.0.1:8546",
-		Name:        "OP Sepolia",
-		EnvVaultKey: "ANVIL_OPTIMISM_SEPOLIA_VAULT_CONTRACT_ADDRESS",
-	},
-	// Arbitrum Sepolia
-	421614: {
-		RPCUrl:      "https://arb-sepolia.g.alchemy.com/v2/22r8dairb21cjlw7",
-		Name:        "Arbitrum Sepolia",
-		EnvVaultKey: "ARBITRUM_SEPOLIA_VAULT_ADDRESS",
-	},
-	// Base Sepolia
-	84532: {
-		RPCUrl:      "http://127.0.0.1:8545",
-		Name:        "Base Sepolia",
-		EnvVaultKey: "ANVIL_BASE_SEPOLIA_VAULT_ADDRESS",
-	},
-	// Polygon Amoy
-	80002: {
-		RPCUrl:      "https://polygon-amoy.g.alchemy.com/v2/22r8dairb21cjlw7",
-		Name:        "Polygon Amoy",
-		EnvVaultKey: "POLYGON_AMOY_VAULT_ADDRESS",
-	},
-	// Unichain Sepolia
-	1301: {
-		RPCUrl:      "https://eth-mainnet.g.alchemy.com/v2/22r8dairb21cjlw7", // Placeholder
-		Name:        "Unichain Sepolia",
-		EnvVaultKey: "UNICHAIN_SEPOLIA_VAULT_ADDRESS",
-	},
-	// Linea Sepolia
-	59141: {
-		RPCUrl:      "https://linea-sepolia.g.alchemy.com/v2/22r8dairb21cjlw7",
-		Name:        "Linea Sepolia",
-		EnvVaultKey: "LINEA_SEPOLIA_VAULT_ADDRESS",
-	},
-	// Sonic Testnet
-	64165: {
-		RPCUrl:      "https://sonic-blaze.g.alchemy.com/v2/Kv5V9_Sv55ZosXpoVga3IfXYO6JV9gNJ",
-		Name:        "Sonic Testnet",
-		EnvVaultKey: "SONIC_TESTNET_VAULT_ADDRESS",
-	},
-	// World Chain Sepolia
-	4801: {
-		RPCUrl:      "https://eth-mainnet.g.alchemy.com/v2/22r8dairb21cjlw7", // Placeholder
-		Name:        "World Chain Sepolia",
-		EnvVaultKey: "WORLD_CHAIN_SEPOLIA_VAULT_ADDRESS",
-	},
-	// Codex Testnet
-	325000: {
-		RPCUrl:      "https://eth-mainnet.g.alchemy.com/v2/22r8dairb21cjlw7", // Placeholder
-		Name:        "Codex Testnet",
-		EnvVaultKey: "CODEX_TESTNET_VAULT_ADDRESS",
-	},
-	// Local Anvil (for development)
-	31337: {
-		RPCUrl:      "http://127.0.0.1:8545",
-		Name:        "Anvil Local (Base Sepolia)",
-		EnvVaultKey: "ANVIL_BASE_SEPOLIA_VAULT_ADDRESS",
-	},
-	// Local Anvil (for development)
-	31338: {
-		RPCUrl:      "http://127.0.0.1:8546",
-		Name:        "Anvil Local (Optimism Sepolia)",
-		EnvVaultKey: "ANVIL_OPTIMISM_SEPOLIA_VAULT_ADDRESS",
-	},
-}
+// supportedChains holds the enabled chains of the chain registry
+var supportedChains = loadSupportedChains()
 
 // envLoader ensures .env is loaded only once
 var envLoader sync.Once
@@ -130,48 +51,52 @@ func loadEnv() {
 func InitializeClients() (*Clients, error) {
 	clients := NewClients()
 
-	// Store the pre-loaded private key
-	if PrivateKey == "" {
-		log.Fatalf("PRIVATE_KEY not set in environment")
-	} else {
-		clients.setPrivateKey(PrivateKey)
-	}
-
+	poolConfig := LoadRPCPoolConfig()
 	for chainID, config := range supportedChains {
-		client, err := NewClient(chainID)
+		pool, err := NewRPCPool(chainID, config.Name, config.RPCUrls, poolConfig)
 		if err != nil {
 			log.Printf("Warning: Failed to initialize %s client: %v", config.Name, err)
 			continue
 		}
-		clients.setClient(chainID, client)
+		clients.setPool(chainID, pool)
+		go pool.Run()
 	}
 
 	log.Printf("Blockchain clients initialized successfully")
 	return clients, nil
 }
 
-// setClient safely sets a client for a chain ID
-func (c *Clients) setClient(chainID uint64, client *ethclient.Client) {
+// setPool safely sets the RPC pool and client for a chain ID
+func (c *Clients) setPool(chainID uint64, pool *RPCPool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.clients[chainID] = client
+	c.pools[chainID] = pool
+	c.clients[chainID] = pool.Client()
 }
 
-// setPrivateKey safely sets the private key
-func (c *Clients) setPrivateKey(privateKey string) {
+// GetSigner returns the signer of the backend's transactions on a chain, loading it on first use
+func (c *Clients) GetSigner(chainID uint64) (Signer, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.privateKey = privateKey
+	return c.getSignerUnsafe(chainID)
 }
 
-// GetPrivateKey safely gets the private key
-func (c *Clients) GetPrivateKey() string {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	return c.privateKey
+// getSignerUnsafe returns the signer of a chain, loading it on first use (caller must hold the lock)
+func (c *Clients) getSignerUnsafe(chainID uint64) (Signer, error) {
+	if signer, exists := c.signers[chainID]; exists {
+		return signer, nil
+	}
+
+	signer, err := LoadSigner(chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load signer for chain %d: %w", chainID, err)
+	}
+	log.Printf("Using %s signer %s on chain %d", signer.Kind(), signer.Address().Hex(), chainID)
+	c.signers[chainID] = signer
+	return signer, nil
 }
 
-// GetClientByChainID returns the appropriate client for a given chain ID
+// GetClientByChainID returns the client of a chain, which sends every request to a healthy RPC endpoint
 func (c *Clients) GetClientByChainID(chainID uint64) (*ethclient.Client, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -184,9 +109,67 @@ func (c *Clients) GetClientByChainID(chainID uint64) (*ethclient.Client, error)
 		}
 		return nil, fmt.Errorf("%s client not initialized", config.Name)
 	}
+	if pool, exists := c.pools[chainID]; exists && !pool.Healthy() {
+		return nil, fmt.Errorf("no healthy RPC endpoint for %s", pool.name)
+	}
 	return client, nil
 }
 
+// ChainStatuses returns the RPC health of every initialized chain, ordered by chain ID
+func (c *Clients) ChainStatuses() []ChainStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	statuses := make([]ChainStatus, 0, len(c.pools))
+	for _, pool := range c.pools {
+		statuses = append(statuses, pool.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].ChainID < statuses[j].ChainID })
+	return statuses
+}
+
+// DisabledReason reports whether the preflight disabled a chain and why
+func (c *Clients) DisabledReason(chainID uint64) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	reason, disabled := c.disabled[chainID]
+	return reason, disabled
+}
+
+// PreflightReports returns the results of the startup checks
+func (c *Clients) PreflightReports() []PreflightReport {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.preflight
+}
+
+// GetTxManager returns the transaction manager shared by every contract the backend signer writes to on a chain
+func (c *Clients) GetTxManager(chainID uint64) (*TxManager, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if txManager, exists := c.txManagers[chainID]; exists {
+		return txManager, nil
+	}
+
+	client, exists := c.clients[chainID]
+	if !exists || client == nil {
+		return nil, fmt.Errorf("no client initialized for chain ID: %d", chainID)
+	}
+
+	signer, err := c.getSignerUnsafe(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	txManager, err := NewTxManager(client, signer, chainID, LoadTxConfig(chainID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction manager for chain %d: %w", chainID, err)
+	}
+	c.txManagers[chainID] = txManager
+	return txManager, nil
+}
+
 // Close closes all blockchain client connections
 func (c *Clients) Close() {
 	c.mu.Lock()
@@ -200,15 +183,12 @@ func (c *Clients) Close() {
 	}
 }
 
-// LoadChainConfigs loads chain configurations from environment variables
+// LoadChainConfigs returns a copy of the chain registry
 func LoadChainConfigs() map[uint64]ChainConfig {
 	loadEnv()
 
 	configs := make(map[uint64]ChainConfig)
-	for chainID, baseConfig := range supportedChains {
-		config := baseConfig
-		config.VaultContractAddress = os.Getenv(baseConfig.EnvVaultKey)
-		config.Permit2Address = GetPermit2Address(chainID)
+	for chainID, config := range supportedChains {
 		configs[chainID] = config
 	}
 
@@ -218,9 +198,6 @@ func LoadChainConfigs() map[uint64]ChainConfig {
 // ChainConfigs holds the loaded chain configurations
 var ChainConfigs = LoadChainConfigs()
 
-// PrivateKey holds the loaded private key
-var PrivateKey = LoadPrivateKey()
-
 // GameFactoryAddress holds the loaded GameFactory contract address
 var GameFactoryAddress = LoadGameFactoryAddress()
 
@@ -230,11 +207,8 @@ var VaultAddresses = LoadVaultAddresses()
 // Permit2Addresses holds the loaded Permit2 contract addresses for all chains
 var Permit2Addresses = LoadPermit2Addresses()
 
-// LoadPrivateKey loads the private key from environment variables
-func LoadPrivateKey() string {
-	loadEnv()
-	return os.Getenv("PRIVATE_KEY")
-}
+// MessageTransmitterAddresses holds the loaded CCTP MessageTransmitterV2 addresses for all chains
+var MessageTransmitterAddresses = LoadMessageTransmitterAddresses()
 
 // LoadGameFactoryAddress loads the GameFactory contract address from environment variables
 func LoadGameFactoryAddress() string {
@@ -242,43 +216,59 @@ func LoadGameFactoryAddress() string {
 	return os.Getenv("GAME_FACTORY_ADDRESS")
 }
 
-// LoadVaultAddresses loads all vault contract addresses from environment variables
+// LoadVaultAddresses loads all vault contract addresses from the chain registry
 func LoadVaultAddresses() map[uint64]string {
 	loadEnv()
 
 	vaultAddresses := make(map[uint64]string)
 	for chainID, config := range supportedChains {
-		address := os.Getenv(config.EnvVaultKey)
-		if address != "" {
-			vaultAddresses[chainID] = address
+		if config.VaultContractAddress != "" {
+			vaultAddresses[chainID] = config.VaultContractAddress
 		}
 	}
 
 	return vaultAddresses
 }
 
-// LoadPermit2Addresses loads all Permit2 contract addresses from environment variables
+// LoadPermit2Addresses loads all Permit2 contract addresses from the chain registry,
+// PERMIT2_ADDRESS overrides the address on every chain
 func LoadPermit2Addresses() map[uint64]string {
 	loadEnv()
 
-	// Permit2 is deployed at the same address on all chains
-	permit2Address := os.Getenv("PERMIT2_ADDRESS")
-	if permit2Address == "" {
-		// Use the canonical Permit2 address if not specified
-		permit2Address = "0x000000000022D473030F116dDEE9F6B43aC78BA3"
-	}
+	override := os.Getenv("PERMIT2_ADDRESS")
 
 	permit2Addresses := make(map[uint64]string)
-	for chainID := range supportedChains {
-		permit2Addresses[chainID] = permit2Address
+	for chainID, config := range supportedChains {
+		switch {
+		case override != "":
+			permit2Addresses[chainID] = override
+		case config.Permit2Address != "":
+			permit2Addresses[chainID] = config.Permit2Address
+		default:
+			// Use the canonical Permit2 address if not specified
+			permit2Addresses[chainID] = "0x000000000022D473030F116dDEE9F6B43aC78BA3"
+		}
 	}
 
 	return permit2Addresses
 }
 
-// GetPrivateKey returns the private key from environment variables
-func GetPrivateKey() string {
-	return PrivateKey
+// LoadMessageTransmitterAddresses loads all CCTP MessageTransmitterV2 addresses from environment variables
+func LoadMessageTransmitterAddresses() map[uint64]string {
+	loadEnv()
+
+	// MessageTransmitterV2 is deployed at the same address on all CCTP testnets
+	transmitterAddress := os.Getenv("CCTP_MESSAGE_TRANSMITTER_ADDRESS")
+	if transmitterAddress == "" {
+		transmitterAddress = "0xE737e5cEBEEBa77EFE34D4aa090756590b1CE275"
+	}
+
+	transmitterAddresses := make(map[uint64]string)
+	for chainID := range supportedChains {
+		transmitterAddresses[chainID] = transmitterAddress
+	}
+
+	return transmitterAddresses
 }
 
 // GetGameFactoryAddress returns the GameFactory contract address (deployed only on Base Sepolia)
@@ -296,6 +286,11 @@ func GetPermit2Address(chainID uint64) string {
 	return Permit2Addresses[chainID]
 }
 
+// GetMessageTransmitterAddress returns the CCTP MessageTransmitterV2 address for a specific chain ID
+func GetMessageTransmitterAddress(chainID uint64) string {
+	return MessageTransmitterAddresses[chainID]
+}
+
 // GetEnv gets an environment variable with a default value
 func GetEnv(key, defaultValue string) string {
 	loadEnv()
@@ -326,21 +321,6 @@ func GetEnvRequired(key string) (string, error) {
 	return value, nil
 }
 
-// NewClient creates a new Ethereum client for the specified chain ID
-func NewClient(chainID uint64) (*ethclient.Client, error) {
-	config, exists := supportedChains[chainID]
-	if !exists {
-		return nil, fmt.Errorf("unsupported chain ID: %d", chainID)
-	}
-
-	client, err := ethclient.Dial(config.RPCUrl)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to %s: %w", config.Name, err)
-	}
-
-	return client, nil
-}
-
 // GetChainConfig returns the configuration for a specific chain ID
 func GetChainConfig(chainID uint64) (ChainConfig, error) {
 	config, exists := ChainConfigs[chainID]
@@ -371,7 +351,8 @@ func GetSupportedChains() []uint64 {
 	return chains
 }
 
-// ReloadChainConfigs reloads chain configurations from environment variables
+// ReloadChainConfigs reloads chain configurations from the chain registry
 func ReloadChainConfigs() {
+	supportedChains = loadSupportedChains()
 	ChainConfigs = LoadChainConfigs()
 }
@@ -1,7 +1,6 @@
 package client
 
 import (
-	"context"
 	"fmt"
 	"log"
 	"math/big"
@@ -11,19 +10,22 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
+// GameFactoryChainID is the chain the GameFactory is deployed on (Base Sepolia)
+const GameFactoryChainID uint64 = 84532
+
 // GameFactory wraps the GameFactory contract instance
 type GameFactory struct {
-	contract *gamefactory.Gamefactory
-	client   *ethclient.Client
-	auth     *bind.TransactOpts
+	contract  *gamefactory.Gamefactory
+	client    *ethclient.Client
+	txManager *TxManager
 }
 
 // NewGameFactory creates a new GameFactory instance connected to Base Sepolia
-func NewGameFactory(client *ethclient.Client, privateKey string) (*GameFactory, error) {
+func NewGameFactory(client *ethclient.Client, txManager *TxManager) (*GameFactory, error) {
 	// Get GameFactory contract address from environment
 	factoryAddress := GetGameFactoryAddress()
 	if factoryAddress == "" {
@@ -40,34 +42,14 @@ func NewGameFactory(client *ethclient.Client, privateKey string) (*GameFactory,
 		return nil, fmt.Errorf("failed to create GameFactory contract instance: %w", err)
 	}
 
-	// Validate private key
-	if privateKey == "" {
-		return nil, fmt.Errorf("private key cannot be empty")
-	}
-
-	// Remove 0x prefix if present
-	privateKey = strings.TrimPrefix(privateKey, "0x")
-
-	key, err := crypto.HexToECDSA(privateKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse private key: %w", err)
-	}
-
-	// Get chain ID for transaction auth
-	chainID, err := client.ChainID(nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get chain ID: %w", err)
-	}
-
-	auth, err := bind.NewKeyedTransactorWithChainID(key, chainID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create transactor: %w", err)
+	if txManager == nil {
+		return nil, fmt.Errorf("transaction manager cannot be nil")
 	}
 
 	return &GameFactory{
-		contract: contract,
-		client:   client,
-		auth:     auth,
+		contract:  contract,
+		client:    client,
+		txManager: txManager,
 	}, nil
 }
 
@@ -75,20 +57,14 @@ func NewGameFactory(client *ethclient.Client, privateKey string) (*GameFactory,
 func (gf *GameFactory) CreateGame(fixedStakeAmount *big.Int) (uint64, error) {
 	log.Printf("Creating new game with stake amount: %s USDC", fixedStakeAmount.String())
 
-	// Call the createGame function
-	tx, err := gf.contract.CreateGame(gf.auth, fixedStakeAmount)
+	// Call the createGame function and wait for it to be mined
+	receipt, err := gf.txManager.SendAndWait(func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return gf.contract.CreateGame(opts, fixedStakeAmount)
+	})
 	if err != nil {
 		return 0, fmt.Errorf("failed to create game transaction: %w", err)
 	}
 
-	log.Printf("Game creation transaction sent: %s", tx.Hash().Hex())
-
-	// Wait for transaction to be mined
-	receipt, err := bind.WaitMined(context.Background(), gf.client, tx)
-	if err != nil {
-		return 0, fmt.Errorf("failed to wait for transaction to be mined: %w", err)
-	}
-
 	if receipt.Status != 1 {
 		return 0, fmt.Errorf("transaction failed with status: %d", receipt.Status)
 	}
@@ -113,26 +89,61 @@ func (gf *GameFactory) AddVote(gameID uint64, playerAddress common.Address, chai
 
 	gameIDBig := new(big.Int).SetUint64(gameID)
 
-	// Call the addVote function
-	tx, err := gf.contract.AddVote(gf.auth, gameIDBig, playerAddress, chainID, team)
+	// Call the addVote function and wait for it to be mined
+	receipt, err := gf.txManager.SendAndWait(func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return gf.contract.AddVote(opts, gameIDBig, playerAddress, chainID, team)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to add vote transaction: %w", err)
 	}
 
-	log.Printf("Add vote transaction sent: %s", tx.Hash().Hex())
+	if receipt.Status != 1 {
+		return fmt.Errorf("transaction failed with status: %d", receipt.Status)
+	}
+
+	log.Printf("Vote added successfully for player %s in game %d", playerAddress.Hex(), gameID)
+	return nil
+}
+
+// VoteRecord is one vote recorded on-chain as part of a batch
+type VoteRecord struct {
+	Player  common.Address
+	ChainID uint32
+	Team    uint8
+}
+
+// AddVotes records a batch of votes in a single transaction and returns its hash
+func (gf *GameFactory) AddVotes(gameID uint64, votes []VoteRecord) (common.Hash, error) {
+	if len(votes) == 0 {
+		return common.Hash{}, fmt.Errorf("no votes to add")
+	}
+
+	log.Printf("Adding %d votes in game %d", len(votes), gameID)
+
+	gameIDBig := new(big.Int).SetUint64(gameID)
+	inputs := make([]gamefactory.IGameContractVoteInput, len(votes))
+	for i, vote := range votes {
+		inputs[i] = gamefactory.IGameContractVoteInput{
+			Player:  vote.Player,
+			ChainId: vote.ChainID,
+			Team:    vote.Team,
+		}
+	}
 
-	// Wait for transaction to be mined
-	receipt, err := bind.WaitMined(context.Background(), gf.client, tx)
+	// Call the addVotes function and wait for it to be mined
+	receipt, err := gf.txManager.SendAndWait(func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return gf.contract.AddVotes(opts, gameIDBig, inputs)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to wait for transaction to be mined: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to add votes transaction: %w", err)
 	}
 
 	if receipt.Status != 1 {
-		return fmt.Errorf("transaction failed with status: %d", receipt.Status)
+		return common.Hash{}, fmt.Errorf("transaction failed with status: %d", receipt.Status)
 	}
 
-	log.Printf("Vote added successfully for player %s in game %d", playerAddress.Hex(), gameID)
-	return nil
+	log.Printf("Added %d votes in game %d", len(votes), gameID)
+	return receipt.TxHash, nil
 }
 
 // EndGame ends a game with the specified result
@@ -141,20 +152,14 @@ func (gf *GameFactory) EndGame(gameID uint64, result uint8) error {
 
 	gameIDBig := new(big.Int).SetUint64(gameID)
 
-	// Call the endGame function
-	tx, err := gf.contract.EndGame(gf.auth, gameIDBig, result)
+	// Call the endGame function and wait for it to be mined
+	receipt, err := gf.txManager.SendAndWait(func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return gf.contract.EndGame(opts, gameIDBig, result)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to end game transaction: %w", err)
 	}
 
-	log.Printf("End game transaction sent: %s", tx.Hash().Hex())
-
-	// Wait for transaction to be mined
-	receipt, err := bind.WaitMined(context.Background(), gf.client, tx)
-	if err != nil {
-		return fmt.Errorf("failed to wait for transaction to be mined: %w", err)
-	}
-
 	if receipt.Status != 1 {
 		return fmt.Errorf("transaction failed with status: %d", receipt.Status)
 	}
@@ -163,6 +168,40 @@ func (gf *GameFactory) EndGame(gameID uint64, result uint8) error {
 	return nil
 }
 
+// SendEndGame submits the end game transaction without waiting for it to be mined.
+// The transaction manager keeps replacing it with higher fees while it is stuck.
+func (gf *GameFactory) SendEndGame(gameID uint64, result uint8) (*types.Transaction, error) {
+	log.Printf("Ending game %d with result: %d", gameID, result)
+
+	gameIDBig := new(big.Int).SetUint64(gameID)
+
+	// Call the endGame function
+	tx, err := gf.txManager.Submit(func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return gf.contract.EndGame(opts, gameIDBig, result)
+	}, TxHooks{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to end game transaction: %w", err)
+	}
+
+	return tx, nil
+}
+
+// IsGameActive checks whether a game has not been ended on-chain yet
+func (gf *GameFactory) IsGameActive(gameID uint64) (bool, error) {
+	gameIDBig := new(big.Int).SetUint64(gameID)
+	active, err := gf.contract.GetGameStatus(nil, gameIDBig)
+	if err != nil {
+		return false, fmt.Errorf("failed to get game status: %w", err)
+	}
+	return active, nil
+}
+
+// TransactionState reports the state of a transaction previously sent by the GameFactory signer and
+// the hash of its version that was mined or is current
+func (gf *GameFactory) TransactionState(txHash common.Hash, nonce uint64) (TxState, common.Hash, error) {
+	return gf.txManager.TransactionState(txHash, nonce)
+}
+
 // GetGameExists checks if a game exists
 func (gf *GameFactory) GetGameExists(gameID uint64) (bool, error) {
 	gameIDBig := new(big.Int).SetUint64(gameID)
@@ -2,14 +2,17 @@ package client
 
 import (
 	"blockchess/contracts-bindings/permit2"
+	"blockchess/internal/money"
+	"context"
 	"fmt"
+	"log"
 	"math/big"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 )
@@ -20,10 +23,16 @@ type Permit2Client struct {
 	client   *ethclient.Client
 	address  common.Address
 	chainID  uint64
+	usdc     *USDCToken // EIP-2612 permits for wallets that have not approved Permit2, nil without a USDC address
+
+	// Unordered nonces handed out for signature transfers - owner -> nonce -> signature deadline
+	reservedNonces map[common.Address]map[uint64]int64
+	nonceMutex     sync.Mutex
 }
 
 // PermitSignatureData represents the data needed for a Permit2 signature
 type PermitSignatureData struct {
+	Kind        string         `json:"kind"` // PermitKindPermit2 or PermitKindEIP2612
 	Owner       common.Address `json:"owner"`
 	Spender     common.Address `json:"spender"`
 	Token       common.Address `json:"token"`
@@ -48,19 +57,67 @@ func NewPermit2Client(client *ethclient.Client, chainID uint64) (*Permit2Client,
 		return nil, fmt.Errorf("failed to create Permit2 contract instance: %w", err)
 	}
 
+	usdc, err := NewUSDCToken(client, chainID)
+	if err != nil {
+		log.Printf("Warning: USDC permits unavailable on chain %d: %v", chainID, err)
+	}
+
 	return &Permit2Client{
 		contract: contract,
 		client:   client,
 		address:  contractAddress,
 		chainID:  chainID,
+		usdc:     usdc,
+
+		reservedNonces: make(map[common.Address]map[uint64]int64),
 	}, nil
 }
 
+// Allowance is a spender's live Permit2 allowance on a user's token
+type Allowance struct {
+	Amount     *big.Int // Remaining amount the spender may transfer
+	Expiration *big.Int // Unix time the allowance expires
+	Nonce      *big.Int // Nonce the next permit must be signed with
+}
+
+// GetAllowance reads the Permit2 allowance of a user's token-spender pair
+func (p *Permit2Client) GetAllowance(owner, token, spender common.Address) (Allowance, error) {
+	allowance, err := p.contract.Allowance(&bind.CallOpts{}, owner, token, spender)
+	if err != nil {
+		return Allowance{}, fmt.Errorf("failed to get allowance: %w", err)
+	}
+	return Allowance{Amount: allowance.Amount, Expiration: allowance.Expiration, Nonce: allowance.Nonce}, nil
+}
+
+// USDC returns the chain's USDC token
+func (p *Permit2Client) USDC() (*USDCToken, error) {
+	if p.usdc == nil {
+		return nil, fmt.Errorf("USDC token not available on chain %d", p.chainID)
+	}
+	return p.usdc, nil
+}
+
+// Address returns the Permit2 contract address
+func (p *Permit2Client) Address() common.Address {
+	return p.address
+}
+
+// GetPermitAllowance reads the live allowance a permit grants, from Permit2 or from the token for EIP-2612 permits
+func (p *Permit2Client) GetPermitAllowance(permitData *PermitSignatureData) (Allowance, error) {
+	if permitData.Kind == PermitKindEIP2612 {
+		if p.usdc == nil {
+			return Allowance{}, fmt.Errorf("USDC permits unavailable on chain %d", p.chainID)
+		}
+		return p.usdc.GetAllowance(permitData.Owner, permitData.Spender)
+	}
+	return p.GetAllowance(permitData.Owner, permitData.Token, permitData.Spender)
+}
+
 // GetNonce gets the current nonce for a user's token-spender pair
 func (p *Permit2Client) GetNonce(owner, token, spender common.Address) (*big.Int, error) {
-	allowance, err := p.contract.Allowance(&bind.CallOpts{}, owner, token, spender)
+	allowance, err := p.GetAllowance(owner, token, spender)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get allowance: %w", err)
+		return nil, err
 	}
 	return allowance.Nonce, nil
 }
@@ -116,6 +173,49 @@ func (p *Permit2Client) CreatePermitTypedData(
 	return typedData, nil
 }
 
+// CreatePermitTransferTypedData creates EIP-712 typed data for a one-shot Permit2 signature transfer to a spender
+func (p *Permit2Client) CreatePermitTransferTypedData(
+	spender, token common.Address,
+	amount, nonce, deadline *big.Int,
+) (*apitypes.TypedData, error) {
+	typedData := &apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": []apitypes.Type{
+				{Name: "name", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"PermitTransferFrom": []apitypes.Type{
+				{Name: "permitted", Type: "TokenPermissions"},
+				{Name: "spender", Type: "address"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "deadline", Type: "uint256"},
+			},
+			"TokenPermissions": []apitypes.Type{
+				{Name: "token", Type: "address"},
+				{Name: "amount", Type: "uint256"},
+			},
+		},
+		PrimaryType: "PermitTransferFrom",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "Permit2",
+			ChainId:           (*math.HexOrDecimal256)(big.NewInt(int64(p.chainID))),
+			VerifyingContract: p.address.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"permitted": apitypes.TypedDataMessage{
+				"token":  token.Hex(),
+				"amount": amount.String(),
+			},
+			"spender":  spender.Hex(),
+			"nonce":    nonce.String(),
+			"deadline": deadline.String(),
+		},
+	}
+
+	return typedData, nil
+}
+
 // CreatePermitSignatureData creates the complete permit signature data for frontend
 func (p *Permit2Client) CreatePermitSignatureData(
 	owner, spender, token common.Address,
@@ -140,6 +240,7 @@ func (p *Permit2Client) CreatePermitSignatureData(
 	}
 
 	permitData := &PermitSignatureData{
+		Kind:        PermitKindPermit2,
 		Owner:       owner,
 		Spender:     spender,
 		Token:       token,
@@ -155,21 +256,12 @@ func (p *Permit2Client) CreatePermitSignatureData(
 
 // ExecutePermit executes a permit transaction using the provided signature
 func (p *Permit2Client) ExecutePermit(
-	privateKey string,
+	signer Signer,
 	permitData *PermitSignatureData,
 	signature string,
 ) error {
-	// Parse private key
-	privKey, err := crypto.HexToECDSA(privateKey)
-	if err != nil {
-		return fmt.Errorf("failed to parse private key: %w", err)
-	}
-
 	// Create auth
-	auth, err := bind.NewKeyedTransactorWithChainID(privKey, big.NewInt(int64(p.chainID)))
-	if err != nil {
-		return fmt.Errorf("failed to create transactor: %w", err)
-	}
+	auth := SignerTransactOpts(context.Background(), signer, p.chainID)
 
 	// Create permit single struct
 	permitSingle := permit2.IAllowanceTransferPermitSingle{
@@ -198,20 +290,8 @@ func (p *Permit2Client) ExecutePermit(
 
 // GetUSDCAddress returns the USDC contract address for the chain
 func (p *Permit2Client) GetUSDCAddress() (common.Address, error) {
-	// This should be loaded from environment variables or configuration
-	// For now, we'll use a placeholder - you should add USDC addresses to your config
-	usdcAddresses := map[uint64]string{
-		11155111: "0x1c7D4B196Cb0C7B01d743Fbc6116a902379C7238", // Ethereum Sepolia
-		84532:    "0x036CbD53842c5426634e7929541eC2318f3dCF7e", // Base Sepolia
-		43113:    "0x5425890298aed601595a70AB815c96711a31Bc65", // Avalanche Fuji
-		11155420: "0x5fd84259d66Cd46123540766Be93DFE6D43130D7", // OP Sepolia
-		421614:   "0x75faf114eafb1BDbe2F0316DF893fd58CE46AA4d", // Arbitrum Sepolia
-		80002:    "0x41e94eb019c0762f9bfcf9fb1e58725bfb0e7582", // Polygon Amoy
-		// Add more chains as needed
-	}
-
-	address, exists := usdcAddresses[p.chainID]
-	if !exists {
+	address := GetUSDCAddress(p.chainID)
+	if address == "" {
 		return common.Address{}, fmt.Errorf("USDC address not configured for chain %d", p.chainID)
 	}
 
@@ -229,8 +309,26 @@ func (p *Permit2Client) CreateGameStakePermit(
 		return nil, nil, fmt.Errorf("failed to get USDC address: %w", err)
 	}
 
-	// Set amount to a reasonable amount for game staking (e.g., 1 USDC)
-	amount := new(big.Int).SetInt64(1000000) // 1 USDC in 6 decimal places
+	// One permit covers several stakes, PERMIT_ALLOWANCE is in USDC base units (default 10 USDC)
+	amount, err := USDCUnits(p.chainID, money.USDC(int64(GetEnvInt("PERMIT_ALLOWANCE", 10000000))))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to convert permit allowance: %w", err)
+	}
+
+	// Wallets that never approved Permit2 sign USDC's own permit instead, unless USDC_PERMIT_FALLBACK is false
+	if p.usdc != nil && GetEnv("USDC_PERMIT_FALLBACK", "true") == "true" {
+		approved, err := p.usdc.AllowanceOf(owner, p.address)
+		switch {
+		case err != nil:
+			log.Printf("Warning: Failed to read Permit2 approval of %s on chain %d: %v", owner.Hex(), p.chainID, err)
+		case approved.Cmp(amount) < 0:
+			permitData, typedData, err := p.usdc.CreatePermitSignatureData(owner, vaultAddress, amount)
+			if err == nil {
+				return permitData, typedData, nil
+			}
+			log.Printf("Warning: Failed to create USDC permit for %s on chain %d, using Permit2: %v", owner.Hex(), p.chainID, err)
+		}
+	}
 
 	return p.CreatePermitSignatureData(owner, vaultAddress, usdcAddress, amount)
 }
@@ -249,6 +347,10 @@ func NewPermit2Manager(clients *Clients) (*Permit2Manager, error) {
 	// Initialize Permit2 clients for all supported chains
 	supportedChains := GetSupportedChains()
 	for _, chainID := range supportedChains {
+		if _, disabled := clients.DisabledReason(chainID); disabled {
+			continue
+		}
+
 		client, err := clients.GetClientByChainID(chainID)
 		if err != nil {
 			fmt.Printf("Warning: Failed to get client for chain %d: %v\n", chainID, err)
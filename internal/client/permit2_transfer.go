@@ -0,0 +1,129 @@
+package client
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// maxNonceWords bounds how many 256-bit words of a player's Permit2 nonce bitmap are searched for a free nonce
+const maxNonceWords = 16
+
+// PermitTransferData is a one-shot Permit2 signature transfer of an exact amount to the spender
+type PermitTransferData struct {
+	Owner     common.Address `json:"owner"`
+	Spender   common.Address `json:"spender"`
+	Token     common.Address `json:"token"`
+	Amount    *big.Int       `json:"amount"`
+	Nonce     *big.Int       `json:"nonce"`
+	Deadline  *big.Int       `json:"deadline"`
+	ChainID   uint64         `json:"chainId"`
+	Signature string         `json:"signature"`
+}
+
+// ReserveNonce returns an unordered Permit2 nonce that is neither used on-chain nor handed out for another
+// signature transfer of the owner. The reservation lasts until the signature deadline.
+func (p *Permit2Client) ReserveNonce(owner common.Address, deadline int64) (*big.Int, error) {
+	p.nonceMutex.Lock()
+	defer p.nonceMutex.Unlock()
+
+	reserved := p.reservedNonces[owner]
+	if reserved == nil {
+		reserved = make(map[uint64]int64)
+		p.reservedNonces[owner] = reserved
+	}
+
+	// A signature past its deadline can never be used, so its nonce is free again
+	now := time.Now().Unix()
+	for nonce, until := range reserved {
+		if until <= now {
+			delete(reserved, nonce)
+		}
+	}
+
+	// Permit2 stores used nonces as bits, nonce = word << 8 | bit
+	for word := uint64(0); word < maxNonceWords; word++ {
+		bitmap, err := p.contract.NonceBitmap(&bind.CallOpts{}, owner, new(big.Int).SetUint64(word))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read nonce bitmap: %w", err)
+		}
+
+		for bit := range 256 {
+			nonce := word<<8 | uint64(bit)
+			if bitmap.Bit(bit) == 1 {
+				delete(reserved, nonce)
+				continue
+			}
+			if _, taken := reserved[nonce]; taken {
+				continue
+			}
+			reserved[nonce] = deadline
+			return new(big.Int).SetUint64(nonce), nil
+		}
+	}
+
+	return nil, fmt.Errorf("no free Permit2 nonce for %s in the first %d bitmap words", owner.Hex(), maxNonceWords)
+}
+
+// ReleaseNonce frees a reserved nonce whose signature transfer will not be submitted
+func (p *Permit2Client) ReleaseNonce(owner common.Address, nonce *big.Int) {
+	if nonce == nil || !nonce.IsUint64() {
+		return
+	}
+
+	p.nonceMutex.Lock()
+	defer p.nonceMutex.Unlock()
+	delete(p.reservedNonces[owner], nonce.Uint64())
+}
+
+// CreatePermitTransferData reserves a nonce and creates the signature transfer data and typed data for frontend
+func (p *Permit2Client) CreatePermitTransferData(
+	owner, spender, token common.Address,
+	amount *big.Int,
+	deadline time.Time,
+) (*PermitTransferData, *apitypes.TypedData, error) {
+	nonce, err := p.ReserveNonce(owner, deadline.Unix())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to reserve nonce: %w", err)
+	}
+
+	deadlineBig := big.NewInt(deadline.Unix())
+	typedData, err := p.CreatePermitTransferTypedData(spender, token, amount, nonce, deadlineBig)
+	if err != nil {
+		p.ReleaseNonce(owner, nonce)
+		return nil, nil, fmt.Errorf("failed to create typed data: %w", err)
+	}
+
+	transfer := &PermitTransferData{
+		Owner:    owner,
+		Spender:  spender,
+		Token:    token,
+		Amount:   amount,
+		Nonce:    nonce,
+		Deadline: deadlineBig,
+		ChainID:  p.chainID,
+	}
+
+	return transfer, typedData, nil
+}
+
+// CreateVoteStakePermit creates a signature transfer of exactly one vote's stake into the vault
+func (p *Permit2Client) CreateVoteStakePermit(
+	owner common.Address,
+	vaultAddress common.Address,
+	amount *big.Int,
+) (*PermitTransferData, *apitypes.TypedData, error) {
+	usdcAddress, err := p.GetUSDCAddress()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get USDC address: %w", err)
+	}
+
+	// The signature only has to outlive the vote's round and the stake queue, VOTE_PERMIT_DEADLINE_SECONDS (default 10 minutes)
+	deadline := time.Now().Add(time.Duration(GetEnvInt("VOTE_PERMIT_DEADLINE_SECONDS", 600)) * time.Second)
+
+	return p.CreatePermitTransferData(owner, vaultAddress, usdcAddress, amount, deadline)
+}
@@ -0,0 +1,191 @@
+package client
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"blockchess/contracts-bindings/permit2"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// callStub is a node that answers eth_call with the output of handle for the call's input
+func callStub(t *testing.T, handle func(input []byte) []byte) *ethclient.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []json.RawMessage
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		response := map[string]any{"jsonrpc": "2.0", "id": request.ID}
+		var call struct {
+			Input hexutil.Bytes `json:"input"`
+		}
+		if request.Method != "eth_call" || len(request.Params) == 0 || json.Unmarshal(request.Params[0], &call) != nil {
+			response["error"] = map[string]any{"code": -32601, "message": "method not found"}
+		} else {
+			response["result"] = hexutil.Bytes(handle(call.Input))
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := ethclient.Dial(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+// bitmapStub creates a Permit2 client whose on-chain nonce bitmap has the given nonces used
+func bitmapStub(t *testing.T, used ...uint64) *Permit2Client {
+	t.Helper()
+	address := common.HexToAddress("0x000000000022D473030F116dDEE9F6B43aC78BA3")
+	client := callStub(t, func(input []byte) []byte {
+		// nonceBitmap(address owner, uint256 word)
+		word := new(big.Int).SetBytes(input[4+32 : 4+64]).Uint64()
+		bitmap := new(big.Int)
+		for _, nonce := range used {
+			if nonce>>8 == word {
+				bitmap.SetBit(bitmap, int(nonce&0xff), 1)
+			}
+		}
+		return common.LeftPadBytes(bitmap.Bytes(), 32)
+	})
+
+	contract, err := permit2.NewPermit2(address, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Permit2Client{
+		contract:       contract,
+		client:         client,
+		address:        address,
+		chainID:        84532,
+		reservedNonces: make(map[common.Address]map[uint64]int64),
+	}
+}
+
+func TestReserveNonceSkipsUsedAndReservedNonces(t *testing.T) {
+	p := bitmapStub(t, 0, 1, 3)
+	owner := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+	deadline := time.Now().Add(time.Minute).Unix()
+
+	reserve := func(deadline int64) uint64 {
+		t.Helper()
+		nonce, err := p.ReserveNonce(owner, deadline)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return nonce.Uint64()
+	}
+
+	if nonce := reserve(deadline); nonce != 2 {
+		t.Fatalf("first nonce = %d, want 2", nonce)
+	}
+	if nonce := reserve(deadline); nonce != 4 {
+		t.Fatalf("second nonce = %d, want 4 while 2 is reserved", nonce)
+	}
+
+	p.ReleaseNonce(owner, big.NewInt(2))
+	if nonce := reserve(time.Now().Add(-time.Second).Unix()); nonce != 2 {
+		t.Fatalf("nonce after release = %d, want 2", nonce)
+	}
+
+	// A reservation past its signature deadline is handed out again
+	if nonce := reserve(deadline); nonce != 2 {
+		t.Fatalf("nonce after expiry = %d, want 2", nonce)
+	}
+}
+
+func TestVerifyPermitTransferSignature(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	vault := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	token := common.HexToAddress("0x036CbD53842c5426634e7929541eC2318f3dCF7e")
+
+	tests := []struct {
+		name     string
+		tamper   func(transfer *PermitTransferData)
+		verifier *Permit2Client
+		wantCode string
+	}{
+		{name: "valid"},
+		{
+			name:     "wrong chain",
+			verifier: &Permit2Client{address: common.HexToAddress("0x000000000022D473030F116dDEE9F6B43aC78BA3"), chainID: 11155111},
+			wantCode: PermitErrorWrongSigner,
+		},
+		{
+			name: "wrong spender",
+			tamper: func(transfer *PermitTransferData) {
+				transfer.Spender = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+			},
+			wantCode: PermitErrorWrongSigner,
+		},
+		{
+			name:     "wrong amount",
+			tamper:   func(transfer *PermitTransferData) { transfer.Amount = big.NewInt(2_000_000) },
+			wantCode: PermitErrorWrongSigner,
+		},
+		{
+			name: "expired deadline",
+			tamper: func(transfer *PermitTransferData) {
+				transfer.Deadline = big.NewInt(time.Now().Add(-time.Minute).Unix())
+			},
+			wantCode: PermitErrorExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := bitmapStub(t)
+			transfer, typedData, err := p.CreatePermitTransferData(owner, vault, token, big.NewInt(1_000_000), time.Now().Add(10*time.Minute))
+			if err != nil {
+				t.Fatal(err)
+			}
+			signature := signTypedData(t, key, typedData)
+			if tt.tamper != nil {
+				tt.tamper(transfer)
+			}
+
+			verifier := p
+			if tt.verifier != nil {
+				verifier = tt.verifier
+			}
+			if code := permitCode(verifier.VerifyPermitTransferSignature(transfer, signature)); code != tt.wantCode {
+				t.Fatalf("VerifyPermitTransferSignature() = %q, want %q", code, tt.wantCode)
+			}
+		})
+	}
+
+	t.Run("replayed nonce", func(t *testing.T) {
+		// A signature over an earlier transfer does not pay for the next one, which gets a fresh nonce
+		p := bitmapStub(t)
+		_, first, err := p.CreatePermitTransferData(owner, vault, token, big.NewInt(1_000_000), time.Now().Add(10*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, _, err := p.CreatePermitTransferData(owner, vault, token, big.NewInt(1_000_000), time.Now().Add(10*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code := permitCode(p.VerifyPermitTransferSignature(second, signTypedData(t, key, first))); code != PermitErrorWrongSigner {
+			t.Fatalf("VerifyPermitTransferSignature() = %q, want %q", code, PermitErrorWrongSigner)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Permit error codes sent to clients
+const (
+	PermitErrorNotFound      = "permit_not_found"           // No permit was requested for the wallet
+	PermitErrorChainMismatch = "permit_chain_mismatch"      // The signature was sent for another chain than the permit
+	PermitErrorMalformed     = "permit_signature_malformed" // Not a 64 or 65 byte hex ECDSA signature
+	PermitErrorExpired       = "permit_expired"             // The permit's signature deadline passed
+	PermitErrorWrongSigner   = "permit_wrong_signer"        // The signature does not recover to the permit owner
+	PermitErrorInvalid       = "permit_invalid"             // The permit could not be rebuilt for verification
+)
+
+// PermitError is a rejected permit signature with a code clients can act on
+type PermitError struct {
+	Code    string
+	Message string
+}
+
+// Error returns the message of the rejection
+func (e *PermitError) Error() string {
+	return e.Message
+}
+
+// permitError creates a PermitError
+func permitError(code, format string, args ...any) *PermitError {
+	return &PermitError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// VerifyPermitSignature rebuilds the EIP-712 typed data of a permit and checks that the signature was made by its owner
+func (p *Permit2Client) VerifyPermitSignature(permitData *PermitSignatureData, signature string) error {
+	if permitData.Kind == PermitKindEIP2612 {
+		if p.usdc == nil {
+			return permitError(PermitErrorInvalid, "USDC permits unavailable on chain %d", p.chainID)
+		}
+		return p.usdc.VerifyPermitSignature(permitData, signature)
+	}
+
+	typedData, err := p.CreatePermitTypedData(
+		permitData.Owner, permitData.Spender, permitData.Token,
+		permitData.Amount, permitData.Expiration, permitData.Nonce, permitData.SigDeadline,
+	)
+	if err != nil {
+		return permitError(PermitErrorInvalid, "failed to rebuild permit: %v", err)
+	}
+	return verifyTypedDataSignature(typedData, permitData.Owner, permitData.SigDeadline, signature)
+}
+
+// VerifyPermitTransferSignature rebuilds the EIP-712 typed data of a signature transfer and checks that the
+// signature was made by its owner
+func (p *Permit2Client) VerifyPermitTransferSignature(transfer *PermitTransferData, signature string) error {
+	typedData, err := p.CreatePermitTransferTypedData(
+		transfer.Spender, transfer.Token, transfer.Amount, transfer.Nonce, transfer.Deadline,
+	)
+	if err != nil {
+		return permitError(PermitErrorInvalid, "failed to rebuild permit: %v", err)
+	}
+	return verifyTypedDataSignature(typedData, transfer.Owner, transfer.Deadline, signature)
+}
+
+// verifyTypedDataSignature checks that a signature over typed data is unexpired and recovers to the owner
+func verifyTypedDataSignature(typedData *apitypes.TypedData, owner common.Address, deadline *big.Int, signature string) error {
+	if deadline != nil && deadline.Cmp(big.NewInt(time.Now().Unix())) <= 0 {
+		return permitError(PermitErrorExpired, "permit signature deadline has passed, please request a new permit")
+	}
+
+	sig, err := decodePermitSignature(signature)
+	if err != nil {
+		return permitError(PermitErrorMalformed, "invalid permit signature: %v", err)
+	}
+
+	hash, _, err := apitypes.TypedDataAndHash(*typedData)
+	if err != nil {
+		return permitError(PermitErrorInvalid, "failed to hash permit: %v", err)
+	}
+
+	pubKey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return permitError(PermitErrorMalformed, "failed to recover permit signer: %v", err)
+	}
+
+	if signer := crypto.PubkeyToAddress(*pubKey); signer != owner {
+		return permitError(PermitErrorWrongSigner, "permit was signed by %s, not by %s", signer.Hex(), owner.Hex())
+	}
+	return nil
+}
+
+// decodePermitSignature parses a 65 byte [R || S || V] or 64 byte EIP-2098 signature into the [R || S || V] form
+// with V of 0 or 1
+func decodePermitSignature(signature string) ([]byte, error) {
+	if !strings.HasPrefix(signature, "0x") {
+		signature = "0x" + signature
+	}
+	raw, err := hexutil.Decode(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	sig := make([]byte, crypto.SignatureLength)
+	switch len(raw) {
+	case crypto.SignatureLength:
+		copy(sig, raw)
+		if sig[64] >= 27 {
+			sig[64] -= 27
+		}
+	case crypto.SignatureLength - 1:
+		// EIP-2098 packs V into the top bit of S
+		copy(sig, raw)
+		sig[64] = sig[32] >> 7
+		sig[32] &= 0x7f
+	default:
+		return nil, fmt.Errorf("expected 64 or 65 bytes, got %d", len(raw))
+	}
+
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:64])
+	if !crypto.ValidateSignatureValues(sig[64], r, s, false) {
+		return nil, fmt.Errorf("signature values out of range")
+	}
+	return sig, nil
+}
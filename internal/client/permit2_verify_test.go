@@ -0,0 +1,154 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// signTypedData signs typed data the way a wallet's eth_signTypedData_v4 does, with V of 27 or 28
+func signTypedData(t *testing.T, key *ecdsa.PrivateKey, typedData *apitypes.TypedData) string {
+	t.Helper()
+	hash, _, err := apitypes.TypedDataAndHash(*typedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := crypto.Sign(hash, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig[64] += 27
+	return hexutil.Encode(sig)
+}
+
+// permitCode returns the code of a PermitError, or the error text for anything else
+func permitCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	var permitErr *PermitError
+	if errors.As(err, &permitErr) {
+		return permitErr.Code
+	}
+	return err.Error()
+}
+
+func TestVerifyPermitSignature(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	otherKey, _ := crypto.GenerateKey()
+	permit2Address := common.HexToAddress("0x000000000022D473030F116dDEE9F6B43aC78BA3")
+	issuer := &Permit2Client{address: permit2Address, chainID: 84532}
+
+	// issue returns the permit the server hands out and the typed data the wallet signs
+	issue := func() (*PermitSignatureData, *apitypes.TypedData) {
+		permitData := &PermitSignatureData{
+			Kind:        PermitKindPermit2,
+			Owner:       crypto.PubkeyToAddress(key.PublicKey),
+			Spender:     common.HexToAddress("0x00000000000000000000000000000000000000aa"),
+			Token:       common.HexToAddress("0x036CbD53842c5426634e7929541eC2318f3dCF7e"),
+			Amount:      big.NewInt(10_000_000),
+			Expiration:  big.NewInt(time.Now().Add(time.Hour).Unix()),
+			Nonce:       big.NewInt(3),
+			SigDeadline: big.NewInt(time.Now().Add(30 * time.Minute).Unix()),
+			ChainID:     84532,
+		}
+		typedData, err := issuer.CreatePermitTypedData(permitData.Owner, permitData.Spender, permitData.Token,
+			permitData.Amount, permitData.Expiration, permitData.Nonce, permitData.SigDeadline)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return permitData, typedData
+	}
+
+	tests := []struct {
+		name     string
+		sign     func(permitData *PermitSignatureData, typedData *apitypes.TypedData) string
+		verifier *Permit2Client
+		wantCode string
+	}{
+		{
+			name: "valid",
+			sign: func(_ *PermitSignatureData, typedData *apitypes.TypedData) string {
+				return signTypedData(t, key, typedData)
+			},
+		},
+		{
+			name: "compact signature",
+			sign: func(_ *PermitSignatureData, typedData *apitypes.TypedData) string {
+				sig := hexutil.MustDecode(signTypedData(t, key, typedData))
+				sig[32] |= (sig[64] - 27) << 7
+				return hexutil.Encode(sig[:64])
+			},
+		},
+		{
+			name: "wrong chain",
+			sign: func(_ *PermitSignatureData, typedData *apitypes.TypedData) string {
+				return signTypedData(t, key, typedData)
+			},
+			verifier: &Permit2Client{address: permit2Address, chainID: 11155111},
+			wantCode: PermitErrorWrongSigner,
+		},
+		{
+			name: "wrong spender",
+			sign: func(permitData *PermitSignatureData, typedData *apitypes.TypedData) string {
+				permitData.Spender = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+				return signTypedData(t, key, typedData)
+			},
+			wantCode: PermitErrorWrongSigner,
+		},
+		{
+			name: "replayed nonce",
+			sign: func(permitData *PermitSignatureData, typedData *apitypes.TypedData) string {
+				// The signature of the previous permit does not cover the nonce the server issued now
+				permitData.Nonce = big.NewInt(4)
+				return signTypedData(t, key, typedData)
+			},
+			wantCode: PermitErrorWrongSigner,
+		},
+		{
+			name: "expired deadline",
+			sign: func(permitData *PermitSignatureData, typedData *apitypes.TypedData) string {
+				permitData.SigDeadline = big.NewInt(time.Now().Add(-time.Minute).Unix())
+				typedData.Message["sigDeadline"] = permitData.SigDeadline.String()
+				return signTypedData(t, key, typedData)
+			},
+			wantCode: PermitErrorExpired,
+		},
+		{
+			name: "other signer",
+			sign: func(_ *PermitSignatureData, typedData *apitypes.TypedData) string {
+				return signTypedData(t, otherKey, typedData)
+			},
+			wantCode: PermitErrorWrongSigner,
+		},
+		{
+			name: "malformed",
+			sign: func(_ *PermitSignatureData, _ *apitypes.TypedData) string {
+				return "0x1234"
+			},
+			wantCode: PermitErrorMalformed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			permitData, typedData := issue()
+			signature := tt.sign(permitData, typedData)
+
+			verifier := issuer
+			if tt.verifier != nil {
+				verifier = tt.verifier
+			}
+			if code := permitCode(verifier.VerifyPermitSignature(permitData, signature)); code != tt.wantCode {
+				t.Fatalf("VerifyPermitSignature() = %q, want %q", code, tt.wantCode)
+			}
+		})
+	}
+}
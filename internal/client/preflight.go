@@ -0,0 +1,300 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"blockchess/contracts-bindings/vaultcontract"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Preflight check results
+const (
+	CheckPassed  = "ok"
+	CheckWarning = "warn" // Reported, but the chain stays enabled
+	CheckFailed  = "fail" // The chain is disabled
+)
+
+// PreflightConfig controls the startup checks of every chain
+type PreflightConfig struct {
+	Enabled bool
+	Timeout time.Duration // Budget for all checks of one chain
+}
+
+// LoadPreflightConfig loads the preflight settings from environment variables
+func LoadPreflightConfig() PreflightConfig {
+	return PreflightConfig{
+		Enabled: GetEnv("PREFLIGHT_ENABLED", "true") == "true",
+		Timeout: time.Duration(GetEnvInt("PREFLIGHT_TIMEOUT_SECONDS", 30)) * time.Second,
+	}
+}
+
+// minGasBalance returns the smallest signer balance a chain accepts, PREFLIGHT_MIN_GAS_WEI_<chainID> overriding
+// PREFLIGHT_MIN_GAS_WEI (default 0.001 ETH)
+func minGasBalance(chainID uint64) *big.Int {
+	value := chainEnv("PREFLIGHT_MIN_GAS_WEI", chainID, "")
+	if value == "" {
+		return big.NewInt(params.Ether / 1000)
+	}
+	threshold, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		log.Printf("Warning: Invalid PREFLIGHT_MIN_GAS_WEI %q for chain %d, using 0.001 ETH", value, chainID)
+		return big.NewInt(params.Ether / 1000)
+	}
+	return threshold
+}
+
+// PreflightCheck is the outcome of one startup check
+type PreflightCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// PreflightReport collects the startup checks of one chain
+type PreflightReport struct {
+	ChainID  uint64           `json:"chainId"`
+	Name     string           `json:"name"`
+	Disabled bool             `json:"disabled"`
+	Checks   []PreflightCheck `json:"checks"`
+}
+
+// add records a check result
+func (r *PreflightReport) add(name, status, format string, args ...any) {
+	r.Checks = append(r.Checks, PreflightCheck{Name: name, Status: status, Detail: fmt.Sprintf(format, args...)})
+	if status == CheckFailed {
+		r.Disabled = true
+	}
+}
+
+// failures returns the details of the failed checks
+func (r *PreflightReport) failures() string {
+	var failed []string
+	for _, check := range r.Checks {
+		if check.Status == CheckFailed {
+			failed = append(failed, check.Name+": "+check.Detail)
+		}
+	}
+	return strings.Join(failed, "; ")
+}
+
+// String renders the report as one line per check
+func (r *PreflightReport) String() string {
+	var b strings.Builder
+	result := "passed"
+	if r.Disabled {
+		result = "FAILED, chain disabled"
+	}
+	fmt.Fprintf(&b, "Preflight %s (%d): %s", r.Name, r.ChainID, result)
+	for _, check := range r.Checks {
+		fmt.Fprintf(&b, "\n  %-4s %-14s %s", strings.ToUpper(check.Status), check.Name, check.Detail)
+	}
+	return b.String()
+}
+
+// Preflight checks the deployed contracts and the signer of every chain and disables the chains that fail
+func (c *Clients) Preflight(config PreflightConfig) []PreflightReport {
+	if !config.Enabled {
+		log.Printf("Warning: Preflight checks are disabled")
+		return nil
+	}
+
+	chainIDs := GetSupportedChains()
+	reports := make([]PreflightReport, len(chainIDs))
+
+	var wg sync.WaitGroup
+	for i, chainID := range chainIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reports[i] = c.preflightChain(chainID, config)
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(reports, func(i, j int) bool { return reports[i].ChainID < reports[j].ChainID })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	disabled := 0
+	for _, report := range reports {
+		log.Print(report.String())
+		if report.Disabled {
+			c.disabled[report.ChainID] = report.failures()
+			disabled++
+		}
+	}
+	c.preflight = reports
+
+	log.Printf("Preflight finished: %d of %d chains enabled", len(reports)-disabled, len(reports))
+	return reports
+}
+
+// preflightChain runs every check of one chain
+func (c *Clients) preflightChain(chainID uint64, config PreflightConfig) PreflightReport {
+	chain := supportedChains[chainID]
+	report := PreflightReport{ChainID: chainID, Name: chain.Name}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+
+	client, err := c.GetClientByChainID(chainID)
+	if err != nil {
+		report.add("rpc", CheckFailed, "%v", err)
+		return report
+	}
+
+	signer, err := c.GetSigner(chainID)
+	if err != nil {
+		report.add("signer", CheckFailed, "%v", err)
+		return report
+	}
+	report.add("signer", CheckPassed, "%s signer %s", signer.Kind(), signer.Address().Hex())
+
+	threshold := minGasBalance(chainID)
+	balance, err := client.BalanceAt(ctx, signer.Address(), nil)
+	switch {
+	case err != nil:
+		report.add("gas_balance", CheckFailed, "failed to read balance: %v", err)
+	case balance.Cmp(threshold) < 0:
+		report.add("gas_balance", CheckFailed, "%s wei is below the %s wei threshold", balance, threshold)
+	default:
+		report.add("gas_balance", CheckPassed, "%s wei", balance)
+	}
+
+	if address := GetPermit2Address(chainID); address != "" {
+		checkCode(ctx, client, &report, "permit2_code", address)
+	} else {
+		report.add("permit2_code", CheckFailed, "no Permit2 address configured")
+	}
+
+	if chainID == GameFactoryChainID {
+		if address := GetGameFactoryAddress(); address != "" {
+			checkCode(ctx, client, &report, "factory_code", address)
+		} else {
+			report.add("factory_code", CheckFailed, "GAME_FACTORY_ADDRESS not set")
+		}
+	}
+
+	vaultAddress := GetVaultAddress(chainID)
+	if vaultAddress == "" {
+		report.add("vault_code", CheckFailed, "no vault address configured")
+		return report
+	}
+	if !checkCode(ctx, client, &report, "vault_code", vaultAddress) {
+		return report
+	}
+	checkVault(ctx, client, &report, common.HexToAddress(vaultAddress), signer.Address())
+
+	return report
+}
+
+// checkCode verifies that a contract is deployed at an address
+func checkCode(ctx context.Context, client *ethclient.Client, report *PreflightReport, name, address string) bool {
+	code, err := client.CodeAt(ctx, common.HexToAddress(address), nil)
+	switch {
+	case err != nil:
+		report.add(name, CheckFailed, "failed to read code at %s: %v", address, err)
+		return false
+	case len(code) == 0:
+		report.add(name, CheckFailed, "no contract deployed at %s", address)
+		return false
+	default:
+		report.add(name, CheckPassed, "%s has %d bytes of code", address, len(code))
+		return true
+	}
+}
+
+// checkVault verifies the vault's authorized backend, USDC token, Permit2 and CCTP routes against our configuration
+func checkVault(ctx context.Context, client *ethclient.Client, report *PreflightReport, address, signer common.Address) {
+	vault, err := vaultcontract.NewVaultcontract(address, client)
+	if err != nil {
+		report.add("vault_backend", CheckFailed, "failed to bind vault: %v", err)
+		return
+	}
+	opts := &bind.CallOpts{Context: ctx}
+
+	backend, err := vault.GetAuthorizedBackend(opts)
+	switch {
+	case err != nil:
+		report.add("vault_backend", CheckFailed, "failed to read authorized backend: %v", err)
+	case backend != signer:
+		report.add("vault_backend", CheckFailed, "vault authorizes %s but we sign with %s", backend.Hex(), signer.Hex())
+	default:
+		report.add("vault_backend", CheckPassed, "%s", backend.Hex())
+	}
+
+	expectedUSDC := GetUSDCAddress(report.ChainID)
+	usdc, err := vault.GetUsdcContractAddress(opts)
+	switch {
+	case err != nil:
+		report.add("vault_usdc", CheckFailed, "failed to read USDC address: %v", err)
+	case expectedUSDC == "":
+		report.add("vault_usdc", CheckWarning, "vault uses %s, registry has no USDC address", usdc.Hex())
+	case usdc != common.HexToAddress(expectedUSDC):
+		report.add("vault_usdc", CheckFailed, "vault uses %s but the registry has %s", usdc.Hex(), expectedUSDC)
+	default:
+		report.add("vault_usdc", CheckPassed, "%s", usdc.Hex())
+	}
+
+	// Only one-shot signature stakes go through the vault's Permit2, so a mismatch does not disable the chain
+	expectedPermit2 := GetPermit2Address(report.ChainID)
+	vaultPermit2, err := vault.GetPermit2(opts)
+	switch {
+	case err != nil:
+		report.add("vault_permit2", CheckWarning, "failed to read Permit2 address: %v", err)
+	case vaultPermit2 != common.HexToAddress(expectedPermit2):
+		report.add("vault_permit2", CheckWarning, "vault uses %s but the registry has %s", vaultPermit2.Hex(), expectedPermit2)
+	default:
+		report.add("vault_permit2", CheckPassed, "%s", vaultPermit2.Hex())
+	}
+
+	// The vault's own route must be supported, missing routes to other chains only limit payouts there
+	var unsupported, mismatched []string
+	for _, chainID := range GetSupportedChains() {
+		route, err := vault.GetChainConfig(opts, new(big.Int).SetUint64(chainID))
+		if err != nil {
+			report.add("vault_cctp", CheckFailed, "failed to read CCTP config of chain %d: %v", chainID, err)
+			return
+		}
+		domain, _ := GetCCTPDomain(chainID)
+		switch {
+		case !route.IsSupported:
+			unsupported = append(unsupported, fmt.Sprint(chainID))
+		case route.DomainId != domain:
+			mismatched = append(mismatched, fmt.Sprintf("%d (vault %d, registry %d)", chainID, route.DomainId, domain))
+		}
+	}
+
+	status := CheckPassed
+	if len(unsupported) > 0 || len(mismatched) > 0 {
+		status = CheckWarning
+	}
+	if len(mismatched) > 0 || slices.Contains(unsupported, fmt.Sprint(report.ChainID)) {
+		status = CheckFailed
+	}
+
+	var details []string
+	if len(unsupported) > 0 {
+		details = append(details, "unsupported chains "+strings.Join(unsupported, ", "))
+	}
+	if len(mismatched) > 0 {
+		details = append(details, "domain mismatch on "+strings.Join(mismatched, ", "))
+	}
+	if len(details) == 0 {
+		details = append(details, fmt.Sprintf("routes to all %d chains supported", len(GetSupportedChains())))
+	}
+	report.add("vault_cctp", status, "%s", strings.Join(details, "; "))
+}
@@ -0,0 +1,358 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// RPCPoolConfig controls health probing and circuit breaking of a chain's RPC endpoints
+type RPCPoolConfig struct {
+	ProbeInterval    time.Duration // How often every endpoint is probed
+	ProbeTimeout     time.Duration // How long a probe may take
+	FailureThreshold int           // Consecutive failures that open an endpoint's circuit
+	OpenDuration     time.Duration // How long an open circuit keeps an endpoint out of rotation
+	MaxBlockLag      uint64        // Blocks an endpoint may trail the best one before it counts as unhealthy
+}
+
+// LoadRPCPoolConfig loads the RPC pool settings from environment variables
+func LoadRPCPoolConfig() RPCPoolConfig {
+	return RPCPoolConfig{
+		ProbeInterval:    time.Duration(GetEnvInt("RPC_PROBE_SECONDS", 15)) * time.Second,
+		ProbeTimeout:     time.Duration(GetEnvInt("RPC_PROBE_TIMEOUT_SECONDS", 10)) * time.Second,
+		FailureThreshold: max(GetEnvInt("RPC_FAILURE_THRESHOLD", 3), 1),
+		OpenDuration:     time.Duration(GetEnvInt("RPC_CIRCUIT_OPEN_SECONDS", 60)) * time.Second,
+		MaxBlockLag:      uint64(GetEnvInt("RPC_MAX_BLOCK_LAG", 10)),
+	}
+}
+
+// rpcEndpoint is one RPC URL of a chain and its observed health
+type rpcEndpoint struct {
+	url       *url.URL
+	latency   time.Duration // Moving average of successful request times
+	failures  int           // Consecutive failures
+	openUntil time.Time     // Circuit stays open until then
+	lagging   bool          // Trails the best endpoint by more than MaxBlockLag blocks
+	block     uint64
+	lastError string
+	checkedAt time.Time
+}
+
+// EndpointStatus is the health of one RPC endpoint as reported by the status endpoint
+type EndpointStatus struct {
+	Host        string `json:"host"` // Host only, so API keys in paths are not exposed
+	Healthy     bool   `json:"healthy"`
+	CircuitOpen bool   `json:"circuitOpen"`
+	LatencyMs   int64  `json:"latencyMs"`
+	Block       uint64 `json:"block"`
+	Failures    int    `json:"failures"`
+	LastError   string `json:"lastError,omitempty"`
+	CheckedAt   int64  `json:"checkedAt,omitempty"`
+}
+
+// ChainStatus is the health of a chain's RPC pool
+type ChainStatus struct {
+	ChainID   uint64           `json:"chainId"`
+	Name      string           `json:"name"`
+	Healthy   bool             `json:"healthy"`
+	Active    string           `json:"active,omitempty"` // Host requests are currently sent to
+	Endpoints []EndpointStatus `json:"endpoints"`
+}
+
+// RPCPool spreads a chain's RPC traffic over several endpoints, preferring the fastest healthy one
+// and failing over to the next when a request fails
+type RPCPool struct {
+	chainID   uint64
+	name      string
+	config    RPCPoolConfig
+	endpoints []*rpcEndpoint
+	client    *ethclient.Client
+	mu        sync.Mutex
+}
+
+// NewRPCPool creates the RPC pool of a chain and a client whose requests go through it
+func NewRPCPool(chainID uint64, name string, rpcURLs []string, config RPCPoolConfig) (*RPCPool, error) {
+	pool := &RPCPool{chainID: chainID, name: name, config: config}
+
+	for _, rawURL := range rpcURLs {
+		parsed, err := url.Parse(rawURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			log.Printf("Warning: Skipping RPC endpoint of %s, only http(s) URLs can be pooled", name)
+			continue
+		}
+		pool.endpoints = append(pool.endpoints, &rpcEndpoint{url: parsed})
+	}
+	if len(pool.endpoints) == 0 {
+		return nil, fmt.Errorf("no usable RPC endpoint configured for %s", name)
+	}
+
+	// The dialled URL is only a placeholder, the transport picks the endpoint of every request
+	httpClient := &http.Client{Transport: &rpcPoolTransport{pool: pool, base: http.DefaultTransport}}
+	rpcClient, err := rpc.DialOptions(context.Background(), pool.endpoints[0].url.String(), rpc.WithHTTPClient(httpClient))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create RPC client for %s: %w", name, err)
+	}
+	pool.client = ethclient.NewClient(rpcClient)
+
+	return pool, nil
+}
+
+// Client returns the chain's client, which always talks to the best available endpoint
+func (p *RPCPool) Client() *ethclient.Client {
+	return p.client
+}
+
+// Healthy reports whether at least one endpoint can take requests
+func (p *RPCPool) Healthy() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pickUnsafe(nil) != nil
+}
+
+// Run probes every endpoint until the server stops
+func (p *RPCPool) Run() {
+	p.probeAll()
+
+	ticker := time.NewTicker(p.config.ProbeInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		p.probeAll()
+	}
+}
+
+// probeAll checks the chain ID and head block of every endpoint and marks endpoints that trail the best one
+func (p *RPCPool) probeAll() {
+	var wg sync.WaitGroup
+	for _, endpoint := range p.endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.probe(endpoint)
+		}()
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	best := uint64(0)
+	for _, endpoint := range p.endpoints {
+		best = max(best, endpoint.block)
+	}
+	for _, endpoint := range p.endpoints {
+		lagging := endpoint.block+p.config.MaxBlockLag < best
+		if lagging && !endpoint.lagging {
+			log.Printf("Warning: RPC endpoint %s of %s is %d blocks behind", endpoint.url.Host, p.name, best-endpoint.block)
+		}
+		endpoint.lagging = lagging
+	}
+}
+
+// probe measures one endpoint directly, bypassing the pool
+func (p *RPCPool) probe(endpoint *rpcEndpoint) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.config.ProbeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	block, err := probeEndpoint(ctx, endpoint.url.String(), p.chainID)
+	elapsed := time.Since(start)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	endpoint.checkedAt = time.Now()
+	if err != nil {
+		p.recordFailureUnsafe(endpoint, err)
+		return
+	}
+	endpoint.block = block
+	p.recordSuccessUnsafe(endpoint, elapsed)
+}
+
+// probeEndpoint reads the chain ID and head block of a single endpoint
+func probeEndpoint(ctx context.Context, rawURL string, chainID uint64) (uint64, error) {
+	client, err := ethclient.DialContext(ctx, rawURL)
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+
+	remoteChainID, err := client.ChainID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if remoteChainID.Cmp(new(big.Int).SetUint64(chainID)) != 0 {
+		return 0, fmt.Errorf("endpoint serves chain %s, expected %d", remoteChainID, chainID)
+	}
+
+	return client.BlockNumber(ctx)
+}
+
+// pickUnsafe returns the fastest endpoint that is not excluded, lagging or open (caller must hold the lock)
+func (p *RPCPool) pickUnsafe(exclude map[*rpcEndpoint]bool) *rpcEndpoint {
+	now := time.Now()
+	candidates := make([]*rpcEndpoint, 0, len(p.endpoints))
+	for _, endpoint := range p.endpoints {
+		if exclude[endpoint] || endpoint.lagging || now.Before(endpoint.openUntil) {
+			continue
+		}
+		candidates = append(candidates, endpoint)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	// Unmeasured endpoints keep their configured order behind measured ones
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i].latency, candidates[j].latency
+		if a == 0 || b == 0 {
+			return a != 0 && b == 0
+		}
+		return a < b
+	})
+	return candidates[0]
+}
+
+// pick selects the endpoint for the next request attempt
+func (p *RPCPool) pick(exclude map[*rpcEndpoint]bool) *rpcEndpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pickUnsafe(exclude)
+}
+
+// recordSuccess updates an endpoint after a successful request
+func (p *RPCPool) recordSuccess(endpoint *rpcEndpoint, elapsed time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.recordSuccessUnsafe(endpoint, elapsed)
+}
+
+// recordSuccessUnsafe closes the endpoint's circuit and folds the request time into its latency (caller must hold the lock)
+func (p *RPCPool) recordSuccessUnsafe(endpoint *rpcEndpoint, elapsed time.Duration) {
+	if endpoint.failures >= p.config.FailureThreshold {
+		log.Printf("RPC endpoint %s of %s recovered", endpoint.url.Host, p.name)
+	}
+	endpoint.failures = 0
+	endpoint.openUntil = time.Time{}
+	endpoint.lastError = ""
+	if endpoint.latency == 0 {
+		endpoint.latency = elapsed
+	} else {
+		endpoint.latency = (endpoint.latency*4 + elapsed) / 5
+	}
+}
+
+// recordFailure updates an endpoint after a failed request
+func (p *RPCPool) recordFailure(endpoint *rpcEndpoint, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.recordFailureUnsafe(endpoint, err)
+}
+
+// recordFailureUnsafe counts a failure and opens the endpoint's circuit past the threshold (caller must hold the lock)
+func (p *RPCPool) recordFailureUnsafe(endpoint *rpcEndpoint, err error) {
+	endpoint.failures++
+	endpoint.lastError = err.Error()
+	if endpoint.failures >= p.config.FailureThreshold {
+		if endpoint.openUntil.IsZero() || time.Now().After(endpoint.openUntil) {
+			log.Printf("Warning: RPC endpoint %s of %s failed %d times, taking it out of rotation for %s: %v",
+				endpoint.url.Host, p.name, endpoint.failures, p.config.OpenDuration, err)
+		}
+		endpoint.openUntil = time.Now().Add(p.config.OpenDuration)
+	}
+}
+
+// Status returns the health of the pool and each of its endpoints
+func (p *RPCPool) Status() ChainStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	status := ChainStatus{ChainID: p.chainID, Name: p.name}
+	if active := p.pickUnsafe(nil); active != nil {
+		status.Healthy = true
+		status.Active = active.url.Host
+	}
+	for _, endpoint := range p.endpoints {
+		circuitOpen := now.Before(endpoint.openUntil)
+		endpointStatus := EndpointStatus{
+			Host:        endpoint.url.Host,
+			Healthy:     !circuitOpen && !endpoint.lagging && endpoint.failures == 0,
+			CircuitOpen: circuitOpen,
+			LatencyMs:   endpoint.latency.Milliseconds(),
+			Block:       endpoint.block,
+			Failures:    endpoint.failures,
+			LastError:   endpoint.lastError,
+		}
+		if !endpoint.checkedAt.IsZero() {
+			endpointStatus.CheckedAt = endpoint.checkedAt.Unix()
+		}
+		status.Endpoints = append(status.Endpoints, endpointStatus)
+	}
+	return status
+}
+
+// rpcPoolTransport sends each JSON-RPC request to the pool's best endpoint, retrying the next one on failure
+type rpcPoolTransport struct {
+	pool *RPCPool
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *rpcPoolTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tried := make(map[*rpcEndpoint]bool)
+	var lastErr error
+
+	for {
+		endpoint := t.pool.pick(tried)
+		if endpoint == nil {
+			break
+		}
+		tried[endpoint] = true
+
+		attempt := req.Clone(req.Context())
+		endpointURL := *endpoint.url
+		attempt.URL = &endpointURL
+		attempt.Host = endpoint.url.Host
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attempt.Body = body
+		}
+
+		start := time.Now()
+		resp, err := t.base.RoundTrip(attempt)
+		if err == nil && resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusTooManyRequests {
+			t.pool.recordSuccess(endpoint, time.Since(start))
+			return resp, nil
+		}
+
+		if err == nil {
+			err = fmt.Errorf("HTTP %s", resp.Status)
+			resp.Body.Close()
+		}
+		lastErr = err
+		t.pool.recordFailure(endpoint, err)
+
+		// The caller gave up, trying another endpoint would not help
+		if req.Context().Err() != nil {
+			return nil, req.Context().Err()
+		}
+	}
+
+	if lastErr == nil {
+		return nil, fmt.Errorf("no healthy RPC endpoint for %s", t.pool.name)
+	}
+	return nil, fmt.Errorf("all RPC endpoints of %s failed: %w", t.pool.name, lastErr)
+}
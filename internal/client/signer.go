@@ -0,0 +1,274 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Signer backends
+const (
+	SignerLocal    = "local"    // Raw hex key from PRIVATE_KEY, for development
+	SignerKeystore = "keystore" // Encrypted go-ethereum keystore file
+	SignerRemote   = "remote"   // Clef or Web3Signer over JSON-RPC
+)
+
+// Remote signer protocols
+const (
+	RemoteProtocolClef       = "clef"       // account_signTransaction
+	RemoteProtocolWeb3Signer = "web3signer" // eth_signTransaction
+)
+
+// remoteSignTimeout bounds a remote signing request when the caller has no deadline
+const remoteSignTimeout = 30 * time.Second
+
+// Signer signs the backend's transactions
+type Signer interface {
+	// Address returns the account transactions are sent from
+	Address() common.Address
+	// SignTx returns a signed copy of an unsigned transaction
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+	// Kind returns the backend name for logs
+	Kind() string
+}
+
+// LoadSigner creates the signer of a chain from SIGNER_* variables, with SIGNER_*_<chainID> overriding them
+func LoadSigner(chainID uint64) (Signer, error) {
+	switch kind := chainEnv("SIGNER_TYPE", chainID, SignerLocal); kind {
+	case SignerLocal:
+		return NewLocalSigner(chainEnv("PRIVATE_KEY", chainID, ""))
+	case SignerKeystore:
+		password, err := keystorePassword(chainID)
+		if err != nil {
+			return nil, err
+		}
+		return NewKeystoreSigner(chainEnv("SIGNER_KEYSTORE_PATH", chainID, ""), password)
+	case SignerRemote:
+		return NewRemoteSigner(
+			chainEnv("SIGNER_REMOTE_URL", chainID, ""),
+			chainEnv("SIGNER_ADDRESS", chainID, ""),
+			chainEnv("SIGNER_REMOTE_PROTOCOL", chainID, RemoteProtocolClef),
+		)
+	default:
+		return nil, fmt.Errorf("unknown SIGNER_TYPE %q for chain %d", kind, chainID)
+	}
+}
+
+// chainEnv reads key_<chainID>, falling back to key and then to the default
+func chainEnv(key string, chainID uint64, defaultValue string) string {
+	return GetEnv(key+"_"+strconv.FormatUint(chainID, 10), GetEnv(key, defaultValue))
+}
+
+// keystorePassword reads the keystore password from SIGNER_KEYSTORE_PASSWORD_FILE or SIGNER_KEYSTORE_PASSWORD
+func keystorePassword(chainID uint64) (string, error) {
+	if path := chainEnv("SIGNER_KEYSTORE_PASSWORD_FILE", chainID, ""); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read keystore password file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	return chainEnv("SIGNER_KEYSTORE_PASSWORD", chainID, ""), nil
+}
+
+// SignerTransactOpts returns transaction options that sign with the given signer
+func SignerTransactOpts(ctx context.Context, signer Signer, chainID uint64) *bind.TransactOpts {
+	from := signer.Address()
+	return &bind.TransactOpts{
+		From: from,
+		Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != from {
+				return nil, bind.ErrNotAuthorized
+			}
+			return signer.SignTx(ctx, tx, new(big.Int).SetUint64(chainID))
+		},
+		Context: ctx,
+	}
+}
+
+// keySigner signs with a private key held in memory
+type keySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+	kind    string
+}
+
+// NewLocalSigner creates a signer from a raw hex private key
+func NewLocalSigner(privateKey string) (Signer, error) {
+	if privateKey == "" {
+		return nil, fmt.Errorf("private key cannot be empty")
+	}
+
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(privateKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	return &keySigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey), kind: SignerLocal}, nil
+}
+
+// NewKeystoreSigner decrypts a go-ethereum keystore file and signs with its key
+func NewKeystoreSigner(path, password string) (Signer, error) {
+	if path == "" {
+		return nil, fmt.Errorf("keystore path cannot be empty")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore %s: %w", path, err)
+	}
+
+	key, err := keystore.DecryptKey(data, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore %s: %w", path, err)
+	}
+
+	return &keySigner{key: key.PrivateKey, address: key.Address, kind: SignerKeystore}, nil
+}
+
+// Address returns the account of the key
+func (s *keySigner) Address() common.Address {
+	return s.address
+}
+
+// SignTx signs a transaction with the key
+func (s *keySigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+// Kind returns the backend name
+func (s *keySigner) Kind() string {
+	return s.kind
+}
+
+// remoteSigner asks a Clef or Web3Signer instance to sign for one of its accounts
+type remoteSigner struct {
+	client   *rpc.Client
+	address  common.Address
+	protocol string
+}
+
+// remoteTxArgs is the transaction both Clef and Web3Signer accept for signing
+type remoteTxArgs struct {
+	From                 common.Address    `json:"from"`
+	To                   *common.Address   `json:"to,omitempty"`
+	Gas                  hexutil.Uint64    `json:"gas"`
+	GasPrice             *hexutil.Big      `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big      `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big      `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *hexutil.Big      `json:"value"`
+	Nonce                hexutil.Uint64    `json:"nonce"`
+	Data                 hexutil.Bytes     `json:"data"`
+	AccessList           *types.AccessList `json:"accessList,omitempty"`
+	ChainID              *hexutil.Big      `json:"chainId,omitempty"`
+}
+
+// clefSignResult is Clef's response to account_signTransaction
+type clefSignResult struct {
+	Raw hexutil.Bytes `json:"raw"`
+}
+
+// NewRemoteSigner creates a signer backed by a Clef or Web3Signer JSON-RPC endpoint
+func NewRemoteSigner(url, address, protocol string) (Signer, error) {
+	if url == "" {
+		return nil, fmt.Errorf("remote signer URL cannot be empty")
+	}
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("invalid remote signer address %q", address)
+	}
+	switch protocol {
+	case RemoteProtocolClef, RemoteProtocolWeb3Signer:
+	default:
+		return nil, fmt.Errorf("unknown remote signer protocol %q", protocol)
+	}
+
+	client, err := rpc.DialContext(context.Background(), url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to remote signer: %w", err)
+	}
+
+	return &remoteSigner{client: client, address: common.HexToAddress(address), protocol: protocol}, nil
+}
+
+// Address returns the remote account
+func (s *remoteSigner) Address() common.Address {
+	return s.address
+}
+
+// Kind returns the backend name
+func (s *remoteSigner) Kind() string {
+	return SignerRemote + "/" + s.protocol
+}
+
+// SignTx sends a transaction to the remote signer and checks that what came back is the same transaction, signed by
+// the expected account
+func (s *remoteSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, remoteSignTimeout)
+		defer cancel()
+	}
+
+	args := remoteTxArgs{
+		From:    s.address,
+		To:      tx.To(),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   (*hexutil.Big)(tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    tx.Data(),
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	if tx.Type() == types.LegacyTxType {
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	} else {
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+		accessList := tx.AccessList()
+		args.AccessList = &accessList
+	}
+
+	var raw hexutil.Bytes
+	switch s.protocol {
+	case RemoteProtocolClef:
+		var result clefSignResult
+		if err := s.client.CallContext(ctx, &result, "account_signTransaction", args); err != nil {
+			return nil, fmt.Errorf("remote signer rejected transaction: %w", err)
+		}
+		raw = result.Raw
+	case RemoteProtocolWeb3Signer:
+		if err := s.client.CallContext(ctx, &raw, "eth_signTransaction", args); err != nil {
+			return nil, fmt.Errorf("remote signer rejected transaction: %w", err)
+		}
+	}
+
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("failed to decode remotely signed transaction: %w", err)
+	}
+
+	signer := types.LatestSignerForChainID(chainID)
+	if signer.Hash(signed) != signer.Hash(tx) {
+		return nil, fmt.Errorf("remote signer returned a different transaction")
+	}
+	sender, err := types.Sender(signer, signed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover sender of remotely signed transaction: %w", err)
+	}
+	if sender != s.address {
+		return nil, fmt.Errorf("remote signer signed with %s instead of %s", sender.Hex(), s.address.Hex())
+	}
+	return signed, nil
+}
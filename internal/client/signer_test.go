@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// signerStub answers account_signTransaction and eth_signTransaction by signing the requested transaction with key,
+// after letting tamper change it
+func signerStub(t *testing.T, key *ecdsa.PrivateKey, delay time.Duration, tamper func(tx *types.DynamicFeeTx)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []remoteTxArgs  `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || len(request.Params) != 1 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		time.Sleep(delay)
+
+		args := request.Params[0]
+		tx := &types.DynamicFeeTx{
+			ChainID:   args.ChainID.ToInt(),
+			Nonce:     uint64(args.Nonce),
+			GasTipCap: args.MaxPriorityFeePerGas.ToInt(),
+			GasFeeCap: args.MaxFeePerGas.ToInt(),
+			Gas:       uint64(args.Gas),
+			To:        args.To,
+			Value:     args.Value.ToInt(),
+			Data:      args.Data,
+		}
+		if tamper != nil {
+			tamper(tx)
+		}
+		signed, err := types.SignNewTx(key, types.LatestSignerForChainID(tx.ChainID), tx)
+		if err != nil {
+			t.Errorf("stub failed to sign: %v", err)
+			return
+		}
+		raw, _ := signed.MarshalBinary()
+
+		response := map[string]any{"jsonrpc": "2.0", "id": request.ID}
+		switch request.Method {
+		case "account_signTransaction":
+			response["result"] = clefSignResult{Raw: raw}
+		case "eth_signTransaction":
+			response["result"] = hexutil.Bytes(raw)
+		default:
+			response["error"] = map[string]any{"code": -32601, "message": "method not found"}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRemoteSignerChecksSignedTransaction(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	otherKey, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	chainID := big.NewInt(84532)
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	unsigned := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     7,
+		GasTipCap: big.NewInt(1_000_000),
+		GasFeeCap: big.NewInt(2_000_000_000),
+		Gas:       60_000,
+		To:        &to,
+		Value:     big.NewInt(0),
+		Data:      []byte{0x01, 0x02},
+	})
+
+	tests := []struct {
+		name     string
+		protocol string
+		key      *ecdsa.PrivateKey
+		tamper   func(tx *types.DynamicFeeTx)
+		delay    time.Duration
+		wantErr  string
+	}{
+		{name: "clef", protocol: RemoteProtocolClef, key: key},
+		{name: "web3signer", protocol: RemoteProtocolWeb3Signer, key: key},
+		{name: "wrong key", protocol: RemoteProtocolClef, key: otherKey, wantErr: "signed with"},
+		{name: "different transaction", protocol: RemoteProtocolClef, key: key, tamper: func(tx *types.DynamicFeeTx) { tx.Nonce++ }, wantErr: "different transaction"},
+		{name: "timeout", protocol: RemoteProtocolClef, key: key, delay: 500 * time.Millisecond, wantErr: "deadline exceeded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := signerStub(t, tt.key, tt.delay, tt.tamper)
+			signer, err := NewRemoteSigner(server.URL, address.Hex(), tt.protocol)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			signed, err := signer.SignTx(ctx, unsigned, chainID)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("SignTx() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SignTx() error = %v", err)
+			}
+			if sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed); err != nil || sender != address {
+				t.Fatalf("signed transaction recovers to %s, %v, want %s", sender.Hex(), err, address.Hex())
+			}
+		})
+	}
+}
+
+func TestRemoteSignerSurfacesRPCErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"request denied"}}`))
+	}))
+	defer server.Close()
+
+	key, _ := crypto.GenerateKey()
+	signer, err := NewRemoteSigner(server.URL, crypto.PubkeyToAddress(key.PublicKey).Hex(), RemoteProtocolWeb3Signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := types.NewTx(&types.DynamicFeeTx{ChainID: big.NewInt(1), GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), Value: big.NewInt(0)})
+	if _, err := signer.SignTx(context.Background(), tx, big.NewInt(1)); err == nil || !strings.Contains(err.Error(), "request denied") {
+		t.Fatalf("SignTx() error = %v, want the signer's error", err)
+	}
+}
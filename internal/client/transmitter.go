@@ -0,0 +1,123 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// messageTransmitterABI covers the parts of CCTP's MessageTransmitterV2 used to relay transfers
+const messageTransmitterABI = `[
+	{"type":"event","name":"MessageSent","anonymous":false,"inputs":[{"name":"message","type":"bytes","indexed":false}]},
+	{"type":"function","name":"receiveMessage","stateMutability":"nonpayable","inputs":[{"name":"message","type":"bytes"},{"name":"attestation","type":"bytes"}],"outputs":[{"name":"success","type":"bool"}]},
+	{"type":"function","name":"usedNonces","stateMutability":"view","inputs":[{"name":"nonce","type":"bytes32"}],"outputs":[{"name":"","type":"uint256"}]}
+]`
+
+// parsedTransmitterABI is the parsed MessageTransmitterV2 ABI
+var parsedTransmitterABI = mustParseABI(messageTransmitterABI)
+
+// MessageSentTopic is the topic of the MessageSent event emitted for every CCTP burn
+var MessageSentTopic = crypto.Keccak256Hash([]byte("MessageSent(bytes)"))
+
+// MessageTransmitter wraps CCTP's MessageTransmitterV2 contract on a specific chain
+type MessageTransmitter struct {
+	contract  *bind.BoundContract
+	client    *ethclient.Client
+	txManager *TxManager
+	chainID   uint64
+}
+
+// NewMessageTransmitter creates a MessageTransmitter instance for a specific chain
+func NewMessageTransmitter(client *ethclient.Client, txManager *TxManager, chainID uint64) (*MessageTransmitter, error) {
+	transmitterAddress := GetMessageTransmitterAddress(chainID)
+	if !common.IsHexAddress(transmitterAddress) {
+		return nil, fmt.Errorf("invalid MessageTransmitter address for chain %d: %s", chainID, transmitterAddress)
+	}
+
+	if txManager == nil {
+		return nil, fmt.Errorf("transaction manager cannot be nil")
+	}
+
+	contract := bind.NewBoundContract(common.HexToAddress(transmitterAddress), parsedTransmitterABI, client, client, client)
+	return &MessageTransmitter{
+		contract:  contract,
+		client:    client,
+		txManager: txManager,
+		chainID:   chainID,
+	}, nil
+}
+
+// SendReceiveMessage submits an attested CCTP message to mint USDC on this chain without waiting for it to be mined
+func (mt *MessageTransmitter) SendReceiveMessage(message, attestation []byte) (*types.Transaction, error) {
+	tx, err := mt.txManager.Submit(func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return mt.contract.Transact(opts, "receiveMessage", message, attestation)
+	}, TxHooks{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive message transaction: %w", err)
+	}
+
+	log.Printf("Receive message transaction sent on chain %d: %s", mt.chainID, tx.Hash().Hex())
+	return tx, nil
+}
+
+// IsNonceUsed checks whether a CCTP message nonce was already received on this chain
+func (mt *MessageTransmitter) IsNonceUsed(nonce [32]byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var out []any
+	if err := mt.contract.Call(&bind.CallOpts{Context: ctx}, &out, "usedNonces", nonce); err != nil {
+		return false, fmt.Errorf("failed to check used nonce: %w", err)
+	}
+	used, ok := out[0].(*big.Int)
+	if !ok {
+		return false, fmt.Errorf("unexpected usedNonces result %T", out[0])
+	}
+	return used.Sign() != 0, nil
+}
+
+// TransactionState reports the state of a transaction previously sent by the relayer signer and
+// the hash of its version that was mined or is current
+func (mt *MessageTransmitter) TransactionState(txHash common.Hash, nonce uint64) (TxState, common.Hash, error) {
+	return mt.txManager.TransactionState(txHash, nonce)
+}
+
+// ExtractSentMessages returns the CCTP messages emitted by a transaction, in log order
+func ExtractSentMessages(receipt *types.Receipt) ([][]byte, error) {
+	var messages [][]byte
+	for _, vLog := range receipt.Logs {
+		if len(vLog.Topics) == 0 || vLog.Topics[0] != MessageSentTopic {
+			continue
+		}
+
+		values, err := parsedTransmitterABI.Unpack("MessageSent", vLog.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode MessageSent event: %w", err)
+		}
+		message, ok := values[0].([]byte)
+		if !ok {
+			return nil, fmt.Errorf("unexpected MessageSent payload %T", values[0])
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// mustParseABI parses an inline ABI definition, panicking on invalid JSON
+func mustParseABI(definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(fmt.Sprintf("invalid inline ABI: %v", err))
+	}
+	return parsed
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// TxState is the on-chain state of a previously sent transaction
+type TxState int
+
+const (
+	TxPending   TxState = iota // Not mined yet
+	TxSucceeded                // Mined with a successful receipt
+	TxReverted                 // Mined but reverted
+	TxDropped                  // Never mined, its nonce was used by another transaction
+)
+
+// String returns a readable name for the transaction state
+func (s TxState) String() string {
+	switch s {
+	case TxSucceeded:
+		return "succeeded"
+	case TxReverted:
+		return "reverted"
+	case TxDropped:
+		return "dropped"
+	default:
+		return "pending"
+	}
+}
+
+// CheckTransaction reports the state of a transaction sent from an account with the given nonce.
+// Pass every version broadcast with that nonce; the returned hash is the one that was mined, or the latest one.
+func CheckTransaction(ethClient *ethclient.Client, from common.Address, nonce uint64, txHashes ...common.Hash) (TxState, common.Hash, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	latest := txHashes[len(txHashes)-1]
+	state, minedHash, err := receiptsState(ctx, ethClient, txHashes)
+	if err != nil || state != TxPending {
+		return state, minedHash, err
+	}
+
+	// Without a receipt the transaction is still pending unless its nonce has been used
+	confirmedNonce, err := ethClient.NonceAt(ctx, from, nil)
+	if err != nil {
+		return TxPending, latest, fmt.Errorf("failed to get nonce for %s: %w", from.Hex(), err)
+	}
+	if confirmedNonce <= nonce {
+		return TxPending, latest, nil
+	}
+
+	// The nonce moved on, check the receipts again in case a version was mined meanwhile
+	state, minedHash, err = receiptsState(ctx, ethClient, txHashes)
+	if err != nil || state != TxPending {
+		return state, minedHash, err
+	}
+	return TxDropped, latest, nil
+}
+
+// receiptsState returns the state and hash of the first version with a receipt, or TxPending when none has one
+func receiptsState(ctx context.Context, ethClient *ethclient.Client, txHashes []common.Hash) (TxState, common.Hash, error) {
+	for _, txHash := range txHashes {
+		state, err := receiptState(ctx, ethClient, txHash)
+		if err != nil || state != TxPending {
+			return state, txHash, err
+		}
+	}
+	return TxPending, txHashes[len(txHashes)-1], nil
+}
+
+// receiptState maps a transaction receipt to a state, returning TxPending when there is none
+func receiptState(ctx context.Context, ethClient *ethclient.Client, txHash common.Hash) (TxState, error) {
+	receipt, err := ethClient.TransactionReceipt(ctx, txHash)
+	if errors.Is(err, ethereum.NotFound) {
+		return TxPending, nil
+	}
+	if err != nil {
+		return TxPending, fmt.Errorf("failed to get receipt for %s: %w", txHash.Hex(), err)
+	}
+	if receipt.Status == types.ReceiptStatusSuccessful {
+		return TxSucceeded, nil
+	}
+	return TxReverted, nil
+}
@@ -0,0 +1,474 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// minBumpPercent is the smallest fee increase nodes accept for a replacement transaction
+const minBumpPercent = 10
+
+// trackedRetention is how long finished transactions stay known for replacement lookups
+const trackedRetention = time.Hour
+
+// ErrTxDropped means a transaction's nonce was used by a transaction the manager did not send
+var ErrTxDropped = errors.New("transaction dropped")
+
+// TxConfig controls fees, replacement and timeouts of the transactions sent on a chain
+type TxConfig struct {
+	MaxFeeCap    *big.Int      // Upper bound for the fee cap (or legacy gas price) in wei
+	MaxTipCap    *big.Int      // Upper bound for the priority fee in wei
+	BumpAfter    time.Duration // How long a transaction may stay unmined before it is replaced with higher fees
+	BumpPercent  int           // Fee increase of every replacement
+	Timeout      time.Duration // How long to track a transaction before giving up
+	PollInterval time.Duration // Delay between receipt checks
+}
+
+// LoadTxConfig loads the transaction policy for a chain, with TX_*_<chainID> variables overriding the defaults
+func LoadTxConfig(chainID uint64) TxConfig {
+	config := TxConfig{
+		MaxFeeCap:    gweiToWei(chainEnvInt("TX_MAX_FEE_GWEI", chainID, 100)),
+		MaxTipCap:    gweiToWei(chainEnvInt("TX_MAX_TIP_GWEI", chainID, 5)),
+		BumpAfter:    time.Duration(chainEnvInt("TX_BUMP_AFTER_SECONDS", chainID, 60)) * time.Second,
+		BumpPercent:  chainEnvInt("TX_BUMP_PERCENT", chainID, 20),
+		Timeout:      time.Duration(chainEnvInt("TX_TIMEOUT_SECONDS", chainID, 600)) * time.Second,
+		PollInterval: time.Duration(chainEnvInt("TX_POLL_SECONDS", chainID, 3)) * time.Second,
+	}
+	if config.BumpPercent < minBumpPercent {
+		log.Printf("Warning: TX_BUMP_PERCENT %d for chain %d is below the %d%% nodes require, using %d%%",
+			config.BumpPercent, chainID, minBumpPercent, minBumpPercent)
+		config.BumpPercent = minBumpPercent
+	}
+	return config
+}
+
+// chainEnvInt reads key_<chainID>, falling back to key and then to the default
+func chainEnvInt(key string, chainID uint64, defaultValue int) int {
+	return GetEnvInt(key+"_"+strconv.FormatUint(chainID, 10), GetEnvInt(key, defaultValue))
+}
+
+// gweiToWei converts a whole number of gwei to wei
+func gweiToWei(gwei int) *big.Int {
+	return new(big.Int).Mul(big.NewInt(int64(gwei)), big.NewInt(params.GWei))
+}
+
+// TxBuilder creates a signed transaction from the given options, typically by calling a contract binding
+type TxBuilder func(opts *bind.TransactOpts) (*types.Transaction, error)
+
+// TxHooks receives updates about a submitted transaction. All hooks are optional.
+type TxHooks struct {
+	OnSigned   func(tx *types.Transaction) error       // Signed but not broadcast yet, an error aborts without using the nonce
+	OnReplaced func(tx *types.Transaction)             // A fee-bumped replacement was broadcast
+	OnReceipt  func(receipt *types.Receipt, err error) // Mined (check the status), dropped or timed out
+}
+
+// managedTx tracks every version of a transaction broadcast with the same nonce
+type managedTx struct {
+	versions   []*types.Transaction // Oldest first, the last one is the current version
+	sentAt     time.Time            // When the current version was broadcast
+	startedAt  time.Time
+	finishedAt time.Time
+	hooks      TxHooks
+}
+
+// latest returns the most recently broadcast version
+func (m *managedTx) latest() *types.Transaction {
+	return m.versions[len(m.versions)-1]
+}
+
+// TxManager sends the transactions of one signer on one chain, coordinating nonces and replacing stuck transactions
+type TxManager struct {
+	client  *ethclient.Client
+	signer  Signer
+	from    common.Address
+	chainID uint64
+	config  TxConfig
+
+	nonceMu  sync.Mutex
+	nonce    uint64
+	nonceSet bool
+
+	trackedMu sync.Mutex
+	tracked   map[common.Hash]*managedTx // hash of any version -> transaction
+}
+
+// NewTxManager creates a transaction manager for the given signer on a chain
+func NewTxManager(client *ethclient.Client, signer Signer, chainID uint64, config TxConfig) (*TxManager, error) {
+	if signer == nil {
+		return nil, fmt.Errorf("signer cannot be nil")
+	}
+
+	return &TxManager{
+		client:  client,
+		signer:  signer,
+		from:    signer.Address(),
+		chainID: chainID,
+		config:  config,
+		tracked: make(map[common.Hash]*managedTx),
+	}, nil
+}
+
+// From returns the address transactions are sent from
+func (tm *TxManager) From() common.Address {
+	return tm.from
+}
+
+// Submit builds a transaction with the next nonce and current fees, broadcasts it and tracks it in the background
+func (tm *TxManager) Submit(build TxBuilder, hooks TxHooks) (*types.Transaction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tipCap, feeCap, legacy, err := tm.suggestFees(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tm.nonceMu.Lock()
+	defer tm.nonceMu.Unlock()
+
+	if !tm.nonceSet {
+		nonce, err := tm.client.PendingNonceAt(ctx, tm.from)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get nonce on chain %d: %w", tm.chainID, err)
+		}
+		tm.nonce = nonce
+		tm.nonceSet = true
+	}
+
+	opts := SignerTransactOpts(ctx, tm.signer, tm.chainID)
+	opts.Nonce = new(big.Int).SetUint64(tm.nonce)
+	opts.NoSend = true
+	if legacy {
+		opts.GasPrice = feeCap
+	} else {
+		opts.GasFeeCap = feeCap
+		opts.GasTipCap = tipCap
+	}
+
+	// A failed build (for example a reverting gas estimate) does not use up the nonce
+	tx, err := build(opts)
+	if err != nil {
+		return nil, err
+	}
+	if hooks.OnSigned != nil {
+		if err := hooks.OnSigned(tx); err != nil {
+			return nil, fmt.Errorf("failed to record transaction before broadcast: %w", err)
+		}
+	}
+
+	// A failed-over request may have reached a node that already accepted the transaction
+	if err := tm.client.SendTransaction(ctx, tx); err != nil && !strings.Contains(err.Error(), "already known") {
+		// Resynchronise with the node in case our nonce drifted
+		tm.nonceSet = false
+		return nil, fmt.Errorf("failed to broadcast transaction on chain %d: %w", tm.chainID, err)
+	}
+	tm.nonce++
+
+	log.Printf("Transaction %s sent on chain %d with nonce %d", tx.Hash().Hex(), tm.chainID, tx.Nonce())
+
+	mtx := tm.track(tx, hooks)
+	go tm.monitor(mtx)
+	return tx, nil
+}
+
+// SendAndWait submits a transaction and blocks until it is mined, dropped or times out
+func (tm *TxManager) SendAndWait(build TxBuilder) (*types.Receipt, error) {
+	type result struct {
+		receipt *types.Receipt
+		err     error
+	}
+	done := make(chan result, 1)
+
+	_, err := tm.Submit(build, TxHooks{
+		OnReceipt: func(receipt *types.Receipt, err error) {
+			done <- result{receipt: receipt, err: err}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := <-done
+	return res.receipt, res.err
+}
+
+// Rebroadcast sends a signed transaction again unless the node already has it, for transactions recorded before a
+// restart interrupted their broadcast. The transaction is tracked and replaced with higher fees like a new one.
+func (tm *TxManager) Rebroadcast(tx *types.Transaction) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, _, err := tm.client.TransactionByHash(ctx, tx.Hash()); err == nil {
+		return nil
+	} else if !errors.Is(err, ethereum.NotFound) {
+		return fmt.Errorf("failed to look up transaction %s on chain %d: %w", tx.Hash().Hex(), tm.chainID, err)
+	}
+
+	if err := tm.client.SendTransaction(ctx, tx); err != nil && !strings.Contains(err.Error(), "already known") {
+		return fmt.Errorf("failed to rebroadcast transaction %s on chain %d: %w", tx.Hash().Hex(), tm.chainID, err)
+	}
+	log.Printf("Transaction %s rebroadcast on chain %d with nonce %d", tx.Hash().Hex(), tm.chainID, tx.Nonce())
+
+	tm.trackedMu.Lock()
+	_, tracked := tm.tracked[tx.Hash()]
+	tm.trackedMu.Unlock()
+	if !tracked {
+		go tm.monitor(tm.track(tx, TxHooks{}))
+	}
+	return nil
+}
+
+// TransactionState reports the state of a transaction sent with the given nonce, also checking its replacements.
+// It returns the hash of the version that was mined, or of the latest version while none was.
+func (tm *TxManager) TransactionState(txHash common.Hash, nonce uint64) (TxState, common.Hash, error) {
+	return CheckTransaction(tm.client, tm.from, nonce, tm.versionHashes(txHash)...)
+}
+
+// track registers a newly sent transaction, pruning transactions that finished a while ago
+func (tm *TxManager) track(tx *types.Transaction, hooks TxHooks) *managedTx {
+	tm.trackedMu.Lock()
+	defer tm.trackedMu.Unlock()
+
+	for hash, mtx := range tm.tracked {
+		if !mtx.finishedAt.IsZero() && time.Since(mtx.finishedAt) > trackedRetention {
+			delete(tm.tracked, hash)
+		}
+	}
+
+	now := time.Now()
+	mtx := &managedTx{
+		versions:  []*types.Transaction{tx},
+		sentAt:    now,
+		startedAt: now,
+		hooks:     hooks,
+	}
+	tm.tracked[tx.Hash()] = mtx
+	return mtx
+}
+
+// versionHashes returns the hashes of every known version of a transaction, oldest first
+func (tm *TxManager) versionHashes(txHash common.Hash) []common.Hash {
+	tm.trackedMu.Lock()
+	defer tm.trackedMu.Unlock()
+
+	mtx, exists := tm.tracked[txHash]
+	if !exists {
+		return []common.Hash{txHash}
+	}
+	hashes := make([]common.Hash, len(mtx.versions))
+	for i, version := range mtx.versions {
+		hashes[i] = version.Hash()
+	}
+	return hashes
+}
+
+// monitor waits for a transaction to be mined, replacing it with higher fees while it is stuck
+func (tm *TxManager) monitor(mtx *managedTx) {
+	ticker := time.NewTicker(tm.config.PollInterval)
+	defer ticker.Stop()
+
+	// Versions are only appended by bump, which runs on this goroutine
+	firstHash := mtx.versions[0].Hash()
+	nonce := mtx.versions[0].Nonce()
+	for range ticker.C {
+		state, minedHash, err := CheckTransaction(tm.client, tm.from, nonce, tm.versionHashes(firstHash)...)
+		if err != nil {
+			log.Printf("Warning: Failed to check transaction %s on chain %d: %v", firstHash.Hex(), tm.chainID, err)
+			continue
+		}
+
+		switch state {
+		case TxSucceeded, TxReverted:
+			receipt, err := tm.client.TransactionReceipt(context.Background(), minedHash)
+			tm.finish(mtx, receipt, err)
+			return
+		case TxDropped:
+			tm.finish(mtx, nil, fmt.Errorf("%w: nonce %d on chain %d was used by another transaction", ErrTxDropped, nonce, tm.chainID))
+			return
+		}
+
+		tm.trackedMu.Lock()
+		startedAt, sentAt := mtx.startedAt, mtx.sentAt
+		tm.trackedMu.Unlock()
+
+		if time.Since(startedAt) > tm.config.Timeout {
+			tm.finish(mtx, nil, fmt.Errorf("transaction %s not mined on chain %d after %s", minedHash.Hex(), tm.chainID, tm.config.Timeout))
+			return
+		}
+		if time.Since(sentAt) > tm.config.BumpAfter {
+			tm.bump(mtx)
+		}
+	}
+}
+
+// finish marks a transaction as no longer monitored and reports the outcome
+func (tm *TxManager) finish(mtx *managedTx, receipt *types.Receipt, err error) {
+	tm.trackedMu.Lock()
+	mtx.finishedAt = time.Now()
+	tm.trackedMu.Unlock()
+
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	if mtx.hooks.OnReceipt != nil {
+		mtx.hooks.OnReceipt(receipt, err)
+	}
+}
+
+// bump replaces a stuck transaction with a copy paying higher fees, or rebroadcasts it when the caps are reached
+func (tm *TxManager) bump(mtx *managedTx) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	current := mtx.latest()
+	replacement, err := tm.replacement(ctx, current)
+	if err != nil {
+		log.Printf("Warning: Cannot replace transaction %s on chain %d, rebroadcasting it: %v", current.Hash().Hex(), tm.chainID, err)
+		if err := tm.client.SendTransaction(ctx, current); err != nil && !strings.Contains(err.Error(), "already known") {
+			log.Printf("Warning: Failed to rebroadcast transaction %s: %v", current.Hash().Hex(), err)
+		}
+		tm.trackedMu.Lock()
+		mtx.sentAt = time.Now()
+		tm.trackedMu.Unlock()
+		return
+	}
+
+	if err := tm.client.SendTransaction(ctx, replacement); err != nil {
+		log.Printf("Warning: Failed to broadcast replacement for transaction %s: %v", current.Hash().Hex(), err)
+		return
+	}
+
+	tm.trackedMu.Lock()
+	mtx.versions = append(mtx.versions, replacement)
+	mtx.sentAt = time.Now()
+	tm.tracked[replacement.Hash()] = mtx
+	tm.trackedMu.Unlock()
+
+	log.Printf("Replaced stuck transaction %s with %s on chain %d (nonce %d)",
+		current.Hash().Hex(), replacement.Hash().Hex(), tm.chainID, replacement.Nonce())
+	if mtx.hooks.OnReplaced != nil {
+		mtx.hooks.OnReplaced(replacement)
+	}
+}
+
+// replacement signs a copy of a transaction with fees raised by the bump percentage and the current estimate
+func (tm *TxManager) replacement(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	tipCap, feeCap, legacy, err := tm.suggestFees(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var unsigned *types.Transaction
+	if legacy || tx.Type() == types.LegacyTxType {
+		gasPrice, err := tm.bumpedFee(tx.GasPrice(), feeCap, tm.config.MaxFeeCap)
+		if err != nil {
+			return nil, err
+		}
+		unsigned = types.NewTx(&types.LegacyTx{
+			Nonce:    tx.Nonce(),
+			GasPrice: gasPrice,
+			Gas:      tx.Gas(),
+			To:       tx.To(),
+			Value:    tx.Value(),
+			Data:     tx.Data(),
+		})
+	} else {
+		newTip, err := tm.bumpedFee(tx.GasTipCap(), tipCap, tm.config.MaxTipCap)
+		if err != nil {
+			return nil, err
+		}
+		newFeeCap, err := tm.bumpedFee(tx.GasFeeCap(), feeCap, tm.config.MaxFeeCap)
+		if err != nil {
+			return nil, err
+		}
+		unsigned = types.NewTx(&types.DynamicFeeTx{
+			ChainID:    tx.ChainId(),
+			Nonce:      tx.Nonce(),
+			GasTipCap:  newTip,
+			GasFeeCap:  newFeeCap,
+			Gas:        tx.Gas(),
+			To:         tx.To(),
+			Value:      tx.Value(),
+			Data:       tx.Data(),
+			AccessList: tx.AccessList(),
+		})
+	}
+
+	return tm.signer.SignTx(ctx, unsigned, new(big.Int).SetUint64(tm.chainID))
+}
+
+// bumpedFee raises a fee by the bump percentage or to the current estimate, failing when the cap prevents a valid replacement
+func (tm *TxManager) bumpedFee(current, suggested, limit *big.Int) (*big.Int, error) {
+	bumped := new(big.Int).Mul(current, big.NewInt(int64(100+tm.config.BumpPercent)))
+	bumped.Div(bumped, big.NewInt(100))
+	if suggested.Cmp(bumped) > 0 {
+		bumped = new(big.Int).Set(suggested)
+	}
+	if bumped.Cmp(limit) > 0 {
+		bumped = new(big.Int).Set(limit)
+	}
+
+	required := new(big.Int).Mul(current, big.NewInt(100+minBumpPercent))
+	required.Div(required, big.NewInt(100))
+	if bumped.Cmp(required) < 0 {
+		return nil, fmt.Errorf("fee cap %s wei reached", limit.String())
+	}
+	return bumped, nil
+}
+
+// suggestFees estimates EIP-1559 fees within the configured caps, or a legacy gas price on chains without a base fee
+func (tm *TxManager) suggestFees(ctx context.Context) (tipCap, feeCap *big.Int, legacy bool, err error) {
+	head, err := tm.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to get latest header on chain %d: %w", tm.chainID, err)
+	}
+
+	if head.BaseFee == nil {
+		gasPrice, err := tm.client.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("failed to suggest gas price on chain %d: %w", tm.chainID, err)
+		}
+		if gasPrice.Cmp(tm.config.MaxFeeCap) > 0 {
+			return nil, nil, false, fmt.Errorf("gas price %s wei on chain %d exceeds the %s wei cap",
+				gasPrice.String(), tm.chainID, tm.config.MaxFeeCap.String())
+		}
+		return nil, gasPrice, true, nil
+	}
+
+	if head.BaseFee.Cmp(tm.config.MaxFeeCap) >= 0 {
+		return nil, nil, false, fmt.Errorf("base fee %s wei on chain %d exceeds the %s wei cap",
+			head.BaseFee.String(), tm.chainID, tm.config.MaxFeeCap.String())
+	}
+
+	tipCap, err = tm.client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to suggest tip on chain %d: %w", tm.chainID, err)
+	}
+	if tipCap.Cmp(tm.config.MaxTipCap) > 0 {
+		tipCap = new(big.Int).Set(tm.config.MaxTipCap)
+	}
+
+	// Leave room for the base fee to double before the transaction stops being includable
+	feeCap = new(big.Int).Add(new(big.Int).Mul(head.BaseFee, big.NewInt(2)), tipCap)
+	if feeCap.Cmp(tm.config.MaxFeeCap) > 0 {
+		feeCap = new(big.Int).Set(tm.config.MaxFeeCap)
+	}
+	if tipCap.Cmp(feeCap) > 0 {
+		tipCap = new(big.Int).Set(feeCap)
+	}
+	return tipCap, feeCap, false, nil
+}
@@ -0,0 +1,358 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// nodeStub is a node with a fixed fee market that records broadcast transactions and mines the ones it is told to
+type nodeStub struct {
+	mu             sync.Mutex
+	baseFee        *big.Int // nil for a chain without EIP-1559
+	tip            *big.Int
+	gasPrice       *big.Int
+	pendingNonce   uint64
+	nonceReads     int    // eth_getTransactionCount calls for the pending nonce
+	sendErr        string // Error returned by the next eth_sendRawTransaction
+	sent           []*types.Transaction
+	mined          map[common.Hash]bool
+	confirmedNonce uint64
+}
+
+// newNodeStub starts a node with a 1 gwei base fee and tip and a client connected to it
+func newNodeStub(t *testing.T) (*nodeStub, *ethclient.Client) {
+	t.Helper()
+	node := &nodeStub{
+		baseFee:  big.NewInt(params.GWei),
+		tip:      big.NewInt(params.GWei),
+		gasPrice: big.NewInt(2 * params.GWei),
+		mined:    make(map[common.Hash]bool),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		result, err := node.handle(request.Method, request.Params)
+		response := map[string]any{"jsonrpc": "2.0", "id": request.ID, "result": result}
+		if err != nil {
+			response = map[string]any{"jsonrpc": "2.0", "id": request.ID, "error": map[string]any{"code": -32000, "message": err.Error()}}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := ethclient.Dial(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return node, client
+}
+
+// handle answers the JSON-RPC methods the transaction manager uses
+func (n *nodeStub) handle(method string, args []json.RawMessage) (any, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	switch method {
+	case "eth_getBlockByNumber":
+		return &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(0), BaseFee: n.baseFee}, nil
+	case "eth_maxPriorityFeePerGas":
+		return (*hexutil.Big)(n.tip), nil
+	case "eth_gasPrice":
+		return (*hexutil.Big)(n.gasPrice), nil
+	case "eth_getTransactionCount":
+		if strings.Contains(string(args[1]), "pending") {
+			n.nonceReads++
+			return hexutil.Uint64(n.pendingNonce), nil
+		}
+		return hexutil.Uint64(n.confirmedNonce), nil
+	case "eth_sendRawTransaction":
+		var raw hexutil.Bytes
+		json.Unmarshal(args[0], &raw)
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(raw); err != nil {
+			return nil, err
+		}
+		if n.sendErr != "" {
+			err := errors.New(n.sendErr)
+			n.sendErr = ""
+			return nil, err
+		}
+		n.sent = append(n.sent, tx)
+		return tx.Hash(), nil
+	case "eth_getTransactionReceipt":
+		var hash common.Hash
+		json.Unmarshal(args[0], &hash)
+		if !n.mined[hash] {
+			return nil, nil
+		}
+		return &types.Receipt{Status: types.ReceiptStatusSuccessful, TxHash: hash, Logs: []*types.Log{}, BlockNumber: big.NewInt(1)}, nil
+	}
+	return nil, errors.New("method not found")
+}
+
+// newTestTxManager creates a transaction manager sending from a generated key through the node
+func newTestTxManager(t *testing.T, client *ethclient.Client, config TxConfig) *TxManager {
+	t.Helper()
+	key, _ := crypto.GenerateKey()
+	signer := &keySigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey), kind: SignerLocal}
+	tm, err := NewTxManager(client, signer, 84532, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tm
+}
+
+// transferBuilder builds a plain transfer with the fees and nonce the manager chose
+func transferBuilder(opts *bind.TransactOpts) (*types.Transaction, error) {
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	if opts.GasPrice != nil {
+		return opts.Signer(opts.From, types.NewTx(&types.LegacyTx{
+			Nonce: opts.Nonce.Uint64(), GasPrice: opts.GasPrice, Gas: 21000, To: &to, Value: big.NewInt(0),
+		}))
+	}
+	return opts.Signer(opts.From, types.NewTx(&types.DynamicFeeTx{
+		ChainID: big.NewInt(84532), Nonce: opts.Nonce.Uint64(), GasTipCap: opts.GasTipCap, GasFeeCap: opts.GasFeeCap,
+		Gas: 21000, To: &to, Value: big.NewInt(0),
+	}))
+}
+
+// idleTxConfig never polls, so submitted transactions stay pending for the test to inspect
+var idleTxConfig = TxConfig{
+	MaxFeeCap:    big.NewInt(100 * params.GWei),
+	MaxTipCap:    big.NewInt(5 * params.GWei),
+	BumpAfter:    time.Hour,
+	BumpPercent:  20,
+	Timeout:      time.Hour,
+	PollInterval: time.Hour,
+}
+
+func TestSubmitLeavesNoNonceGaps(t *testing.T) {
+	node, client := newNodeStub(t)
+	node.pendingNonce = 5
+	tm := newTestTxManager(t, client, idleTxConfig)
+
+	submit := func(build TxBuilder) (uint64, error) {
+		t.Helper()
+		tx, err := tm.Submit(build, TxHooks{})
+		if err != nil {
+			return 0, err
+		}
+		return tx.Nonce(), nil
+	}
+
+	if nonce, err := submit(transferBuilder); err != nil || nonce != 5 {
+		t.Fatalf("first nonce = %d, %v, want 5", nonce, err)
+	}
+
+	// A build that fails before signing, for example a reverting gas estimate, keeps the nonce
+	if _, err := submit(func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return nil, errors.New("execution reverted")
+	}); err == nil {
+		t.Fatal("Submit() with a failing build succeeded")
+	}
+	if nonce, err := submit(transferBuilder); err != nil || nonce != 6 {
+		t.Fatalf("nonce after failed build = %d, %v, want 6", nonce, err)
+	}
+
+	// So does a transaction that was recorded but never broadcast
+	if _, err := tm.Submit(transferBuilder, TxHooks{OnSigned: func(*types.Transaction) error { return errors.New("store unavailable") }}); err == nil {
+		t.Fatal("Submit() with a failing OnSigned hook succeeded")
+	}
+	if nonce, err := submit(transferBuilder); err != nil || nonce != 7 {
+		t.Fatalf("nonce after failed hook = %d, %v, want 7", nonce, err)
+	}
+
+	// A node that already has the transaction accepted it
+	node.mu.Lock()
+	node.sendErr = "already known"
+	node.mu.Unlock()
+	if nonce, err := submit(transferBuilder); err != nil || nonce != 8 {
+		t.Fatalf("nonce of known transaction = %d, %v, want 8", nonce, err)
+	}
+	if nonce, err := submit(transferBuilder); err != nil || nonce != 9 {
+		t.Fatalf("nonce after known transaction = %d, %v, want 9", nonce, err)
+	}
+
+	if node.nonceReads != 1 {
+		t.Fatalf("pending nonce read %d times, want once", node.nonceReads)
+	}
+}
+
+func TestSubmitResyncsNonceAfterFailedBroadcast(t *testing.T) {
+	node, client := newNodeStub(t)
+	node.pendingNonce = 5
+	tm := newTestTxManager(t, client, idleTxConfig)
+
+	node.mu.Lock()
+	node.sendErr = "nonce too low"
+	node.mu.Unlock()
+	if _, err := tm.Submit(transferBuilder, TxHooks{}); err == nil || !strings.Contains(err.Error(), "nonce too low") {
+		t.Fatalf("Submit() error = %v, want the node's rejection", err)
+	}
+
+	// Another sender used the account meanwhile
+	node.mu.Lock()
+	node.pendingNonce = 8
+	node.mu.Unlock()
+	tx, err := tm.Submit(transferBuilder, TxHooks{})
+	if err != nil || tx.Nonce() != 8 {
+		t.Fatalf("Submit() after failed broadcast = %v, %v, want nonce 8", tx, err)
+	}
+}
+
+func TestMonitorReplacesStuckTransaction(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseFee *big.Int
+	}{
+		{name: "eip1559", baseFee: big.NewInt(params.GWei)},
+		{name: "legacy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, client := newNodeStub(t)
+			node.baseFee = tt.baseFee
+			config := idleTxConfig
+			config.PollInterval = 10 * time.Millisecond
+			config.BumpAfter = 0
+			tm := newTestTxManager(t, client, config)
+
+			receipts := make(chan *types.Receipt, 1)
+			original, err := tm.Submit(transferBuilder, TxHooks{
+				// The first replacement gets mined
+				OnReplaced: func(tx *types.Transaction) {
+					node.mu.Lock()
+					node.mined[tx.Hash()] = true
+					node.mu.Unlock()
+				},
+				OnReceipt: func(receipt *types.Receipt, err error) {
+					if err != nil {
+						t.Errorf("OnReceipt() error = %v", err)
+					}
+					receipts <- receipt
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var receipt *types.Receipt
+			select {
+			case receipt = <-receipts:
+			case <-time.After(5 * time.Second):
+				t.Fatal("transaction was not mined")
+			}
+
+			node.mu.Lock()
+			sent := node.sent
+			node.mu.Unlock()
+			if len(sent) != 2 {
+				t.Fatalf("broadcast %d transactions, want the original and one replacement", len(sent))
+			}
+			replacement := sent[1]
+			if receipt == nil || receipt.TxHash != replacement.Hash() {
+				t.Fatalf("receipt %v, want the receipt of the replacement %s", receipt, replacement.Hash().Hex())
+			}
+			if replacement.Nonce() != original.Nonce() {
+				t.Fatalf("replacement nonce %d, want %d", replacement.Nonce(), original.Nonce())
+			}
+
+			// Nodes only accept a replacement paying at least 10% more
+			atLeast := func(fee, current *big.Int) bool {
+				return new(big.Int).Mul(fee, big.NewInt(100)).Cmp(new(big.Int).Mul(current, big.NewInt(100+minBumpPercent))) >= 0
+			}
+			if !atLeast(replacement.GasFeeCap(), original.GasFeeCap()) || !atLeast(replacement.GasTipCap(), original.GasTipCap()) {
+				t.Fatalf("replacement fees %s/%s, want 10%% above %s/%s",
+					replacement.GasFeeCap(), replacement.GasTipCap(), original.GasFeeCap(), original.GasTipCap())
+			}
+			if replacement.Type() != original.Type() {
+				t.Fatalf("replacement type %d, want %d", replacement.Type(), original.Type())
+			}
+
+			if state, minedHash, err := tm.TransactionState(original.Hash(), original.Nonce()); err != nil || state != TxSucceeded || minedHash != replacement.Hash() {
+				t.Fatalf("TransactionState() = %s, %s, %v, want succeeded with the replacement", state, minedHash.Hex(), err)
+			}
+		})
+	}
+}
+
+func TestBumpRebroadcastsAtFeeCap(t *testing.T) {
+	node, client := newNodeStub(t)
+	config := idleTxConfig
+	config.MaxTipCap = big.NewInt(params.GWei)
+	config.MaxFeeCap = big.NewInt(3 * params.GWei)
+	tm := newTestTxManager(t, client, config)
+
+	tx, err := tm.Submit(transferBuilder, TxHooks{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm.bump(tm.tracked[tx.Hash()])
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	if len(node.sent) != 2 || node.sent[1].Hash() != tx.Hash() {
+		t.Fatalf("broadcast %d transactions, want the original twice", len(node.sent))
+	}
+	if versions := tm.versionHashes(tx.Hash()); len(versions) != 1 {
+		t.Fatalf("tracked %d versions, want only the original", len(versions))
+	}
+}
+
+func TestBumpedFee(t *testing.T) {
+	tm := &TxManager{config: TxConfig{BumpPercent: 20}}
+
+	tests := []struct {
+		name      string
+		current   int64
+		suggested int64
+		limit     int64
+		want      int64
+		wantErr   bool
+	}{
+		{name: "bump percentage", current: 100, suggested: 50, limit: 1000, want: 120},
+		{name: "higher estimate", current: 100, suggested: 200, limit: 1000, want: 200},
+		{name: "capped", current: 100, suggested: 200, limit: 115, want: 115},
+		{name: "cap below minimum bump", current: 100, suggested: 200, limit: 105, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tm.bumpedFee(big.NewInt(tt.current), big.NewInt(tt.suggested), big.NewInt(tt.limit))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("bumpedFee() = %s, want an error", got)
+				}
+				return
+			}
+			if err != nil || got.Int64() != tt.want {
+				t.Fatalf("bumpedFee() = %s, %v, want %d", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	gethmath "github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Permit kinds a player can sign for an allowance
+const (
+	PermitKindPermit2 = "permit2" // Permit2 AllowanceTransfer, needs a one-time approval of Permit2
+	PermitKindEIP2612 = "eip2612" // USDC's native permit, works without any prior approval
+)
+
+// usdcPermitABI covers the ERC-20 and EIP-2612 parts of USDC's FiatToken implementation. The generated usdc binding
+// only has the proxy's admin functions.
+const usdcPermitABI = `[
+	{"type":"function","name":"name","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"string"}]},
+	{"type":"function","name":"version","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"string"}]},
+	{"type":"function","name":"DOMAIN_SEPARATOR","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"bytes32"}]},
+	{"type":"function","name":"nonces","stateMutability":"view","inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"balanceOf","stateMutability":"view","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"allowance","stateMutability":"view","inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"permit","stateMutability":"nonpayable","inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"},{"name":"value","type":"uint256"},{"name":"deadline","type":"uint256"},{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}],"outputs":[]}
+]`
+
+// parsedUSDCPermitABI is the parsed FiatToken ABI
+var parsedUSDCPermitABI = mustParseABI(usdcPermitABI)
+
+// USDCToken wraps the USDC token of a specific chain for balances and EIP-2612 permits
+type USDCToken struct {
+	contract *bind.BoundContract
+	address  common.Address
+	chainID  uint64
+
+	// EIP-712 domain read from the token, checked against its DOMAIN_SEPARATOR once
+	domain      *apitypes.TypedDataDomain
+	domainMutex sync.Mutex
+}
+
+// NewUSDCToken creates a USDCToken instance for a specific chain
+func NewUSDCToken(client *ethclient.Client, chainID uint64) (*USDCToken, error) {
+	usdcAddress := GetUSDCAddress(chainID)
+	if !common.IsHexAddress(usdcAddress) {
+		return nil, fmt.Errorf("invalid USDC address for chain %d: %q", chainID, usdcAddress)
+	}
+
+	address := common.HexToAddress(usdcAddress)
+	return &USDCToken{
+		contract: bind.NewBoundContract(address, parsedUSDCPermitABI, client, client, client),
+		address:  address,
+		chainID:  chainID,
+	}, nil
+}
+
+// call reads a single value from the token
+func (t *USDCToken) call(method string, args ...any) (any, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var out []any
+	if err := t.contract.Call(&bind.CallOpts{Context: ctx}, &out, method, args...); err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", method, err)
+	}
+	return out[0], nil
+}
+
+// Domain returns the token's EIP-712 domain, built from its name and version and verified against DOMAIN_SEPARATOR
+func (t *USDCToken) Domain() (apitypes.TypedDataDomain, error) {
+	t.domainMutex.Lock()
+	defer t.domainMutex.Unlock()
+
+	if t.domain != nil {
+		return *t.domain, nil
+	}
+
+	name, err := t.call("name")
+	if err != nil {
+		return apitypes.TypedDataDomain{}, err
+	}
+	version, err := t.call("version")
+	if err != nil {
+		return apitypes.TypedDataDomain{}, err
+	}
+	separator, err := t.call("DOMAIN_SEPARATOR")
+	if err != nil {
+		return apitypes.TypedDataDomain{}, err
+	}
+
+	domain := apitypes.TypedDataDomain{
+		Name:              name.(string),
+		Version:           version.(string),
+		ChainId:           (*gethmath.HexOrDecimal256)(new(big.Int).SetUint64(t.chainID)),
+		VerifyingContract: t.address.Hex(),
+	}
+
+	// A domain that hashes differently would make every signature we ask for unusable on-chain
+	typedData := apitypes.TypedData{Types: apitypes.Types{"EIP712Domain": eip712DomainType}, Domain: domain}
+	hash, err := typedData.HashStruct("EIP712Domain", domain.Map())
+	if err != nil {
+		return apitypes.TypedDataDomain{}, fmt.Errorf("failed to hash token domain: %w", err)
+	}
+	expected := separator.([32]byte)
+	if !bytes.Equal(hash, expected[:]) {
+		return apitypes.TypedDataDomain{}, fmt.Errorf("token domain %q version %q does not match DOMAIN_SEPARATOR of %s", domain.Name, domain.Version, t.address.Hex())
+	}
+
+	t.domain = &domain
+	return domain, nil
+}
+
+// eip712DomainType is the EIP-712 domain of tokens with a name and version
+var eip712DomainType = []apitypes.Type{
+	{Name: "name", Type: "string"},
+	{Name: "version", Type: "string"},
+	{Name: "chainId", Type: "uint256"},
+	{Name: "verifyingContract", Type: "address"},
+}
+
+// Nonce returns the owner's next EIP-2612 permit nonce
+func (t *USDCToken) Nonce(owner common.Address) (*big.Int, error) {
+	nonce, err := t.call("nonces", owner)
+	if err != nil {
+		return nil, err
+	}
+	return nonce.(*big.Int), nil
+}
+
+// BalanceOf returns the USDC balance of an account in base units
+func (t *USDCToken) BalanceOf(account common.Address) (*big.Int, error) {
+	balance, err := t.call("balanceOf", account)
+	if err != nil {
+		return nil, err
+	}
+	return balance.(*big.Int), nil
+}
+
+// AllowanceOf returns the ERC-20 allowance an owner granted a spender
+func (t *USDCToken) AllowanceOf(owner, spender common.Address) (*big.Int, error) {
+	allowance, err := t.call("allowance", owner, spender)
+	if err != nil {
+		return nil, err
+	}
+	return allowance.(*big.Int), nil
+}
+
+// GetAllowance reads a spender's live ERC-20 allowance in the form of a Permit2 allowance. ERC-20 allowances do not
+// expire and the nonce is the owner's next permit nonce.
+func (t *USDCToken) GetAllowance(owner, spender common.Address) (Allowance, error) {
+	amount, err := t.AllowanceOf(owner, spender)
+	if err != nil {
+		return Allowance{}, fmt.Errorf("failed to get allowance: %w", err)
+	}
+	nonce, err := t.Nonce(owner)
+	if err != nil {
+		return Allowance{}, fmt.Errorf("failed to get nonce: %w", err)
+	}
+	return Allowance{Amount: amount, Expiration: big.NewInt(math.MaxInt64), Nonce: nonce}, nil
+}
+
+// CreatePermitTypedData creates EIP-712 typed data for a USDC EIP-2612 permit
+func (t *USDCToken) CreatePermitTypedData(owner, spender common.Address, value, nonce, deadline *big.Int) (*apitypes.TypedData, error) {
+	domain, err := t.Domain()
+	if err != nil {
+		return nil, err
+	}
+
+	typedData := &apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": eip712DomainType,
+			"Permit": []apitypes.Type{
+				{Name: "owner", Type: "address"},
+				{Name: "spender", Type: "address"},
+				{Name: "value", Type: "uint256"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "deadline", Type: "uint256"},
+			},
+		},
+		PrimaryType: "Permit",
+		Domain:      domain,
+		Message: apitypes.TypedDataMessage{
+			"owner":    owner.Hex(),
+			"spender":  spender.Hex(),
+			"value":    value.String(),
+			"nonce":    nonce.String(),
+			"deadline": deadline.String(),
+		},
+	}
+
+	return typedData, nil
+}
+
+// CreatePermitSignatureData creates the complete EIP-2612 permit signature data for frontend
+func (t *USDCToken) CreatePermitSignatureData(owner, spender common.Address, amount *big.Int) (*PermitSignatureData, *apitypes.TypedData, error) {
+	nonce, err := t.Nonce(owner)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get nonce: %w", err)
+	}
+
+	// Set signature deadline to 30 minutes from now
+	deadline := big.NewInt(time.Now().Add(30 * time.Minute).Unix())
+
+	typedData, err := t.CreatePermitTypedData(owner, spender, amount, nonce, deadline)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create typed data: %w", err)
+	}
+
+	permitData := &PermitSignatureData{
+		Kind:        PermitKindEIP2612,
+		Owner:       owner,
+		Spender:     spender,
+		Token:       t.address,
+		Amount:      amount,
+		Expiration:  big.NewInt(math.MaxInt64), // ERC-20 allowances do not expire
+		Nonce:       nonce,
+		SigDeadline: deadline,
+		ChainID:     t.chainID,
+	}
+
+	return permitData, typedData, nil
+}
+
+// VerifyPermitSignature rebuilds the EIP-712 typed data of an EIP-2612 permit and checks that the signature was made
+// by its owner
+func (t *USDCToken) VerifyPermitSignature(permitData *PermitSignatureData, signature string) error {
+	typedData, err := t.CreatePermitTypedData(permitData.Owner, permitData.Spender, permitData.Amount, permitData.Nonce, permitData.SigDeadline)
+	if err != nil {
+		return permitError(PermitErrorInvalid, "failed to rebuild permit: %v", err)
+	}
+	return verifyTypedDataSignature(typedData, permitData.Owner, permitData.SigDeadline, signature)
+}
+
+// SubmitPermit executes a signed EIP-2612 permit through the given transaction manager and returns the transaction hash
+func (t *USDCToken) SubmitPermit(txManager *TxManager, permitData *PermitSignatureData) (common.Hash, error) {
+	sig, err := decodePermitSignature(permitData.Signature)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("invalid permit signature: %w", err)
+	}
+
+	var r, s [32]byte
+	copy(r[:], sig[:32])
+	copy(s[:], sig[32:64])
+	v := sig[64] + 27
+
+	receipt, err := txManager.SendAndWait(func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return t.contract.Transact(opts, "permit", permitData.Owner, permitData.Spender, permitData.Amount, permitData.SigDeadline, v, r, s)
+	})
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to execute USDC permit: %w", err)
+	}
+
+	if receipt.Status != 1 {
+		return common.Hash{}, fmt.Errorf("USDC permit transaction failed with status: %d", receipt.Status)
+	}
+
+	log.Printf("USDC permit of %s for player %s on chain %d confirmed: %s",
+		permitData.Amount.String(), permitData.Owner.Hex(), t.chainID, receipt.TxHash.Hex())
+	return receipt.TxHash, nil
+}
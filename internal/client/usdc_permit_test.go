@@ -0,0 +1,134 @@
+package client
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	gethmath "github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// usdcStub creates a USDC token that reports the given version and DOMAIN_SEPARATOR and the owner's permit nonce
+func usdcStub(t *testing.T, chainID uint64, version string, separator []byte, nonce int64) *USDCToken {
+	t.Helper()
+	address := common.HexToAddress("0x036CbD53842c5426634e7929541eC2318f3dCF7e")
+	outputs := map[string][]any{
+		"name":             {"USDC"},
+		"version":          {version},
+		"DOMAIN_SEPARATOR": {[32]byte(separator)},
+		"nonces":           {big.NewInt(nonce)},
+	}
+	client := callStub(t, func(input []byte) []byte {
+		for name, values := range outputs {
+			method := parsedUSDCPermitABI.Methods[name]
+			if bytes.Equal(input[:4], method.ID) {
+				output, err := method.Outputs.Pack(values...)
+				if err != nil {
+					t.Errorf("failed to pack %s: %v", name, err)
+				}
+				return output
+			}
+		}
+		t.Errorf("unexpected call %x", input[:4])
+		return nil
+	})
+
+	return &USDCToken{
+		contract: bind.NewBoundContract(address, parsedUSDCPermitABI, client, client, client),
+		address:  address,
+		chainID:  chainID,
+	}
+}
+
+// usdcSeparator computes the DOMAIN_SEPARATOR of the stub token at a version
+func usdcSeparator(t *testing.T, chainID uint64, version string) []byte {
+	t.Helper()
+	domain := apitypes.TypedDataDomain{
+		Name:              "USDC",
+		Version:           version,
+		ChainId:           (*gethmath.HexOrDecimal256)(new(big.Int).SetUint64(chainID)),
+		VerifyingContract: "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+	}
+	typedData := apitypes.TypedData{Types: apitypes.Types{"EIP712Domain": eip712DomainType}, Domain: domain}
+	separator, err := typedData.HashStruct("EIP712Domain", domain.Map())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return separator
+}
+
+func TestUSDCDomainMustMatchSeparator(t *testing.T) {
+	token := usdcStub(t, 84532, "2", usdcSeparator(t, 84532, "1"), 0)
+	if _, err := token.Domain(); err == nil || !strings.Contains(err.Error(), "does not match DOMAIN_SEPARATOR") {
+		t.Fatalf("Domain() error = %v, want a DOMAIN_SEPARATOR mismatch", err)
+	}
+}
+
+func TestVerifyUSDCPermitSignature(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	vault := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+
+	tests := []struct {
+		name     string
+		tamper   func(permitData *PermitSignatureData)
+		chainID  uint64 // Chain of the verifying token, the issuing chain if zero
+		wantCode string
+	}{
+		{name: "valid"},
+		{name: "wrong chain", chainID: 11155111, wantCode: PermitErrorWrongSigner},
+		{
+			name: "wrong spender",
+			tamper: func(permitData *PermitSignatureData) {
+				permitData.Spender = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+			},
+			wantCode: PermitErrorWrongSigner,
+		},
+		{
+			name:     "replayed nonce",
+			tamper:   func(permitData *PermitSignatureData) { permitData.Nonce = big.NewInt(6) },
+			wantCode: PermitErrorWrongSigner,
+		},
+		{
+			name: "expired deadline",
+			tamper: func(permitData *PermitSignatureData) {
+				permitData.SigDeadline = big.NewInt(time.Now().Add(-time.Minute).Unix())
+			},
+			wantCode: PermitErrorExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := usdcStub(t, 84532, "2", usdcSeparator(t, 84532, "2"), 5)
+			permitData, typedData, err := token.CreatePermitSignatureData(owner, vault, big.NewInt(10_000_000))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if permitData.Kind != PermitKindEIP2612 || permitData.Nonce.Int64() != 5 {
+				t.Fatalf("permit kind %s nonce %s, want %s nonce 5", permitData.Kind, permitData.Nonce, PermitKindEIP2612)
+			}
+			signature := signTypedData(t, key, typedData)
+			if tt.tamper != nil {
+				tt.tamper(permitData)
+			}
+
+			verifier := token
+			if tt.chainID != 0 {
+				verifier = usdcStub(t, tt.chainID, "2", usdcSeparator(t, tt.chainID, "2"), 5)
+			}
+
+			// Permit2 clients hand EIP-2612 permits to the token
+			p := &Permit2Client{chainID: verifier.chainID, usdc: verifier}
+			if code := permitCode(p.VerifyPermitSignature(permitData, signature)); code != tt.wantCode {
+				t.Fatalf("VerifyPermitSignature() = %q, want %q", code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -5,24 +5,25 @@ import (
 	"fmt"
 	"log"
 	"math/big"
-	"strings"
 	"time"
 
 	"blockchess/contracts-bindings/permit2"
 	"blockchess/contracts-bindings/vaultcontract"
 
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 // Vault wraps a VaultContract instance for a specific chain
 type Vault struct {
-	contract *vaultcontract.Vaultcontract
-	client   *ethclient.Client
-	auth     *bind.TransactOpts
-	chainID  uint64
+	contract  *vaultcontract.Vaultcontract
+	client    *ethclient.Client
+	txManager *TxManager
+	chainID   uint64
 }
 
 // VaultManager manages vault contracts across all chains
@@ -31,7 +32,7 @@ type VaultManager struct {
 }
 
 // NewVault creates a new Vault instance for a specific chain
-func NewVault(client *ethclient.Client, privateKey string, chainID uint64) (*Vault, error) {
+func NewVault(client *ethclient.Client, txManager *TxManager, chainID uint64) (*Vault, error) {
 	// Get vault contract address for this chain
 	vaultAddress := GetVaultAddress(chainID)
 	if vaultAddress == "" {
@@ -48,29 +49,15 @@ func NewVault(client *ethclient.Client, privateKey string, chainID uint64) (*Vau
 		return nil, fmt.Errorf("failed to create vault contract instance for chain %d: %w", chainID, err)
 	}
 
-	// Validate private key
-	if privateKey == "" {
-		return nil, fmt.Errorf("private key cannot be empty")
-	}
-
-	// Remove 0x prefix if present
-	privateKey = strings.TrimPrefix(privateKey, "0x")
-
-	key, err := crypto.HexToECDSA(privateKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse private key: %w", err)
-	}
-
-	auth, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(int64(chainID)))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create transactor: %w", err)
+	if txManager == nil {
+		return nil, fmt.Errorf("transaction manager cannot be nil")
 	}
 
 	return &Vault{
-		contract: contract,
-		client:   client,
-		auth:     auth,
-		chainID:  chainID,
+		contract:  contract,
+		client:    client,
+		txManager: txManager,
+		chainID:   chainID,
 	}, nil
 }
 
@@ -80,20 +67,26 @@ func NewVaultManager(clients *Clients) (*VaultManager, error) {
 		vaults: make(map[uint64]*Vault),
 	}
 
-	privateKey := clients.GetPrivateKey()
-	if privateKey == "" {
-		return nil, fmt.Errorf("no private key available for vault manager")
-	}
-
 	// Create vault instances for all chains that have vault addresses configured
 	for chainID := range VaultAddresses {
+		if reason, disabled := clients.DisabledReason(chainID); disabled {
+			log.Printf("Warning: Skipping vault on chain %d disabled by preflight: %s", chainID, reason)
+			continue
+		}
+
 		client, err := clients.GetClientByChainID(chainID)
 		if err != nil {
 			log.Printf("Warning: Failed to get client for chain %d: %v", chainID, err)
 			continue
 		}
 
-		vault, err := NewVault(client, privateKey, chainID)
+		txManager, err := clients.GetTxManager(chainID)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+
+		vault, err := NewVault(client, txManager, chainID)
 		if err != nil {
 			log.Printf("Warning: Failed to create vault for chain %d: %v", chainID, err)
 			continue
@@ -124,85 +117,123 @@ func (vm *VaultManager) GetAvailableChains() []uint64 {
 	return chains
 }
 
-// Stake deposits USDC from a player to the vault contract using Permit2
-func (v *Vault) Stake(playerAddress common.Address, gameID uint64, amount *big.Int) error {
+// Stake deposits USDC from a player to the vault contract using Permit2 and returns the transaction hash
+func (v *Vault) Stake(playerAddress common.Address, gameID uint64, amount *big.Int) (common.Hash, error) {
 	log.Printf("Staking %s USDC for player %s in game %d on chain %d",
 		amount.String(), playerAddress.Hex(), gameID, v.chainID)
 
 	gameIDBig := new(big.Int).SetUint64(gameID)
 
 	// Call the stake function (the vault contract will handle the USDC transfer internally)
-	tx, err := v.contract.Stake(v.auth, playerAddress, gameIDBig, amount)
+	receipt, err := v.txManager.SendAndWait(func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return v.contract.Stake(opts, playerAddress, gameIDBig, amount)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to stake transaction: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to stake transaction: %w", err)
 	}
 
-	log.Printf("Stake transaction sent: %s", tx.Hash().Hex())
+	if receipt.Status != 1 {
+		return common.Hash{}, fmt.Errorf("transaction failed with status: %d", receipt.Status)
+	}
 
-	// Wait for transaction to be mined
-	receipt, err := bind.WaitMined(context.Background(), v.client, tx)
+	log.Printf("Successfully staked %s USDC for player %s in game %d on chain %d",
+		amount.String(), playerAddress.Hex(), gameID, v.chainID)
+	return receipt.TxHash, nil
+}
+
+// StakeWithSignature deposits exactly one signed Permit2 signature transfer from a player to the vault and returns the
+// transaction hash
+func (v *Vault) StakeWithSignature(playerAddress common.Address, gameID uint64, transfer *PermitTransferData) (common.Hash, error) {
+	if transfer == nil || transfer.Signature == "" {
+		return common.Hash{}, fmt.Errorf("signed permit transfer is required")
+	}
+
+	if transfer.Owner != playerAddress {
+		return common.Hash{}, fmt.Errorf("permit owner mismatch: expected %s, got %s", playerAddress.Hex(), transfer.Owner.Hex())
+	}
+
+	expectedSpender := common.HexToAddress(GetVaultAddress(v.chainID))
+	if transfer.Spender != expectedSpender {
+		return common.Hash{}, fmt.Errorf("permit spender mismatch: expected %s, got %s", expectedSpender.Hex(), transfer.Spender.Hex())
+	}
+
+	if transfer.Deadline.Cmp(big.NewInt(time.Now().Unix())) <= 0 {
+		return common.Hash{}, fmt.Errorf("permit signature has expired")
+	}
+
+	log.Printf("Staking %s USDC for player %s in game %d on chain %d with Permit2 nonce %s",
+		transfer.Amount.String(), playerAddress.Hex(), gameID, v.chainID, transfer.Nonce.String())
+
+	gameIDBig := new(big.Int).SetUint64(gameID)
+	sigBytes := common.FromHex(transfer.Signature)
+
+	// The vault passes the signature to Permit2, which moves the USDC and burns the nonce
+	receipt, err := v.txManager.SendAndWait(func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return v.contract.StakeWithSignature(opts, playerAddress, gameIDBig, transfer.Amount, transfer.Nonce, transfer.Deadline, sigBytes)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to wait for transaction to be mined: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to stake transaction: %w", err)
 	}
 
 	if receipt.Status != 1 {
-		return fmt.Errorf("transaction failed with status: %d", receipt.Status)
+		return common.Hash{}, fmt.Errorf("transaction failed with status: %d", receipt.Status)
 	}
 
 	log.Printf("Successfully staked %s USDC for player %s in game %d on chain %d",
-		amount.String(), playerAddress.Hex(), gameID, v.chainID)
-	return nil
+		transfer.Amount.String(), playerAddress.Hex(), gameID, v.chainID)
+	return receipt.TxHash, nil
 }
 
-// StakeWithPermit deposits USDC from a player to the vault contract using Permit2 signature
-func (v *Vault) StakeWithPermit(playerAddress common.Address, gameID uint64, amount *big.Int, permitData *PermitSignatureData) error {
-	log.Printf("Staking %s USDC for player %s in game %d on chain %d using Permit2",
-		amount.String(), playerAddress.Hex(), gameID, v.chainID)
-
+// SubmitPermit executes a signed Permit2 or USDC permit that lets the vault draw the player's USDC and returns the transaction hash
+func (v *Vault) SubmitPermit(playerAddress common.Address, permitData *PermitSignatureData) (common.Hash, error) {
 	if permitData == nil {
-		return fmt.Errorf("permit signature data is required")
+		return common.Hash{}, fmt.Errorf("permit signature data is required")
 	}
 
 	if permitData.Signature == "" {
-		return fmt.Errorf("permit signature is required")
+		return common.Hash{}, fmt.Errorf("permit signature is required")
 	}
 
-	// Verify the permit is for the correct player and amount
+	// Verify the permit is for the correct player
 	if permitData.Owner != playerAddress {
-		return fmt.Errorf("permit owner mismatch: expected %s, got %s", playerAddress.Hex(), permitData.Owner.Hex())
-	}
-
-	if permitData.Amount.Cmp(amount) < 0 {
-		return fmt.Errorf("permit amount insufficient: need %s, have %s", amount.String(), permitData.Amount.String())
+		return common.Hash{}, fmt.Errorf("permit owner mismatch: expected %s, got %s", playerAddress.Hex(), permitData.Owner.Hex())
 	}
 
 	// Get the vault contract address as the spender
 	vaultAddress := GetVaultAddress(v.chainID)
 	if vaultAddress == "" {
-		return fmt.Errorf("no vault address configured for chain %d", v.chainID)
+		return common.Hash{}, fmt.Errorf("no vault address configured for chain %d", v.chainID)
 	}
 
 	expectedSpender := common.HexToAddress(vaultAddress)
 	if permitData.Spender != expectedSpender {
-		return fmt.Errorf("permit spender mismatch: expected %s, got %s", expectedSpender.Hex(), permitData.Spender.Hex())
+		return common.Hash{}, fmt.Errorf("permit spender mismatch: expected %s, got %s", expectedSpender.Hex(), permitData.Spender.Hex())
 	}
 
 	// Check if permit hasn't expired
 	now := big.NewInt(time.Now().Unix())
 	if permitData.SigDeadline.Cmp(now) <= 0 {
-		return fmt.Errorf("permit signature has expired")
+		return common.Hash{}, fmt.Errorf("permit signature has expired")
+	}
+
+	// USDC's own permit sets the vault's ERC-20 allowance directly
+	if permitData.Kind == PermitKindEIP2612 {
+		token, err := NewUSDCToken(v.client, v.chainID)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		return token.SubmitPermit(v.txManager, permitData)
 	}
 
-	// First, execute the permit to allow the vault to spend USDC
 	permit2Address := GetPermit2Address(v.chainID)
 	if permit2Address == "" {
-		return fmt.Errorf("no Permit2 address configured for chain %d", v.chainID)
+		return common.Hash{}, fmt.Errorf("no Permit2 address configured for chain %d", v.chainID)
 	}
 
 	// Create permit2 contract instance
 	permit2Contract, err := permit2.NewPermit2(common.HexToAddress(permit2Address), v.client)
 	if err != nil {
-		return fmt.Errorf("failed to create Permit2 contract instance: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to create Permit2 contract instance: %w", err)
 	}
 
 	// Create permit single struct
@@ -220,88 +251,187 @@ func (v *Vault) StakeWithPermit(playerAddress common.Address, gameID uint64, amo
 	// Convert signature to bytes
 	sigBytes := common.FromHex(permitData.Signature)
 
-	// Execute permit
-	permitTx, err := permit2Contract.Permit0(v.auth, permitData.Owner, permitSingle, sigBytes)
+	// Execute permit and wait for it to be mined
+	receipt, err := v.txManager.SendAndWait(func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return permit2Contract.Permit0(opts, permitData.Owner, permitSingle, sigBytes)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to execute permit: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to execute permit: %w", err)
+	}
+
+	if receipt.Status != 1 {
+		return common.Hash{}, fmt.Errorf("permit transaction failed with status: %d", receipt.Status)
 	}
 
-	log.Printf("Permit transaction sent: %s", permitTx.Hash().Hex())
+	log.Printf("Permit of %s USDC for player %s on chain %d confirmed: %s",
+		permitData.Amount.String(), playerAddress.Hex(), v.chainID, receipt.TxHash.Hex())
+	return receipt.TxHash, nil
+}
+
+// TransferRewards transfers rewards from this vault to a recipient on another chain and returns the transaction hash
+func (v *Vault) TransferRewards(gameID uint64, amount *big.Int, toChain uint64, recipient common.Address, useFastTransfer bool, maxFee *big.Int) (common.Hash, error) {
+	log.Printf("Transferring %s USDC rewards from chain %d to chain %d for recipient %s",
+		amount.String(), v.chainID, toChain, recipient.Hex())
 
-	// Wait for permit transaction to be mined
-	permitReceipt, err := bind.WaitMined(context.Background(), v.client, permitTx)
+	// Wait for transaction to be mined
+	receipt, err := v.txManager.SendAndWait(v.transferRewardsBuilder(gameID, amount, toChain, recipient, useFastTransfer, maxFee))
 	if err != nil {
-		return fmt.Errorf("failed to wait for permit transaction to be mined: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to transfer rewards transaction: %w", err)
 	}
 
-	if permitReceipt.Status != 1 {
-		return fmt.Errorf("permit transaction failed with status: %d", permitReceipt.Status)
+	if receipt.Status != 1 {
+		return common.Hash{}, fmt.Errorf("transaction failed with status: %d", receipt.Status)
 	}
 
-	log.Printf("Permit transaction confirmed: %s", permitTx.Hash().Hex())
+	log.Printf("Successfully transferred %s USDC rewards from chain %d to chain %d for recipient %s",
+		amount.String(), v.chainID, toChain, recipient.Hex())
+	return receipt.TxHash, nil
+}
 
-	// Now call the stake function
-	gameIDBig := new(big.Int).SetUint64(gameID)
-	stakeTx, err := v.contract.Stake(v.auth, playerAddress, gameIDBig, amount)
+// SendTransferRewards submits a reward transfer without waiting for it to be mined.
+// The transaction manager keeps replacing it with higher fees while it is stuck.
+// onSigned is called with the signed transaction before it is broadcast, an error aborts the transfer.
+func (v *Vault) SendTransferRewards(gameID uint64, amount *big.Int, toChain uint64, recipient common.Address, useFastTransfer bool, maxFee *big.Int, onSigned func(tx *types.Transaction) error) (*types.Transaction, error) {
+	log.Printf("Transferring %s USDC rewards from chain %d to chain %d for recipient %s",
+		amount.String(), v.chainID, toChain, recipient.Hex())
+
+	tx, err := v.txManager.Submit(v.transferRewardsBuilder(gameID, amount, toChain, recipient, useFastTransfer, maxFee), TxHooks{OnSigned: onSigned})
 	if err != nil {
-		return fmt.Errorf("failed to stake transaction: %w", err)
+		return nil, fmt.Errorf("failed to transfer rewards transaction: %w", err)
 	}
+	return tx, nil
+}
 
-	log.Printf("Stake transaction sent: %s", stakeTx.Hash().Hex())
+// transferRewardsBuilder prepares a transferRewardsCrossChain call
+func (v *Vault) transferRewardsBuilder(gameID uint64, amount *big.Int, toChain uint64, recipient common.Address, useFastTransfer bool, maxFee *big.Int) TxBuilder {
+	gameIDBig := new(big.Int).SetUint64(gameID)
+	toChainIDBig := new(big.Int).SetUint64(toChain)
 
-	// Wait for stake transaction to be mined
-	stakeReceipt, err := bind.WaitMined(context.Background(), v.client, stakeTx)
+	return func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		// Call the transferRewardsCrossChain function
+		return v.contract.TransferRewardsCrossChain(
+			opts,
+			gameIDBig,
+			amount,
+			toChainIDBig,
+			recipient,
+			useFastTransfer,
+			maxFee,
+		)
+	}
+}
+
+// RewardTransferInput is one recipient of a batched reward transfer
+type RewardTransferInput struct {
+	Recipient   common.Address
+	Amount      *big.Int
+	DestChainID uint64
+}
+
+// BatchTransferResult is the outcome of one transfer of a mined reward batch
+type BatchTransferResult struct {
+	Index     int // Position in the submitted batch
+	Succeeded bool
+	Reason    string // Revert reason of a failed transfer
+}
+
+// EstimateTransferRewardsBatch estimates the gas a batched reward transfer needs
+func (v *Vault) EstimateTransferRewardsBatch(gameID uint64, transfers []RewardTransferInput, useFastTransfer bool, maxFee *big.Int) (uint64, error) {
+	parsed, err := vaultcontract.VaultcontractMetaData.GetAbi()
 	if err != nil {
-		return fmt.Errorf("failed to wait for stake transaction to be mined: %w", err)
+		return 0, fmt.Errorf("failed to load vault ABI: %w", err)
 	}
 
-	if stakeReceipt.Status != 1 {
-		return fmt.Errorf("stake transaction failed with status: %d", stakeReceipt.Status)
+	data, err := parsed.Pack("transferRewardsBatch", new(big.Int).SetUint64(gameID), rewardTransferInputs(transfers), useFastTransfer, maxFee)
+	if err != nil {
+		return 0, fmt.Errorf("failed to pack reward batch: %w", err)
 	}
 
-	log.Printf("Successfully staked %s USDC for player %s in game %d on chain %d using Permit2",
-		amount.String(), playerAddress.Hex(), gameID, v.chainID)
-	return nil
+	vaultAddress := common.HexToAddress(GetVaultAddress(v.chainID))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	gas, err := v.client.EstimateGas(ctx, ethereum.CallMsg{
+		From: v.txManager.From(),
+		To:   &vaultAddress,
+		Data: data,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to estimate reward batch gas: %w", err)
+	}
+	return gas, nil
 }
 
-// TransferRewards transfers rewards from this vault to the main vault on Base Sepolia
-func (v *Vault) TransferRewards(gameID uint64, amount *big.Int, toChain uint64, recipient common.Address, useFastTransfer bool, maxFee *big.Int) error {
+// SendTransferRewardsBatch submits one transaction paying several recipients without waiting for it to be mined.
+// Failing recipients are skipped on-chain and reported by TransferBatchResults.
+// onSigned is called with the signed transaction before it is broadcast, an error aborts the transfer.
+func (v *Vault) SendTransferRewardsBatch(gameID uint64, transfers []RewardTransferInput, useFastTransfer bool, maxFee *big.Int, onSigned func(tx *types.Transaction) error) (*types.Transaction, error) {
+	if len(transfers) == 0 {
+		return nil, fmt.Errorf("no reward transfers to send")
+	}
 
-	log.Printf("Transferring %s USDC rewards from chain %d to chain %d for recipient %s",
-		amount.String(), v.chainID, toChain, recipient.Hex())
+	log.Printf("Transferring rewards to %d recipients from chain %d in one batch", len(transfers), v.chainID)
 
 	gameIDBig := new(big.Int).SetUint64(gameID)
-	toChainIDBig := new(big.Int).SetUint64(toChain)
-
-	// Call the transferRewardsCrossChain function
-	tx, err := v.contract.TransferRewardsCrossChain(
-		v.auth,
-		gameIDBig,
-		amount,
-		toChainIDBig,
-		recipient,
-		useFastTransfer,
-		maxFee,
-	)
+	inputs := rewardTransferInputs(transfers)
+	tx, err := v.txManager.Submit(func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return v.contract.TransferRewardsBatch(opts, gameIDBig, inputs, useFastTransfer, maxFee)
+	}, TxHooks{OnSigned: onSigned})
 	if err != nil {
-		return fmt.Errorf("failed to transfer rewards transaction: %w", err)
+		return nil, fmt.Errorf("failed to transfer rewards batch transaction: %w", err)
 	}
+	return tx, nil
+}
 
-	log.Printf("Transfer rewards transaction sent: %s", tx.Hash().Hex())
+// TransferBatchResults reads the per-recipient outcome of a mined reward batch from its events
+func (v *Vault) TransferBatchResults(txHash common.Hash) ([]BatchTransferResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	// Wait for transaction to be mined
-	receipt, err := bind.WaitMined(context.Background(), v.client, tx)
+	receipt, err := v.client.TransactionReceipt(ctx, txHash)
 	if err != nil {
-		return fmt.Errorf("failed to wait for transaction to be mined: %w", err)
+		return nil, fmt.Errorf("failed to get receipt for reward batch %s: %w", txHash.Hex(), err)
 	}
 
-	if receipt.Status != 1 {
-		return fmt.Errorf("transaction failed with status: %d", receipt.Status)
+	var results []BatchTransferResult
+	for _, vLog := range receipt.Logs {
+		if succeeded, err := v.contract.ParseRewardTransferSucceeded(*vLog); err == nil {
+			results = append(results, BatchTransferResult{Index: int(succeeded.Index.Int64()), Succeeded: true})
+			continue
+		}
+		if failed, err := v.contract.ParseRewardTransferFailed(*vLog); err == nil {
+			reason, unpackErr := abi.UnpackRevert(failed.Reason)
+			if unpackErr != nil {
+				reason = common.Bytes2Hex(failed.Reason)
+			}
+			results = append(results, BatchTransferResult{Index: int(failed.Index.Int64()), Reason: reason})
+		}
 	}
+	return results, nil
+}
 
-	log.Printf("Successfully transferred %s USDC rewards from chain %d to chain %d for recipient %s",
-		amount.String(), v.chainID, toChain, recipient.Hex())
-	return nil
+// rewardTransferInputs converts reward transfers to the contract's struct
+func rewardTransferInputs(transfers []RewardTransferInput) []vaultcontract.IVaultContractRewardTransfer {
+	inputs := make([]vaultcontract.IVaultContractRewardTransfer, len(transfers))
+	for i, transfer := range transfers {
+		inputs[i] = vaultcontract.IVaultContractRewardTransfer{
+			Recipient:          transfer.Recipient,
+			Amount:             transfer.Amount,
+			DestinationChainId: new(big.Int).SetUint64(transfer.DestChainID),
+		}
+	}
+	return inputs
+}
+
+// TransactionState reports the state of a transaction previously sent by this vault's signer and
+// the hash of its version that was mined or is current
+func (v *Vault) TransactionState(txHash common.Hash, nonce uint64) (TxState, common.Hash, error) {
+	return v.txManager.TransactionState(txHash, nonce)
+}
+
+// Rebroadcast sends a signed vault transaction again if the node does not know it
+func (v *Vault) Rebroadcast(tx *types.Transaction) error {
+	return v.txManager.Rebroadcast(tx)
 }
 
 // GetTotalStakes returns the total amount staked in this vault
@@ -312,3 +442,16 @@ func (v *Vault) GetTotalStakes() (*big.Int, error) {
 	}
 	return totalStakes, nil
 }
+
+// USDCBalance returns the USDC this vault actually holds, including minted funds not yet counted in its total stakes
+func (v *Vault) USDCBalance() (*big.Int, error) {
+	usdc, err := NewUSDCToken(v.client, v.chainID)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := usdc.BalanceOf(common.HexToAddress(GetVaultAddress(v.chainID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vault USDC balance: %w", err)
+	}
+	return balance, nil
+}
@@ -0,0 +1,123 @@
+package game
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"blockchess/internal/client"
+	"blockchess/internal/money"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Game templates used to select fee overrides
+const (
+	TemplateLobby       = "lobby"
+	TemplateMatchmaking = "matchmaking"
+)
+
+// FeePolicy describes the platform fee taken from a pot before winners are paid
+type FeePolicy struct {
+	BasisPoints int64        // Percentage of the pot in basis points (100 = 1%)
+	Flat        money.Amount // Flat fee added on top of the percentage
+}
+
+// Fee returns the fee for a pot, never more than the pot itself
+func (p FeePolicy) Fee(pot money.Amount) money.Amount {
+	if !pot.IsPositive() {
+		return money.USDC(0)
+	}
+
+	fee, err := pot.MulDiv(p.BasisPoints, 10000)
+	if err != nil {
+		return pot
+	}
+	fee = fee.Add(p.Flat)
+	if fee.Cmp(pot) > 0 {
+		return pot
+	}
+	if !fee.IsPositive() {
+		return money.USDC(0)
+	}
+	return fee
+}
+
+// FeeConfig holds the default fee policy, per-template overrides and the treasury destination
+type FeeConfig struct {
+	Default         FeePolicy
+	Templates       map[string]FeePolicy
+	TreasuryAddress common.Address
+	TreasuryChainID uint64
+}
+
+// LoadFeeConfig loads the fee configuration from environment variables
+func LoadFeeConfig() FeeConfig {
+	config := FeeConfig{
+		Default: FeePolicy{
+			BasisPoints: int64(client.GetEnvInt("FEE_BASIS_POINTS", 0)),
+			Flat:        money.USDC(int64(client.GetEnvInt("FEE_FLAT_UNITS", 0))),
+		},
+		Templates:       parseFeeOverrides(client.GetEnv("FEE_TEMPLATE_OVERRIDES", "")),
+		TreasuryChainID: uint64(client.GetEnvInt("TREASURY_CHAIN_ID", int(client.GameFactoryChainID))),
+	}
+
+	treasury := client.GetEnv("TREASURY_ADDRESS", "")
+	if common.IsHexAddress(treasury) {
+		config.TreasuryAddress = common.HexToAddress(treasury)
+	} else {
+		if treasury != "" {
+			log.Printf("Warning: Invalid TREASURY_ADDRESS %q", treasury)
+		}
+		if config.Default != (FeePolicy{}) || len(config.Templates) > 0 {
+			log.Printf("Warning: No treasury address configured, platform fees are disabled")
+		}
+		config.Default = FeePolicy{}
+		config.Templates = nil
+	}
+
+	return config
+}
+
+// PolicyFor returns the fee policy for a game template
+func (c FeeConfig) PolicyFor(template string) FeePolicy {
+	if policy, ok := c.Templates[template]; ok {
+		return policy
+	}
+	return c.Default
+}
+
+// parseFeeOverrides parses "template=bps:flatUnits" pairs separated by commas
+func parseFeeOverrides(value string) map[string]FeePolicy {
+	overrides := make(map[string]FeePolicy)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		template, spec, ok := strings.Cut(pair, "=")
+		if !ok {
+			log.Printf("Warning: Ignoring malformed fee override %q", pair)
+			continue
+		}
+		bpsValue, flatValue, _ := strings.Cut(spec, ":")
+
+		bps, err := strconv.ParseInt(strings.TrimSpace(bpsValue), 10, 64)
+		if err != nil || bps < 0 || bps > 10000 {
+			log.Printf("Warning: Ignoring fee override %q with invalid basis points", pair)
+			continue
+		}
+		var flat int64
+		if flatValue != "" {
+			flat, err = strconv.ParseInt(strings.TrimSpace(flatValue), 10, 64)
+			if err != nil || flat < 0 {
+				log.Printf("Warning: Ignoring fee override %q with invalid flat fee", pair)
+				continue
+			}
+		}
+
+		overrides[strings.TrimSpace(template)] = FeePolicy{BasisPoints: bps, Flat: money.USDC(flat)}
+	}
+	return overrides
+}
@@ -0,0 +1,29 @@
+package game
+
+import (
+	"testing"
+
+	"blockchess/internal/money"
+)
+
+func TestFeeNeverExceedsPot(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy FeePolicy
+		pot    int64
+		want   int64
+	}{
+		{name: "percentage rounds down", policy: FeePolicy{BasisPoints: 333}, pot: 100001, want: 3330},
+		{name: "percentage and flat", policy: FeePolicy{BasisPoints: 100, Flat: money.USDC(500)}, pot: 200000, want: 2500},
+		{name: "flat capped at the pot", policy: FeePolicy{Flat: money.USDC(500000)}, pot: 200000, want: 200000},
+		{name: "empty pot", policy: FeePolicy{BasisPoints: 500, Flat: money.USDC(500)}, pot: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Fee(money.USDC(tt.pot)); got.Units() != tt.want {
+				t.Fatalf("Fee(%d) = %d units, want %d", tt.pot, got.Units(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"blockchess/internal/client"
+	"blockchess/internal/money"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Funds error codes sent to clients
+const (
+	FundsErrorInsufficient = "insufficient_funds"  // The wallet holds less USDC than the stake
+	FundsErrorAllowance    = "allowance_exhausted" // The vault or Permit2 may not draw the stake, a new permit or approval is needed
+)
+
+// FundsError is a vote refused before staking because the player cannot pay it
+type FundsError struct {
+	Code      string
+	Message   string
+	Required  money.Amount // Stake of the vote
+	Available money.Amount // Balance or allowance the player has
+	TopUp     money.Amount // Amount missing to pay the stake
+}
+
+// Error returns the message of the refusal
+func (e *FundsError) Error() string {
+	return e.Message
+}
+
+// newFundsError creates a FundsError, suggesting the difference between the stake and what is available as top-up
+func newFundsError(code, what string, chainID uint32, required money.Amount, available *big.Int) *FundsError {
+	have, err := client.USDCAmount(uint64(chainID), available)
+	if err != nil {
+		have = money.USDC(0)
+	}
+	topUp := required.Sub(have)
+	action := "top up at least"
+	if code == FundsErrorAllowance {
+		action = "allow at least another"
+	}
+	return &FundsError{
+		Code:      code,
+		Message:   fmt.Sprintf("%s of %s USDC is below the stake of %s USDC - %s %s USDC", what, have, required, action, topUp),
+		Required:  required,
+		Available: have,
+		TopUp:     topUp,
+	}
+}
+
+// fundsCacheEntry is a balance or allowance read from chain
+type fundsCacheEntry struct {
+	value     *big.Int
+	fetchedAt time.Time
+}
+
+// fundsCache keeps balances and allowances briefly so bursts of votes do not each hit the RPC
+type fundsCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]fundsCacheEntry // kind:chainID:wallet -> value
+}
+
+// newFundsCache creates a cache whose entries live FUNDS_CACHE_SECONDS (default 5)
+func newFundsCache() *fundsCache {
+	return &fundsCache{
+		ttl:     time.Duration(client.GetEnvInt("FUNDS_CACHE_SECONDS", 5)) * time.Second,
+		entries: make(map[string]fundsCacheEntry),
+	}
+}
+
+// get returns a cached value, reading and caching it when missing or stale
+func (c *fundsCache) get(key string, read func() (*big.Int, error)) (*big.Int, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Since(entry.fetchedAt) < c.ttl {
+		return entry.value, nil
+	}
+
+	value, err := read()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = fundsCacheEntry{value: value, fetchedAt: time.Now()}
+	c.mu.Unlock()
+	return value, nil
+}
+
+// invalidate drops the cached values of a player on a chain
+func (c *fundsCache) invalidate(chainID uint32, walletAddress string) {
+	suffix := ":" + strconv.FormatUint(uint64(chainID), 10) + ":" + walletAddress
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, kind := range []string{"balance", "allowance", "approval"} {
+		delete(c.entries, kind+suffix)
+	}
+}
+
+// checkFunds refuses a vote whose stake the player's USDC balance or live allowance cannot pay. Failed reads let the
+// vote through, the stake itself is the final check.
+func (m *Manager) checkFunds(walletAddress string, chainID uint32, stake money.Amount, votePermit bool) error {
+	if m.permit2Manager == nil || chainID == 0 {
+		return nil
+	}
+	permit2Client, err := m.permit2Manager.GetPermit2Client(uint64(chainID))
+	if err != nil {
+		return nil
+	}
+	usdc, err := permit2Client.USDC()
+	if err != nil {
+		return nil
+	}
+
+	owner := common.HexToAddress(walletAddress)
+	required, err := client.USDCUnits(uint64(chainID), stake)
+	if err != nil {
+		return err
+	}
+	suffix := ":" + strconv.FormatUint(uint64(chainID), 10) + ":" + walletAddress
+
+	balance, err := m.funds.get("balance"+suffix, func() (*big.Int, error) { return usdc.BalanceOf(owner) })
+	if err != nil {
+		log.Printf("Warning: Failed to read USDC balance of %s on chain %d: %v", walletAddress, chainID, err)
+	} else if balance.Cmp(required) < 0 {
+		return newFundsError(FundsErrorInsufficient, "USDC balance", chainID, stake, balance)
+	}
+
+	// Signature transfers are pulled by Permit2, which needs the player's approval
+	if votePermit {
+		approval, err := m.funds.get("approval"+suffix, func() (*big.Int, error) { return usdc.AllowanceOf(owner, permit2Client.Address()) })
+		if err != nil {
+			log.Printf("Warning: Failed to read Permit2 approval of %s on chain %d: %v", walletAddress, chainID, err)
+		} else if approval.Cmp(required) < 0 {
+			return newFundsError(FundsErrorAllowance, "Permit2 approval", chainID, stake, approval)
+		}
+		return nil
+	}
+
+	// Only a permit that is on-chain has a live allowance, signed ones are checked against their amount when staked
+	permit := m.playerPermitFor(walletAddress)
+	if permit == nil {
+		return nil
+	}
+	permit.mu.Lock()
+	data, active := permit.data, permit.state == PermitActive
+	permit.mu.Unlock()
+	if !active {
+		return nil
+	}
+
+	allowance, err := m.funds.get("allowance"+suffix, func() (*big.Int, error) {
+		live, err := m.readAllowance(data)
+		if err != nil {
+			return nil, err
+		}
+		if live.Expiration.Int64() <= time.Now().Unix() {
+			return new(big.Int), nil
+		}
+		return live.Amount, nil
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to read allowance of %s on chain %d: %v", walletAddress, chainID, err)
+	} else if allowance.Cmp(required) < 0 {
+		// Track the live allowance so the permit counts as exhausted and the player is asked for a new one
+		permit.mu.Lock()
+		if permit.state == PermitActive && permit.data == data {
+			permit.remaining = new(big.Int).Set(allowance)
+		}
+		permit.mu.Unlock()
+		return newFundsError(FundsErrorAllowance, "Remaining allowance", chainID, stake, allowance)
+	}
+	return nil
+}
@@ -1,10 +1,14 @@
 package game
 
 import (
+	"blockchess/internal/cctp"
 	"blockchess/internal/client"
+	"blockchess/internal/ledger"
+	"blockchess/internal/money"
 	"fmt"
 	"log"
-	"math/big"
+	"maps"
+	"math/rand/v2"
 	"strings"
 	"sync"
 	"time"
@@ -16,10 +20,34 @@ import (
 
 // Constants
 const (
-	GameTimerSeconds = 15   // Timer duration in seconds for each turn
-	StakeAmount      = 0.01 // 0.01 USDC
+	GameTimerSeconds = 15    // Timer duration in seconds for each turn
+	StakeUnits       = 10000 // 0.01 USDC in USDC's 6 decimal places
+	BotAddressPrefix = "bot_"
+	TeamAuto         = "auto" // Join the smaller or weaker team
 )
 
+// GameOptions configures a newly created game
+type GameOptions struct {
+	TurnSeconds  int          // Timer duration in seconds for each turn
+	Stake        money.Amount // Stake per vote
+	MaxTeamSize  int          // Maximum players per team, 0 for unlimited
+	MaxImbalance int          // Maximum player difference between teams, 0 for unlimited
+	TeamLockMove int          // Move number from which teams no longer accept players, 0 to never lock
+	Template     string       // Game template used to select the fee policy
+}
+
+// DefaultGameOptions returns the options used for games created without explicit settings
+func DefaultGameOptions() GameOptions {
+	return GameOptions{
+		TurnSeconds:  GameTimerSeconds,
+		Stake:        money.USDC(StakeUnits),
+		MaxTeamSize:  client.GetEnvInt("GAME_MAX_TEAM_SIZE", 0),
+		MaxImbalance: client.GetEnvInt("GAME_MAX_TEAM_IMBALANCE", 2),
+		TeamLockMove: client.GetEnvInt("GAME_TEAM_LOCK_MOVE", 0),
+		Template:     TemplateLobby,
+	}
+}
+
 type Vote struct {
 	Move  string
 	Count int
@@ -33,15 +61,31 @@ type GameState struct {
 	Players     []string       // connected player IDs
 	CurrentMove int            // Current move number
 	CreatedAt   int64          // Unix timestamp when game was created
+	TurnSeconds int            // Timer duration in seconds for each turn
+	Stake       money.Amount   // Stake per vote
+	Template    string         // Game template used to select the fee policy
+
+	// Team limits
+	MaxTeamSize  int // Maximum players per team, 0 for unlimited
+	MaxImbalance int // Maximum player difference between teams, 0 for unlimited
+	TeamLockMove int // Move number from which teams no longer accept players, 0 to never lock
+
+	// Bot players - botID -> true, bots are also listed in the team maps
+	BotPlayers map[string]bool
+
+	// Abnormal endings that refund stakes instead of paying winners
+	Aborted   bool // A team never voted before the game ended
+	Cancelled bool // An operator cancelled the game
 
 	// Team tracking with wallet addresses
 	WhitePlayers map[string]bool // walletAddress -> true if on white team
 	BlackPlayers map[string]bool // walletAddress -> true if on black team
 
 	// Pot tracking
-	TotalPot float64
-	WhitePot float64
-	BlackPot float64
+	TotalPot    money.Amount
+	WhitePot    money.Amount
+	BlackPot    money.Amount
+	ChainStakes map[uint64]money.Amount // chainID -> amount staked in that chain's vault
 
 	// Vote tracking per round
 	WhiteVotesThisTurn   int
@@ -49,9 +93,11 @@ type GameState struct {
 	PlayerVotedThisRound map[string]bool // Track who has voted this round (by wallet address)
 
 	// Total vote tracking (persistent across all rounds)
-	WhiteTeamTotalVotes int
-	BlackTeamTotalVotes int
-	PlayerTotalVotes    map[string]int // walletAddress -> total votes made throughout the game
+	WhiteTeamTotalVotes   int
+	BlackTeamTotalVotes   int
+	PlayerTotalVotes      map[string]int          // walletAddress -> total votes made throughout the game
+	PlayerTotalSpent      map[string]money.Amount // walletAddress -> total staked throughout the game
+	PlayerConfirmedStakes map[string]money.Amount // walletAddress -> stakes mined into the vaults, the only ones paid out
 
 	// Blockchain integration
 	BlockchainGameID uint64 // Game ID from the smart contract
@@ -66,34 +112,67 @@ type Manager struct {
 	moveResultCallback func(gameID, move string)
 	gameEndCallback    func(gameID, winner, reason string, gameStats map[string]any)
 
+	// Votes waiting for their on-chain stake, and who to tell when one is rejected
+	stakes               *stakeOutbox
+	voteRejectedCallback func(rejection VoteRejection)
+
+	// Staked votes waiting to be recorded on-chain, batched per round
+	votes           *voteBatcher
+	voteBatchConfig VoteBatchConfig
+
 	// Blockchain clients for multi-chain operations
 	clients        *client.Clients
 	gameFactory    *client.GameFactory
 	vaultManager   *client.VaultManager
 	permit2Manager *client.Permit2Manager
 
+	// Accounting ledger for stakes and settlements
+	ledger *ledger.Ledger
+
+	// Relays CCTP burns made by settlements to their destination chains
+	relayer *cctp.Relayer
+
+	// Platform fee policy and treasury destination
+	fees FeeConfig
+
+	// Refund rules for draws, aborts, cancellations and failed settlements
+	refunds RefundConfig
+
+	// Persisted end-of-game settlement jobs and their retry policy
+	settlements      *settlementStore
+	settlementConfig SettlementConfig
+
 	// Player chain ID mapping - walletAddress -> chainID
 	playerChainIDs map[string]uint32
 	chainIDMutex   sync.RWMutex
 
-	// Player permit signatures - walletAddress -> permit signature data
-	playerPermits map[string]*client.PermitSignatureData
+	// Player permits and the allowance they granted - walletAddress -> permit
+	playerPermits map[string]*playerPermit
 	permitMutex   sync.RWMutex
+
+	// One-shot vote permits - gameID:walletAddress -> transfer, and the wallets that stake with them
+	votePermits       map[string]*client.PermitTransferData
+	votePermitWallets map[string]bool
+
+	// Recently read USDC balances and allowances checked before votes are accepted
+	funds *fundsCache
 }
 
-func NewGamesManager(clients *client.Clients) *Manager {
+func NewGamesManager(clients *client.Clients, gameLedger *ledger.Ledger, relayer *cctp.Relayer) *Manager {
 	// Initialize GameFactory with Base Sepolia client
 	var gameFactory *client.GameFactory
-	baseSepoliaChainID := uint64(84532)
+	baseSepoliaChainID := client.GameFactoryChainID
 	baseSepoliaClient, err := clients.GetClientByChainID(baseSepoliaChainID)
 	if err != nil {
 		log.Printf("Warning: Failed to get Base Sepolia client: %v", err)
+	} else if reason, disabled := clients.DisabledReason(baseSepoliaChainID); disabled {
+		log.Printf("Warning: GameFactory chain disabled by preflight: %s", reason)
 	} else {
-		privateKey := clients.GetPrivateKey()
-		if privateKey == "" {
-			log.Printf("Warning: No private key available for GameFactory")
+		txManager, err := clients.GetTxManager(baseSepoliaChainID)
+		if err != nil {
+			log.Printf("Warning: No transaction manager available for GameFactory: %v", err)
 		} else {
-			gameFactory, err = client.NewGameFactory(baseSepoliaClient, privateKey)
+			gameFactory, err = client.NewGameFactory(baseSepoliaClient, txManager)
 			if err != nil {
 				log.Printf("Warning: Failed to initialize GameFactory: %v", err)
 			}
@@ -118,15 +197,47 @@ func NewGamesManager(clients *client.Clients) *Manager {
 		log.Printf("Permit2Manager initialized with %d chains: %v", len(availableChains), availableChains)
 	}
 
-	return &Manager{
-		games:          make(map[string]*GameState),
-		clients:        clients,
-		gameFactory:    gameFactory,
-		vaultManager:   vaultManager,
-		permit2Manager: permit2Manager,
-		playerChainIDs: make(map[string]uint32),
-		playerPermits:  make(map[string]*client.PermitSignatureData),
+	settlements, err := newSettlementStore(client.GetEnv("SETTLEMENT_PATH", "data/settlements.json"))
+	if err != nil {
+		log.Printf("Warning: Failed to load settlement jobs, keeping them in memory: %v", err)
+		settlements, _ = newSettlementStore("")
 	}
+
+	manager := &Manager{
+		games:            make(map[string]*GameState),
+		stakes:           newStakeOutbox(),
+		votes:            newVoteBatcher(),
+		voteBatchConfig:  LoadVoteBatchConfig(),
+		clients:          clients,
+		gameFactory:      gameFactory,
+		vaultManager:     vaultManager,
+		permit2Manager:   permit2Manager,
+		ledger:           gameLedger,
+		relayer:          relayer,
+		fees:             LoadFeeConfig(),
+		refunds:          LoadRefundConfig(),
+		settlements:      settlements,
+		settlementConfig: LoadSettlementConfig(),
+		playerChainIDs:   make(map[string]uint32),
+		playerPermits:    make(map[string]*playerPermit),
+
+		votePermits:       make(map[string]*client.PermitTransferData),
+		votePermitWallets: make(map[string]bool),
+		funds:             newFundsCache(),
+	}
+
+	// Send stakes of optimistically accepted votes
+	for range client.GetEnvInt("STAKE_WORKERS", 4) {
+		go manager.runStakeWorker()
+	}
+
+	// Return stakes of games interrupted by a previous shutdown or crash
+	go manager.refundOpenGames()
+
+	// Resume settlements left pending by a previous run and retry failed steps
+	go manager.runSettlements()
+
+	return manager
 }
 
 // SetMoveResultCallback sets the callback for broadcasting move results
@@ -154,19 +265,59 @@ func (m *Manager) GetPlayerChainID(walletAddress string) uint32 {
 	return m.playerChainIDs[walletAddress]
 }
 
-// StorePlayerPermit stores a permit signature for a player
+// StorePlayerPermit stores a permit for a player, replacing their previous permit and its allowance
 func (m *Manager) StorePlayerPermit(walletAddress string, permitData *client.PermitSignatureData) {
 	m.permitMutex.Lock()
 	defer m.permitMutex.Unlock()
-	m.playerPermits[walletAddress] = permitData
+	m.playerPermits[walletAddress] = newPlayerPermit(permitData)
 	log.Printf("Stored permit signature for player %s on chain %d", walletAddress, permitData.ChainID)
 }
 
 // GetPlayerPermit retrieves a permit signature for a player
 func (m *Manager) GetPlayerPermit(walletAddress string) *client.PermitSignatureData {
-	m.permitMutex.RLock()
-	defer m.permitMutex.RUnlock()
-	return m.playerPermits[walletAddress]
+	permit := m.playerPermitFor(walletAddress)
+	if permit == nil {
+		return nil
+	}
+
+	permit.mu.Lock()
+	defer permit.mu.Unlock()
+	return permit.data
+}
+
+// PermitError is a rejected permit signature carrying a code for the client
+type PermitError = client.PermitError
+
+// SubmitPermitSignature verifies a player's signature against the permit they requested and stores it.
+// Rejections are *PermitError values.
+func (m *Manager) SubmitPermitSignature(walletAddress string, chainID uint32, signature string) error {
+	permitData := m.GetPlayerPermit(walletAddress)
+	if permitData == nil {
+		return &PermitError{Code: client.PermitErrorNotFound, Message: "No permit data found. Please request a new permit."}
+	}
+	if permitData.ChainID != uint64(chainID) {
+		return &PermitError{
+			Code:    client.PermitErrorChainMismatch,
+			Message: fmt.Sprintf("Permit is for chain %d, not chain %d", permitData.ChainID, chainID),
+		}
+	}
+	if m.permit2Manager == nil {
+		return &PermitError{Code: client.PermitErrorInvalid, Message: "Permit2 manager not available"}
+	}
+
+	permit2Client, err := m.permit2Manager.GetPermit2Client(permitData.ChainID)
+	if err != nil {
+		return &PermitError{Code: client.PermitErrorInvalid, Message: err.Error()}
+	}
+	if err := permit2Client.VerifyPermitSignature(permitData, signature); err != nil {
+		return err
+	}
+
+	// Store a copy so a concurrent reader never sees a half-updated permit
+	signed := *permitData
+	signed.Signature = signature
+	m.StorePlayerPermit(walletAddress, &signed)
+	return nil
 }
 
 // CreatePermitForPlayer creates a permit signature request for a player
@@ -200,55 +351,14 @@ func (m *Manager) CreatePermitForPlayer(walletAddress string, chainID uint32) (*
 	return permitData, typedData, nil
 }
 
-// HasValidPermit checks if a player has a valid permit signature
+// HasValidPermit checks if a player has a permit that can pay a default stake without a new signature
 func (m *Manager) HasValidPermit(walletAddress string, chainID uint32) bool {
-	permitData := m.GetPlayerPermit(walletAddress)
-	if permitData == nil {
-		return false
-	}
-
-	// Check if the permit is for the correct chain
-	if permitData.ChainID != uint64(chainID) {
-		return false
-	}
-
-	// Check if the permit signature is present
-	if permitData.Signature == "" {
-		return false
-	}
-
-	// Check if the permit hasn't expired (signature deadline)
-	now := big.NewInt(time.Now().Unix())
-	if permitData.SigDeadline.Cmp(now) <= 0 {
-		log.Printf("Permit expired for player %s on chain %d", walletAddress, chainID)
-		return false
-	}
-
-	return true
+	return m.checkPermit(walletAddress, chainID, DefaultGameOptions().Stake) == nil
 }
 
-// EnsurePlayerPermit ensures a player has a valid permit for their chain
-func (m *Manager) EnsurePlayerPermit(walletAddress string, chainID uint32) error {
-	if m.HasValidPermit(walletAddress, chainID) {
-		return nil // Already has valid permit
-	}
-
-	// Check if we have permit data but it's invalid
-	permitData := m.GetPlayerPermit(walletAddress)
-	if permitData != nil {
-		if permitData.ChainID != uint64(chainID) {
-			return fmt.Errorf("permit is for chain %d, but player is on chain %d", permitData.ChainID, chainID)
-		}
-		if permitData.Signature == "" {
-			return fmt.Errorf("permit signature is missing - please sign the permit")
-		}
-		now := big.NewInt(time.Now().Unix())
-		if permitData.SigDeadline.Cmp(now) <= 0 {
-			return fmt.Errorf("permit has expired - please request a new permit")
-		}
-	}
-
-	return fmt.Errorf("no permit found - please sign permit before voting")
+// EnsurePlayerPermit ensures a player has a permit for their chain that can pay the given stake
+func (m *Manager) EnsurePlayerPermit(walletAddress string, chainID uint32, stake money.Amount) error {
+	return m.checkPermit(walletAddress, chainID, stake)
 }
 
 // GetOrCreatePlayerPermit gets existing permit or creates a new one if needed
@@ -264,55 +374,82 @@ func (m *Manager) GetOrCreatePlayerPermit(walletAddress string, chainID uint32)
 }
 
 // GetOrCreateGame gets an existing game or creates a new one
-func (m *Manager) GetOrCreateGame() *GameState {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+func (m *Manager) GetOrCreateGame() (*GameState, error) {
+	return m.CreateGame(DefaultGameOptions())
+}
+
+// CreateGame creates a new game with the given options and starts its timer. The game contract is created
+// on-chain first, so the call blocks until that transaction is mined.
+func (m *Manager) CreateGame(options GameOptions) (*GameState, error) {
+	defaults := DefaultGameOptions()
+	if options.TurnSeconds <= 0 {
+		options.TurnSeconds = defaults.TurnSeconds
+	}
+	if !options.Stake.IsPositive() {
+		options.Stake = defaults.Stake
+	}
+	if options.Template == "" {
+		options.Template = defaults.Template
+	}
 
 	// Generate a unique game ID
 	gameID := uuid.New().String()
 
 	game := &GameState{
-		ID:                   gameID,
-		Votes:                make(map[string]int),
-		TimeLeft:             GameTimerSeconds, // Use constant for timer duration
-		Game:                 chess.NewGame(),
-		Players:              make([]string, 0),
-		CurrentMove:          1,
-		CreatedAt:            time.Now().Unix(),
-		WhitePlayers:         make(map[string]bool),
-		BlackPlayers:         make(map[string]bool),
-		TotalPot:             0,
-		WhitePot:             0,
-		BlackPot:             0,
-		WhiteVotesThisTurn:   0,
-		BlackVotesThisTurn:   0,
-		WhiteTeamTotalVotes:  0,
-		BlackTeamTotalVotes:  0,
-		PlayerVotedThisRound: make(map[string]bool),
-		PlayerTotalVotes:     make(map[string]int),
+		ID:                    gameID,
+		Votes:                 make(map[string]int),
+		TimeLeft:              options.TurnSeconds,
+		Game:                  chess.NewGame(),
+		Players:               make([]string, 0),
+		CurrentMove:           1,
+		CreatedAt:             time.Now().Unix(),
+		TurnSeconds:           options.TurnSeconds,
+		Stake:                 options.Stake,
+		Template:              options.Template,
+		MaxTeamSize:           options.MaxTeamSize,
+		MaxImbalance:          options.MaxImbalance,
+		TeamLockMove:          options.TeamLockMove,
+		BotPlayers:            make(map[string]bool),
+		WhitePlayers:          make(map[string]bool),
+		BlackPlayers:          make(map[string]bool),
+		TotalPot:              money.USDC(0),
+		WhitePot:              money.USDC(0),
+		BlackPot:              money.USDC(0),
+		WhiteVotesThisTurn:    0,
+		BlackVotesThisTurn:    0,
+		WhiteTeamTotalVotes:   0,
+		BlackTeamTotalVotes:   0,
+		PlayerVotedThisRound:  make(map[string]bool),
+		PlayerTotalVotes:      make(map[string]int),
+		PlayerTotalSpent:      make(map[string]money.Amount),
+		PlayerConfirmedStakes: make(map[string]money.Amount),
+		ChainStakes:           make(map[uint64]money.Amount),
 	}
 
 	// Create blockchain game if GameFactory is available
 	if m.gameFactory != nil {
-		// Use fixed stake amount of 0.01 USDC (converted to wei: 0.01 * 10^6)
-		stakeAmount := new(big.Int).SetInt64(10000) // 0.01 USDC in USDC's 6 decimal places
+		stakeAmount, err := client.USDCUnits(client.GameFactoryChainID, options.Stake)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert stake: %w", err)
+		}
 		blockchainGameID, err := m.gameFactory.CreateGame(stakeAmount)
 		if err != nil {
-			log.Fatalf("Failed to create game contract: %v", err)
-		} else {
-			game.BlockchainGameID = blockchainGameID
-			log.Printf("Created game contract with ID: %d for local game: %s", blockchainGameID, gameID)
+			return nil, fmt.Errorf("failed to create game contract: %w", err)
 		}
+		game.BlockchainGameID = blockchainGameID
+		log.Printf("Created game contract with ID: %d for local game: %s", blockchainGameID, gameID)
 	} else {
 		log.Printf("Deployment by base")
 	}
 
+	m.mu.Lock()
 	m.games[game.ID] = game
+	m.mu.Unlock()
 
 	// Start game timer
 	go m.runGameTimer(game)
 
-	return game
+	return game, nil
 }
 
 // GetGame retrieves an existing game without creating it
@@ -334,11 +471,19 @@ func (m *Manager) runGameTimer(game *GameState) {
 
 	for range ticker.C {
 		game.mu.Lock()
+		if game.Cancelled {
+			game.mu.Unlock()
+			log.Printf("Game %s timer stopped due to cancellation.", game.ID)
+			return
+		}
 		game.TimeLeft--
 
+		// Let bots on the current team cast their vote
+		m.castBotVotes(game)
+
 		// Check if we should execute the move early (when only 1 player on current team)
 		shouldExecuteEarly := false
-		if game.TimeLeft <= GameTimerSeconds-1 { // After 1 second (started at GameTimerSeconds)
+		if game.TimeLeft <= game.TurnSeconds-1 { // After 1 second (started at TurnSeconds)
 			currentTurn := game.Game.Position().Turn()
 			var currentTeamPlayerCount int
 			if currentTurn == chess.White {
@@ -363,6 +508,12 @@ func (m *Manager) runGameTimer(game *GameState) {
 				game.Game.Resign(currentTurn) // This sets the game outcome
 				log.Printf("Game %s: Team %s forfeited due to inactivity.", game.ID, currentTurn.String())
 
+				// A game where one team never voted never really started
+				if game.WhiteTeamTotalVotes == 0 || game.BlackTeamTotalVotes == 0 {
+					game.Aborted = true
+					log.Printf("Game %s: Aborted because a team never voted.", game.ID)
+				}
+
 				// Get final stats before unlocking
 				gameStats := m.getGameStatsUnsafe(game, true)
 				game.mu.Unlock()
@@ -379,9 +530,12 @@ func (m *Manager) runGameTimer(game *GameState) {
 			// Check if the game ended after this move
 			gameEnded := m.checkGameEnd(game)
 
+			// Record the closing round's staked votes on-chain in one batch
+			m.flushVotes(game.ID, game.BlockchainGameID, game.CurrentMove)
+
 			// Reset for next turn immediately to prevent race conditions
 			game.Votes = make(map[string]int)
-			game.TimeLeft = GameTimerSeconds // Reset to constant value
+			game.TimeLeft = game.TurnSeconds
 			game.CurrentMove++
 
 			// Reset round vote tracking
@@ -433,9 +587,12 @@ func (m *Manager) handleGameEnd(gameID string, gameStats map[string]any) {
 	outcome := game.Game.Outcome()
 	method := game.Game.Method()
 	blockchainGameID := game.BlockchainGameID
+	template := game.Template
+	aborted := game.Aborted
+	cancelled := game.Cancelled
 	game.mu.RUnlock()
 
-	if outcome == chess.NoOutcome {
+	if outcome == chess.NoOutcome && !cancelled {
 		return
 	}
 
@@ -473,24 +630,54 @@ func (m *Manager) handleGameEnd(gameID string, gameStats map[string]any) {
 		}
 	}
 
-	// Distribute rewards to winners before ending the game
-	if m.vaultManager != nil && blockchainGameID != 0 {
-		m.distributeRewards(gameID, winner, gameStats)
+	// Aborted and cancelled games are refunded, on-chain they end as a draw
+	chainResult := winner
+	switch {
+	case cancelled:
+		winner, reason, chainResult = "cancelled", "cancelled", "draw"
+	case aborted:
+		winner, reason, chainResult = "aborted", "aborted", "draw"
 	}
 
-	// End the blockchain game if available
-	if m.gameFactory != nil && blockchainGameID != 0 {
-		result, err := client.ResultStringToUint8(winner)
-		if err != nil {
-			log.Printf("Warning: Failed to convert result '%s' to uint8: %v", winner, err)
-		} else {
-			err = m.gameFactory.EndGame(blockchainGameID, result)
-			if err != nil {
-				log.Printf("Warning: Failed to end blockchain game %d: %v", blockchainGameID, err)
-			} else {
-				log.Printf("Successfully ended blockchain game %d with result: %s", blockchainGameID, winner)
-			}
+	// Settle only once every accepted vote's stake is mined or rolled back
+	if m.stakes.pending(gameID) > 0 {
+		log.Printf("Game %s waiting for %d stakes before settling", gameID, m.stakes.pending(gameID))
+		if !m.stakes.waitForGame(gameID, stakeDrainTimeout) {
+			log.Printf("Warning: Game %s still has %d stakes in flight after %s, settling without them",
+				gameID, m.stakes.pending(gameID), stakeDrainTimeout)
 		}
+		game.mu.RLock()
+		maps.Copy(gameStats, m.getGameStatsUnsafe(game, true))
+		game.mu.RUnlock()
+	}
+
+	// Record the votes staked since the last round closed and wait for every batch
+	game.mu.RLock()
+	round := game.CurrentMove
+	game.mu.RUnlock()
+	m.flushVotes(gameID, blockchainGameID, round)
+	if !m.votes.waitForGame(gameID, voteFlushTimeout) {
+		log.Printf("Warning: Vote batches of game %s still pending after %s", gameID, voteFlushTimeout)
+	}
+	voteBatches := m.votes.finish(gameID)
+
+	// Split the platform fee out of the stakes mined into the vaults, unconfirmed stakes are never paid out
+	confirmedPot := confirmedPot(gameStats)
+	fee, netPayout := money.USDC(0), money.USDC(0)
+	if (winner == "white" || winner == "black") && winnersStaked(winner, gameStats) {
+		fee = m.fees.PolicyFor(template).Fee(confirmedPot)
+		netPayout = confirmedPot.Sub(fee)
+	}
+	gameStats["fee"] = fee
+	gameStats["netPayout"] = netPayout
+
+	// Pay out or refund and end the blockchain game through a persisted settlement job
+	if blockchainGameID != 0 && (m.vaultManager != nil || m.gameFactory != nil) {
+		job := m.planSettlement(gameID, blockchainGameID, winner, chainResult, gameStats)
+		job.VoteBatches = voteBatches
+		job = m.startSettlement(job)
+		gameStats["refunds"] = job.Refunds()
+		gameStats["settlementStatus"] = string(job.Status)
 	}
 
 	// Broadcast game end
@@ -528,14 +715,14 @@ func (m *Manager) VoteForMove(gameID, walletAddress, move string, team string, c
 		return fmt.Errorf("game not found: %s", gameID)
 	}
 
-	game.mu.Lock()
-	defer game.mu.Unlock()
-
 	// Validate wallet address
 	if walletAddress == "" {
 		return fmt.Errorf("wallet address cannot be empty")
 	}
 
+	game.mu.Lock()
+	defer game.mu.Unlock()
+
 	// Check if player is on the team they're trying to vote for
 	switch team {
 	case "white":
@@ -560,50 +747,39 @@ func (m *Manager) VoteForMove(gameID, walletAddress, move string, team string, c
 		return fmt.Errorf("invalid move: %s", move)
 	}
 
-	// MANDATORY: Ensure player has valid permit before allowing vote
+	// MANDATORY: Ensure player has a signed vote permit or a permit with allowance before allowing vote
+	var transfer *client.PermitTransferData
 	if chainId != 0 {
-		err := m.EnsurePlayerPermit(walletAddress, chainId)
-		if err != nil {
-			return fmt.Errorf("permit required for voting: %w", err)
+		var err error
+		transfer, err = m.takeVotePermit(gameID, walletAddress, chainId, game.Stake)
+		if err == nil && transfer == nil {
+			err = m.EnsurePlayerPermit(walletAddress, chainId, game.Stake)
 		}
-	}
-
-	// Stake USDC to vault contract on player's chain using stored permit
-	if m.vaultManager != nil && chainId != 0 {
-		vault, err := m.vaultManager.GetVault(uint64(chainId))
 		if err != nil {
-			log.Printf("Warning: Failed to get vault for chain %d: %v", chainId, err)
-		} else {
-			playerAddress := common.HexToAddress(walletAddress)
-			stakeAmount := new(big.Int).SetInt64(10000) // 0.01 USDC in USDC's 6 decimal places
-
-			// Get the stored permit (we already validated it exists above)
-			permitData := m.GetPlayerPermit(walletAddress)
-			log.Printf("Using stored permit for player %s on chain %d", walletAddress, chainId)
-			err = vault.StakeWithPermit(playerAddress, game.BlockchainGameID, stakeAmount, permitData)
-			if err != nil {
-				log.Printf("Error: Failed to stake with permit to vault on chain %d: %v", chainId, err)
-				return fmt.Errorf("staking with permit failed: %w", err)
-			} else {
-				log.Printf("Successfully staked 0.01 USDC for player %s on chain %d using Permit2", walletAddress, chainId)
-			}
+			return fmt.Errorf("permit required for voting: %w", err)
 		}
 	}
 
-	// Add vote to blockchain if available
-	if m.gameFactory != nil && game.BlockchainGameID != 0 {
-		teamUint8, err := client.TeamStringToUint8(team)
+	// Stake and record the vote on-chain in the background so slow RPCs never hold up the game
+	needsStake := m.vaultManager != nil && chainId != 0
+	needsChainVote := m.gameFactory != nil && game.BlockchainGameID != 0
+	if needsStake || needsChainVote {
+		err := m.stakes.enqueue(pendingStake{
+			GameID:    gameID,
+			ChainGame: game.BlockchainGameID,
+			Wallet:    walletAddress,
+			Move:      move,
+			Team:      team,
+			ChainID:   chainId,
+			Round:     game.CurrentMove,
+			Amount:    game.Stake,
+			Transfer:  transfer,
+		})
 		if err != nil {
-			log.Printf("Warning: Failed to convert team '%s' to uint8: %v", team, err)
-		} else {
-			playerAddress := common.HexToAddress(walletAddress)
-			err = m.gameFactory.AddVote(game.BlockchainGameID, playerAddress, chainId, teamUint8)
-			if err != nil {
-				log.Printf("Warning: Failed to add vote to blockchain: %v", err)
-				// Continue with local vote even if blockchain fails
-			} else {
-				log.Printf("Successfully added vote to blockchain for player %s", walletAddress)
+			if transfer != nil {
+				m.releaseVotePermitNonce(transfer)
 			}
+			return err
 		}
 	}
 
@@ -614,22 +790,23 @@ func (m *Manager) VoteForMove(gameID, walletAddress, move string, team string, c
 	game.Votes[move]++
 	game.PlayerVotedThisRound[walletAddress] = true
 	game.PlayerTotalVotes[walletAddress]++
+	game.PlayerTotalSpent[walletAddress] = game.PlayerTotalSpent[walletAddress].Add(game.Stake)
 
 	// Update team vote count and pot
 	switch team {
 	case "white":
 		game.WhiteVotesThisTurn++
 		game.WhiteTeamTotalVotes++
-		game.WhitePot += 0.01
-		game.TotalPot += 0.01
+		game.WhitePot = game.WhitePot.Add(game.Stake)
+		game.TotalPot = game.TotalPot.Add(game.Stake)
 	case "black":
 		game.BlackVotesThisTurn++
 		game.BlackTeamTotalVotes++
-		game.BlackPot += 0.01
-		game.TotalPot += 0.01
+		game.BlackPot = game.BlackPot.Add(game.Stake)
+		game.TotalPot = game.TotalPot.Add(game.Stake)
 	}
 
-	log.Printf("Player %s voted for move %s in team %s (game %s)", walletAddress, move, team, gameID)
+	log.Printf("Player %s voted for move %s in team %s (game %s), stake pending", walletAddress, move, team, gameID)
 	return nil
 }
 
@@ -752,41 +929,50 @@ func (m *Manager) BroadcastMoveResult(gameID, move string) {
 	}
 }
 
-// AddPlayerToTeam adds a player to a team with wallet address validation
-func (m *Manager) AddPlayerToTeam(gameID, walletAddress, team string) error {
+// AddPlayerToTeam adds a player to a team with wallet address validation and returns the team joined.
+// Passing TeamAuto places the player on the smaller team, or the weaker one when sizes are equal.
+func (m *Manager) AddPlayerToTeam(gameID, walletAddress, team string) (string, error) {
 	m.mu.RLock()
 	game, exists := m.games[gameID]
 	m.mu.RUnlock()
 
 	if !exists {
-		return fmt.Errorf("game not found: %s", gameID)
+		return "", fmt.Errorf("game not found: %s", gameID)
 	}
 
 	// Validate wallet address
 	if walletAddress == "" {
-		return fmt.Errorf("wallet address cannot be empty")
+		return "", fmt.Errorf("wallet address cannot be empty")
 	}
 
 	if len(walletAddress) != 42 || !strings.HasPrefix(walletAddress, "0x") {
-		return fmt.Errorf("invalid wallet address format")
+		return "", fmt.Errorf("invalid wallet address format")
 	}
 
 	game.mu.Lock()
 	defer game.mu.Unlock()
 
+	if team == TeamAuto {
+		team = m.pickAutoTeam(game)
+	}
+
 	// Check if player is already on any team
 	if game.WhitePlayers[walletAddress] {
 		if team == "white" {
-			return fmt.Errorf("player already on white team")
+			return "", fmt.Errorf("player already on white team")
 		}
-		return fmt.Errorf("player cannot join black team - already on white team")
+		return "", fmt.Errorf("player cannot join black team - already on white team")
 	}
 
 	if game.BlackPlayers[walletAddress] {
 		if team == "black" {
-			return fmt.Errorf("player already on black team")
+			return "", fmt.Errorf("player already on black team")
 		}
-		return fmt.Errorf("player cannot join white team - already on black team")
+		return "", fmt.Errorf("player cannot join white team - already on black team")
+	}
+
+	if err := m.checkTeamLimits(game, team); err != nil {
+		return "", err
 	}
 
 	// Add to requested team
@@ -797,13 +983,167 @@ func (m *Manager) AddPlayerToTeam(gameID, walletAddress, team string) error {
 	case "black":
 		game.BlackPlayers[walletAddress] = true
 		log.Printf("Player %s joined black team in game %s", walletAddress, gameID)
+	default:
+		return "", fmt.Errorf("invalid team: %s", team)
+	}
+
+	return team, nil
+}
+
+// checkTeamLimits verifies a new player may join a team (caller must hold the lock)
+func (m *Manager) checkTeamLimits(game *GameState, team string) error {
+	if m.teamsLockedUnsafe(game) {
+		return fmt.Errorf("teams are locked from move %d", game.TeamLockMove)
+	}
+
+	var teamSize, otherSize int
+	switch team {
+	case "white":
+		teamSize, otherSize = len(game.WhitePlayers), len(game.BlackPlayers)
+	case "black":
+		teamSize, otherSize = len(game.BlackPlayers), len(game.WhitePlayers)
 	default:
 		return fmt.Errorf("invalid team: %s", team)
 	}
 
+	if game.MaxTeamSize > 0 && teamSize >= game.MaxTeamSize {
+		return fmt.Errorf("%s team is full (%d players)", team, game.MaxTeamSize)
+	}
+
+	if game.MaxImbalance > 0 && teamSize+1-otherSize > game.MaxImbalance {
+		return fmt.Errorf("%s team would have %d more players than the other team (max %d)",
+			team, teamSize+1-otherSize, game.MaxImbalance)
+	}
+
 	return nil
 }
 
+// pickAutoTeam returns the smaller team, or the one with less material when sizes are equal (caller must hold the lock)
+func (m *Manager) pickAutoTeam(game *GameState) string {
+	whiteSize, blackSize := len(game.WhitePlayers), len(game.BlackPlayers)
+	if whiteSize != blackSize {
+		if whiteSize < blackSize {
+			return "white"
+		}
+		return "black"
+	}
+
+	if materialScore(game.Game.Position(), chess.Black) < materialScore(game.Game.Position(), chess.White) {
+		return "black"
+	}
+	return "white"
+}
+
+// materialScore returns the standard piece value total for one color
+func materialScore(position *chess.Position, color chess.Color) int {
+	pieceValues := map[chess.PieceType]int{
+		chess.Pawn:   1,
+		chess.Knight: 3,
+		chess.Bishop: 3,
+		chess.Rook:   5,
+		chess.Queen:  9,
+	}
+
+	score := 0
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		piece := position.Board().Piece(sq)
+		if piece != chess.NoPiece && piece.Color() == color {
+			score += pieceValues[piece.Type()]
+		}
+	}
+	return score
+}
+
+// teamsLockedUnsafe reports whether the game no longer accepts new players (caller must hold the lock)
+func (m *Manager) teamsLockedUnsafe(game *GameState) bool {
+	return game.TeamLockMove > 0 && game.CurrentMove >= game.TeamLockMove
+}
+
+// AreTeamsLocked reports whether a game no longer accepts new players
+func (m *Manager) AreTeamsLocked(gameID string) bool {
+	m.mu.RLock()
+	game, exists := m.games[gameID]
+	m.mu.RUnlock()
+
+	if !exists {
+		return true
+	}
+
+	game.mu.RLock()
+	defer game.mu.RUnlock()
+
+	return m.teamsLockedUnsafe(game)
+}
+
+// AddBotToTeam adds a bot player to a team and returns the bot's ID
+func (m *Manager) AddBotToTeam(gameID, team string) (string, error) {
+	m.mu.RLock()
+	game, exists := m.games[gameID]
+	m.mu.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf("game not found: %s", gameID)
+	}
+
+	botID := BotAddressPrefix + uuid.New().String()[:8]
+
+	game.mu.Lock()
+	defer game.mu.Unlock()
+
+	switch team {
+	case "white":
+		game.WhitePlayers[botID] = true
+	case "black":
+		game.BlackPlayers[botID] = true
+	default:
+		return "", fmt.Errorf("invalid team: %s", team)
+	}
+	game.BotPlayers[botID] = true
+
+	log.Printf("Bot %s joined %s team in game %s", botID, team, gameID)
+	return botID, nil
+}
+
+// castBotVotes lets bots on the team to move vote for a random valid move (caller must hold the lock)
+func (m *Manager) castBotVotes(game *GameState) {
+	if len(game.BotPlayers) == 0 || game.Game.Outcome() != chess.NoOutcome {
+		return
+	}
+
+	isWhiteTurn := game.Game.Position().Turn() == chess.White
+	teamPlayers := game.BlackPlayers
+	if isWhiteTurn {
+		teamPlayers = game.WhitePlayers
+	}
+
+	validMoves := game.Game.ValidMoves()
+	if len(validMoves) == 0 {
+		return
+	}
+
+	for botID := range game.BotPlayers {
+		if !teamPlayers[botID] || game.PlayerVotedThisRound[botID] {
+			continue
+		}
+
+		move := validMoves[rand.IntN(len(validMoves))]
+		moveStr := move.S1().String() + move.S2().String()
+
+		// Bots do not stake, so they only count towards the round's votes
+		game.Votes[moveStr]++
+		game.PlayerVotedThisRound[botID] = true
+		if isWhiteTurn {
+			game.WhiteVotesThisTurn++
+			game.WhiteTeamTotalVotes++
+		} else {
+			game.BlackVotesThisTurn++
+			game.BlackTeamTotalVotes++
+		}
+
+		log.Printf("Bot %s voted for move %s in game %s", botID, moveStr, game.ID)
+	}
+}
+
 // GetGameStats returns game statistics
 func (m *Manager) GetGameStats(gameID string) map[string]any {
 	m.mu.RLock()
@@ -935,13 +1275,14 @@ func (m *Manager) getGameStatsUnsafe(game *GameState, gameEnded bool) map[string
 	whiteTeamPlayers := make([]map[string]any, 0)
 	for walletAddress := range game.WhitePlayers {
 		votes := game.PlayerTotalVotes[walletAddress]
-		spent := float64(votes) * 0.01 // Each vote costs 0.01 USDC
+		spent := game.PlayerTotalSpent[walletAddress]
 		whiteTeamPlayers = append(whiteTeamPlayers, map[string]any{
-			"walletAddress": walletAddress,
-			"totalVotes":    votes,
-			"totalSpent":    spent,
+			"walletAddress":  walletAddress,
+			"totalVotes":     votes,
+			"totalSpent":     spent,
+			"confirmedStake": game.PlayerConfirmedStakes[walletAddress],
 		})
-		log.Printf("Collecting white player: %s - %d votes, %.3f USDC", walletAddress, votes, spent)
+		log.Printf("Collecting white player: %s - %d votes, %s USDC", walletAddress, votes, spent)
 	}
 	log.Printf("Total white team players collected: %d", len(whiteTeamPlayers))
 
@@ -949,13 +1290,14 @@ func (m *Manager) getGameStatsUnsafe(game *GameState, gameEnded bool) map[string
 	blackTeamPlayers := make([]map[string]any, 0)
 	for walletAddress := range game.BlackPlayers {
 		votes := game.PlayerTotalVotes[walletAddress]
-		spent := float64(votes) * 0.01 // Each vote costs 0.01 USDC
+		spent := game.PlayerTotalSpent[walletAddress]
 		blackTeamPlayers = append(blackTeamPlayers, map[string]any{
-			"walletAddress": walletAddress,
-			"totalVotes":    votes,
-			"totalSpent":    spent,
+			"walletAddress":  walletAddress,
+			"totalVotes":     votes,
+			"totalSpent":     spent,
+			"confirmedStake": game.PlayerConfirmedStakes[walletAddress],
 		})
-		log.Printf("Collecting black player: %s - %d votes, %.3f USDC", walletAddress, votes, spent)
+		log.Printf("Collecting black player: %s - %d votes, %s USDC", walletAddress, votes, spent)
 	}
 	log.Printf("Total black team players collected: %d", len(blackTeamPlayers))
 
@@ -969,6 +1311,7 @@ func (m *Manager) getGameStatsUnsafe(game *GameState, gameEnded bool) map[string
 		"totalPot":              game.TotalPot,
 		"whitePot":              game.WhitePot,
 		"blackPot":              game.BlackPot,
+		"chainStakes":           maps.Clone(game.ChainStakes),
 		"currentTurn":           currentTurn,
 		"timeLeft":              game.TimeLeft,
 		"currentMove":           currentMove,
@@ -979,6 +1322,8 @@ func (m *Manager) getGameStatsUnsafe(game *GameState, gameEnded bool) map[string
 		"blackTeamPlayers":      blackTeamPlayers,
 		"isInCheck":             isInCheck,
 		"isCheckmate":           isCheckmate,
+		"maxTeamSize":           game.MaxTeamSize,
+		"teamsLocked":           m.teamsLockedUnsafe(game),
 	}
 }
 
@@ -1112,126 +1457,19 @@ func (m *Manager) GetAllGames() []string {
 	return gameIDs
 }
 
-// distributeRewards distributes rewards to winning players using multicall approach
-func (m *Manager) distributeRewards(gameID, winner string, gameStats map[string]any) {
-	if winner == "draw" {
-		log.Printf("Game %s ended in draw, no rewards to distribute", gameID)
+// recordLedgerEntry records a ledger entry, logging instead of failing the caller
+func (m *Manager) recordLedgerEntry(entry ledger.Entry) {
+	if m.ledger == nil {
 		return
 	}
-
-	// Step 1: Gather all rewards from participating vaults to Base Sepolia
-	err := m.gatherRewards(gameID, gameStats)
-	if err != nil {
-		log.Printf("Warning: Failed to gather rewards for game %s: %v", gameID, err)
-		return
+	if _, err := m.ledger.Record(entry); err != nil {
+		log.Printf("Warning: Failed to record %s ledger entry for game %s: %v", entry.Kind, entry.GameID, err)
 	}
-
-	// Step 2: Calculate and distribute rewards from total pot
-	m.distributeRewardsFromTotalPot(gameID, winner, gameStats)
 }
 
-// gatherRewards sends all vault rewards to the central Base Sepolia vault
-func (m *Manager) gatherRewards(gameID string, gameStats map[string]any) error {
-	// Get all players from both teams to determine which chains were involved
-	var allPlayers []map[string]any
-
-	if whiteTeamPlayers, ok := gameStats["whiteTeamPlayers"].([]map[string]any); ok {
-		allPlayers = append(allPlayers, whiteTeamPlayers...)
-	}
-	if blackTeamPlayers, ok := gameStats["blackTeamPlayers"].([]map[string]any); ok {
-		allPlayers = append(allPlayers, blackTeamPlayers...)
-	}
-
-	if len(allPlayers) == 0 {
-		return fmt.Errorf("no players found in game stats")
-	}
-
-	// Get unique chain IDs from all players
-	involvedChains := make(map[uint64]bool)
-	for _, player := range allPlayers {
-		if walletAddress, ok := player["walletAddress"].(string); ok {
-			playerChainID := m.GetPlayerChainID(walletAddress)
-			if playerChainID != 0 {
-				involvedChains[uint64(playerChainID)] = true
-			}
-		}
-	}
-
-	// Base Sepolia chain ID (destination for all rewards)
-	baseSepoliaChainID := uint64(84532)
-
-	// Remove Base Sepolia from involved chains since we're gathering TO it
-	delete(involvedChains, baseSepoliaChainID)
-
-	if len(involvedChains) == 0 {
-		log.Printf("No external chains involved in game %s, all rewards already on Base Sepolia", gameID)
-		return nil
-	}
-
-	// Get blockchain game ID
-	m.mu.RLock()
-	game, exists := m.games[gameID]
-	gameIDUint := uint64(0)
-	if exists {
-		gameIDUint = game.BlockchainGameID
-	}
-	m.mu.RUnlock()
-
-	if !exists {
-		return fmt.Errorf("game %s not found", gameID)
-	}
-
-	// Get total pot to transfer
-	totalPot := 0.0
-	if tp, ok := gameStats["totalPot"].(float64); ok {
-		totalPot = tp
-	}
-
-	if totalPot <= 0 {
-		return fmt.Errorf("no pot to gather for game %s", gameID)
-	}
-
-	// Calculate amount per chain (distribute the gathering equally)
-	totalPotWei := new(big.Int).SetInt64(int64(totalPot * 1000000)) // Convert to USDC wei
-	amountPerChain := new(big.Int).Div(totalPotWei, big.NewInt(int64(len(involvedChains))))
-
-	log.Printf("Gathering %s USDC total pot from %d chains to Base Sepolia (%s per chain)",
-		totalPotWei.String(), len(involvedChains), amountPerChain.String())
-
-	// Get Base Sepolia vault address as recipient
-	baseVaultAddress := client.GetVaultAddress(baseSepoliaChainID)
-	if baseVaultAddress == "" {
-		return fmt.Errorf("no Base Sepolia vault address configured")
-	}
-	recipient := common.HexToAddress(baseVaultAddress)
-
-	// Transfer from each involved chain to Base Sepolia
-	for chainID := range involvedChains {
-		vault, err := m.vaultManager.GetVault(chainID)
-		if err != nil {
-			log.Printf("Warning: Failed to get vault for chain %d: %v", chainID, err)
-			continue
-		}
-
-		// Transfer rewards to Base Sepolia vault
-		useFastTransfer := false // Use standard transfer for lower fees
-		maxFee := big.NewInt(0)  // Let the contract determine the fee
-
-		err = vault.TransferRewards(gameIDUint, amountPerChain, baseSepoliaChainID, recipient, useFastTransfer, maxFee)
-		if err != nil {
-			log.Printf("Warning: Failed to gather rewards from chain %d: %v", chainID, err)
-			continue
-		}
-
-		log.Printf("Successfully gathered %s USDC from chain %d to Base Sepolia vault",
-			amountPerChain.String(), chainID)
-	}
-
-	return nil
-}
-
-// distributeRewardsFromTotalPot distributes rewards from the total pot using multicall
-func (m *Manager) distributeRewardsFromTotalPot(gameID, winner string, gameStats map[string]any) {
+// planRewardTransfers splits the pot between the winning players in proportion to their votes,
+// routing the platform fee to the treasury first
+func (m *Manager) planRewardTransfers(gameID, winner string, gameStats map[string]any) []RewardTransfer {
 	// Get the winning team players
 	var winningPlayers []map[string]any
 
@@ -1248,66 +1486,70 @@ func (m *Manager) distributeRewardsFromTotalPot(gameID, winner string, gameStats
 
 	if len(winningPlayers) == 0 {
 		log.Printf("No winning players for game %s", gameID)
-		return
+		return nil
 	}
 
-	// Get total pot (not just losing team pot)
-	totalPot := 0.0
-	if tp, ok := gameStats["totalPot"].(float64); ok {
-		totalPot = tp
+	// Only stakes mined into the vaults can be paid out (not just losing team pot)
+	totalPot := confirmedPot(gameStats)
+	if !totalPot.IsPositive() {
+		log.Printf("No confirmed pot for game %s", gameID)
+		return nil
 	}
 
-	if totalPot <= 0 {
-		log.Printf("No total pot for game %s", gameID)
-		return
-	}
+	// Winners share what is left after the platform fee
+	fee, _ := gameStats["fee"].(money.Amount)
+	netPayout := totalPot.Sub(fee)
 
-	// Calculate total votes from winning team
-	totalWinningVotes := 0
+	// Calculate confirmed stakes from winning team
+	totalWinningStakes := money.USDC(0)
 	for _, player := range winningPlayers {
-		if votes, ok := player["totalVotes"].(int); ok {
-			totalWinningVotes += votes
+		if staked, ok := player["confirmedStake"].(money.Amount); ok {
+			totalWinningStakes = totalWinningStakes.Add(staked)
 		}
 	}
 
-	if totalWinningVotes == 0 {
-		log.Printf("No votes from winning team for game %s", gameID)
-		return
+	if !totalWinningStakes.IsPositive() {
+		log.Printf("No confirmed stakes from winning team for game %s", gameID)
+		return nil
 	}
 
-	// Convert total pot to USDC wei (6 decimal places)
-	totalPotWei := new(big.Int).SetInt64(int64(totalPot * 1000000)) // Convert to USDC wei
-
-	log.Printf("Distributing %s USDC from total pot to %d winning players based on %d total votes",
-		totalPotWei.String(), len(winningPlayers), totalWinningVotes)
+	log.Printf("Planning %s USDC from confirmed pot (%s USDC fee) to %d winning players based on %s USDC confirmed stakes",
+		netPayout, fee, len(winningPlayers), totalWinningStakes)
 
 	// Prepare multicall data for all reward transfers
 	var rewardTransfers []RewardTransfer
 
-	// Calculate each player's share
+	// Calculate each player's share. Shares of winners that cannot be paid stay in the vault
+	// instead of going to another winner, so the operator can settle them by hand.
+	allocated := money.USDC(0)
 	for _, player := range winningPlayers {
-		walletAddress, ok := player["walletAddress"].(string)
-		if !ok {
+		playerStake, ok := player["confirmedStake"].(money.Amount)
+		if !ok || !playerStake.IsPositive() {
 			continue
 		}
 
-		playerVotes, ok := player["totalVotes"].(int)
-		if !ok || playerVotes <= 0 {
+		// Calculate player's proportional share of the confirmed pot
+		playerShare, err := netPayout.MulDiv(playerStake.Units(), totalWinningStakes.Units())
+		if err != nil {
+			log.Printf("Error: Failed to compute the share of a winner in game %s: %v", gameID, err)
+			continue
+		}
+		allocated = allocated.Add(playerShare)
+		if !playerShare.IsPositive() {
 			continue
 		}
 
-		// Calculate player's proportional share of the total pot
-		playerShare := new(big.Int).Mul(totalPotWei, big.NewInt(int64(playerVotes)))
-		playerShare.Div(playerShare, big.NewInt(int64(totalWinningVotes)))
-
-		if playerShare.Cmp(big.NewInt(0)) <= 0 {
+		walletAddress, _ := player["walletAddress"].(string)
+		if walletAddress == "" {
+			log.Printf("Error: Winner without a wallet address in game %s, %s USDC share stays in the vault", gameID, playerShare)
 			continue
 		}
 
 		// Get player's chain ID for destination
 		playerChainID := m.GetPlayerChainID(walletAddress)
 		if playerChainID == 0 {
-			log.Printf("Warning: No chain ID found for player %s, skipping reward", walletAddress)
+			log.Printf("Error: No chain ID found for player %s in game %s, %s USDC share stays in the vault",
+				walletAddress, gameID, playerShare)
 			continue
 		}
 
@@ -1318,69 +1560,39 @@ func (m *Manager) distributeRewardsFromTotalPot(gameID, winner string, gameStats
 		})
 
 		log.Printf("Prepared reward transfer: %s USDC to %s on chain %d",
-			playerShare.String(), walletAddress, playerChainID)
+			playerShare, walletAddress, playerChainID)
 	}
 
 	if len(rewardTransfers) == 0 {
 		log.Printf("No valid reward transfers for game %s", gameID)
-		return
+		return nil
 	}
 
-	// Execute multicall reward distribution
-	err := m.executeMulticallRewards(gameID, rewardTransfers)
-	if err != nil {
-		log.Printf("Warning: Failed to execute multicall rewards for game %s: %v", gameID, err)
+	// Give the rounding dust to the first winner so the whole pot is paid out
+	if remainder := netPayout.Sub(allocated); remainder.IsPositive() {
+		rewardTransfers[0].Amount = rewardTransfers[0].Amount.Add(remainder)
+		log.Printf("Added %s USDC rounding remainder to reward for %s", remainder, rewardTransfers[0].Recipient.Hex())
+	}
+
+	// Route the fee to the treasury before paying winners
+	if fee.IsPositive() {
+		rewardTransfers = append([]RewardTransfer{{
+			Recipient:        m.fees.TreasuryAddress,
+			Amount:           fee,
+			DestinationChain: m.fees.TreasuryChainID,
+			Kind:             ledger.KindFee,
+		}}, rewardTransfers...)
+		log.Printf("Prepared fee transfer: %s USDC to treasury %s on chain %d",
+			fee, m.fees.TreasuryAddress.Hex(), m.fees.TreasuryChainID)
 	}
+
+	return rewardTransfers
 }
 
 // RewardTransfer represents a single reward transfer
 type RewardTransfer struct {
 	Recipient        common.Address
-	Amount           *big.Int
+	Amount           money.Amount
 	DestinationChain uint64
-}
-
-// executeMulticallRewards executes multiple reward transfers using multicall
-func (m *Manager) executeMulticallRewards(gameID string, transfers []RewardTransfer) error {
-	// Get Base Sepolia vault (where all rewards are now gathered)
-	baseSepoliaChainID := uint64(84532)
-	baseVault, err := m.vaultManager.GetVault(baseSepoliaChainID)
-	if err != nil {
-		return fmt.Errorf("failed to get Base Sepolia vault: %w", err)
-	}
-
-	// Get blockchain game ID
-	m.mu.RLock()
-	game, exists := m.games[gameID]
-	gameIDUint := uint64(0)
-	if exists {
-		gameIDUint = game.BlockchainGameID
-	}
-	m.mu.RUnlock()
-
-	if !exists {
-		return fmt.Errorf("game %s not found", gameID)
-	}
-
-	log.Printf("Executing multicall reward distribution for %d transfers", len(transfers))
-
-	for i, transfer := range transfers {
-		log.Printf("Executing transfer %d/%d: %s USDC to %s on chain %d",
-			i+1, len(transfers), transfer.Amount.String(), transfer.Recipient.Hex(), transfer.DestinationChain)
-
-		useFastTransfer := false // Use standard transfer for lower fees
-		maxFee := big.NewInt(0)  // Let the contract determine the fee
-
-		err := baseVault.TransferRewards(gameIDUint, transfer.Amount, transfer.DestinationChain, transfer.Recipient, useFastTransfer, maxFee)
-		if err != nil {
-			log.Printf("Warning: Failed to transfer reward %d: %v", i+1, err)
-			continue
-		}
-
-		log.Printf("Successfully transferred reward %d: %s USDC to %s on chain %d",
-			i+1, transfer.Amount.String(), transfer.Recipient.Hex(), transfer.DestinationChain)
-	}
-
-	log.Printf("Completed multicall reward distribution for game %s", gameID)
-	return nil
+	Kind             ledger.Kind // ledger.KindPayout when empty
 }
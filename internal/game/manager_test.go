@@ -0,0 +1,54 @@
+package game
+
+import (
+	"slices"
+	"testing"
+
+	"blockchess/internal/money"
+)
+
+func TestPlanRewardTransfersOnlyGivesDustToFirstWinner(t *testing.T) {
+	winner := func(wallet string, stake int64) map[string]any {
+		return map[string]any{"walletAddress": wallet, "confirmedStake": money.USDC(stake)}
+	}
+	const (
+		alice = "0xAA00000000000000000000000000000000000001"
+		bob   = "0xBB00000000000000000000000000000000000002"
+		carol = "0xCC00000000000000000000000000000000000003"
+	)
+
+	tests := []struct {
+		name    string
+		players []map[string]any
+		want    []int64
+	}{
+		{
+			name:    "rounding dust",
+			players: []map[string]any{winner(alice, 100000), winner(bob, 100000), winner(carol, 100000)},
+			want:    []int64{333334, 333333, 333333},
+		},
+		{
+			name:    "unpayable winner keeps their share in the vault",
+			players: []map[string]any{winner(alice, 100000), winner("0xDD00000000000000000000000000000000000004", 100000)},
+			want:    []int64{500000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manager{playerChainIDs: map[string]uint32{alice: 84532, bob: 84532, carol: 11155111}}
+			gameStats := map[string]any{
+				"chainStakes":      map[uint64]money.Amount{84532: money.USDC(1000000)},
+				"whiteTeamPlayers": tt.players,
+			}
+
+			var got []int64
+			for _, transfer := range m.planRewardTransfers("g", "white", gameStats) {
+				got = append(got, transfer.Amount.Units())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("planned %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+
+	"blockchess/internal/client"
+	"blockchess/internal/ledger"
+	"blockchess/internal/money"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Payout modes
+const (
+	PayoutModeBatch        = "batch"         // Pay winners in as few vault transactions as gas allows
+	PayoutModePerRecipient = "per_recipient" // Pay every winner in its own transaction
+)
+
+// BatchTransfer is one recipient of a payout_batch step
+type BatchTransfer struct {
+	Recipient   string       `json:"recipient"`
+	DestChainID uint64       `json:"destChainId"`
+	Amount      money.Amount `json:"amount"`
+	Done        bool         `json:"done"`
+	TxHash      string       `json:"txHash,omitempty"`
+	LastError   string       `json:"lastError,omitempty"`
+}
+
+// paidTransfers returns the transfers of a batch that were already paid
+func (s SettlementStep) paidTransfers() []BatchTransfer {
+	var paid []BatchTransfer
+	for _, transfer := range s.Transfers {
+		if transfer.Done {
+			paid = append(paid, transfer)
+		}
+	}
+	return paid
+}
+
+// unpaidAmount returns what a step still has to send, the unpaid recipients for payout_batch steps
+func (s SettlementStep) unpaidAmount() money.Amount {
+	if s.Kind != StepPayoutBatch {
+		return s.Amount
+	}
+	unpaid := money.USDC(0)
+	for _, transfer := range s.Transfers {
+		if !transfer.Done {
+			unpaid = unpaid.Add(transfer.Amount)
+		}
+	}
+	return unpaid
+}
+
+// batchPayoutSteps groups payouts sent from the same vault into batches sized by the gas budget
+func (m *Manager) batchPayoutSteps(payouts []SettlementStep) []SettlementStep {
+	if len(payouts) < 2 {
+		return payouts
+	}
+
+	perBatch := max(int(m.settlementConfig.BatchGasLimit/m.settlementConfig.GasPerTransfer), 1)
+
+	var steps []SettlementStep
+	for start := 0; start < len(payouts); start += perBatch {
+		end := min(start+perBatch, len(payouts))
+		step := SettlementStep{
+			ID:            fmt.Sprintf("payout_batch:%d", start/perBatch+1),
+			Kind:          StepPayoutBatch,
+			SourceChainID: payouts[start].SourceChainID,
+			Amount:        money.USDC(0),
+		}
+		for _, payout := range payouts[start:end] {
+			step.Transfers = append(step.Transfers, BatchTransfer{
+				Recipient:   payout.Recipient,
+				DestChainID: payout.DestChainID,
+				Amount:      payout.Amount,
+			})
+			step.Amount = step.Amount.Add(payout.Amount)
+		}
+		steps = append(steps, step)
+	}
+
+	log.Printf("Grouped %d payouts into %d batches of up to %d recipients", len(payouts), len(steps), perBatch)
+	return steps
+}
+
+// sendPayoutBatch submits the unpaid recipients of a batch, halving it until its gas estimate fits the budget
+func (m *Manager) sendPayoutBatch(job *SettlementJob, step *SettlementStep) (*types.Transaction, error) {
+	vault, err := m.getSettlementVault(step.SourceChainID)
+	if err != nil {
+		return nil, err
+	}
+
+	var unpaid []int
+	for i, transfer := range step.Transfers {
+		if !transfer.Done {
+			unpaid = append(unpaid, i)
+		}
+	}
+	if len(unpaid) == 0 {
+		return nil, fmt.Errorf("payout batch %s has no unpaid recipients", step.ID)
+	}
+
+	useFastTransfer := false // Use standard transfer for lower fees
+	maxFee := big.NewInt(0)  // Let the contract determine the fee
+
+	count := len(unpaid)
+	for {
+		inputs, err := m.batchInputs(step, unpaid[:count])
+		if err != nil {
+			return nil, err
+		}
+		gas, err := vault.EstimateTransferRewardsBatch(job.ChainGame, inputs, useFastTransfer, maxFee)
+		if err != nil {
+			return nil, err
+		}
+		if gas <= m.settlementConfig.BatchGasLimit || count == 1 {
+			break
+		}
+		log.Printf("Payout batch %s of game %s needs %d gas for %d recipients, splitting it", step.ID, job.GameID, gas, count)
+		count /= 2
+	}
+
+	inputs, err := m.batchInputs(step, unpaid[:count])
+	if err != nil {
+		return nil, err
+	}
+	step.Sent = unpaid[:count]
+	return vault.SendTransferRewardsBatch(job.ChainGame, inputs, useFastTransfer, maxFee, m.recordSignedStep(job, step))
+}
+
+// batchInputs converts the given transfers of a batch to vault inputs
+func (m *Manager) batchInputs(step *SettlementStep, indices []int) ([]client.RewardTransferInput, error) {
+	inputs := make([]client.RewardTransferInput, len(indices))
+	for i, index := range indices {
+		transfer := step.Transfers[index]
+		amount, err := client.USDCUnits(step.SourceChainID, transfer.Amount)
+		if err != nil {
+			return nil, err
+		}
+		inputs[i] = client.RewardTransferInput{
+			Recipient:   common.HexToAddress(transfer.Recipient),
+			Amount:      amount,
+			DestChainID: transfer.DestChainID,
+		}
+	}
+	return inputs, nil
+}
+
+// completePayoutBatch applies the per-recipient results of a mined batch, recording each paid recipient in the
+// ledger exactly once. Failed recipients are retried with the step's backoff.
+func (m *Manager) completePayoutBatch(job *SettlementJob, step *SettlementStep) error {
+	vault, err := m.getSettlementVault(step.SourceChainID)
+	if err != nil {
+		return err
+	}
+
+	results, err := vault.TransferBatchResults(common.HexToHash(step.TxHash))
+	if err != nil {
+		return err
+	}
+
+	var destChainIDs []uint64
+	failed := 0
+	lastReason := ""
+	for _, result := range results {
+		if result.Index < 0 || result.Index >= len(step.Sent) {
+			continue
+		}
+		transfer := &step.Transfers[step.Sent[result.Index]]
+		if !result.Succeeded {
+			transfer.LastError = result.Reason
+			failed++
+			lastReason = result.Reason
+			log.Printf("Warning: Payout of %s USDC to %s in batch %s of game %s failed: %s",
+				transfer.Amount, transfer.Recipient, step.ID, job.GameID, result.Reason)
+			continue
+		}
+
+		transfer.Done = true
+		transfer.TxHash = step.TxHash
+		transfer.LastError = ""
+		destChainIDs = append(destChainIDs, transfer.DestChainID)
+
+		ref := fmt.Sprintf("%s/%s/%s@%d", job.GameID, step.ID, transfer.Recipient, transfer.DestChainID)
+		if m.ledger != nil && !m.ledger.HasRef(ref) {
+			m.recordLedgerEntry(ledger.Entry{
+				GameID:    job.GameID,
+				ChainGame: job.ChainGame,
+				Kind:      ledger.KindPayout,
+				From:      ledger.Pot(job.GameID, step.SourceChainID),
+				To:        ledger.Wallet(transfer.Recipient, transfer.DestChainID),
+				Amount:    transfer.Amount,
+				TxHash:    step.TxHash,
+				Ref:       ref,
+			})
+		}
+	}
+
+	// Each paid recipient's burn emits one CCTP message, in batch order
+	if m.relayer != nil && len(destChainIDs) > 0 {
+		if err := m.relayer.Track(job.GameID, step.SourceChainID, destChainIDs, common.HexToHash(step.TxHash)); err != nil {
+			log.Printf("Warning: Failed to track CCTP transfers of batch %s in game %s: %v", step.ID, job.GameID, err)
+		}
+	}
+
+	log.Printf("Payout batch %s of game %s confirmed in %s: %d paid, %d failed",
+		step.ID, job.GameID, step.TxHash, len(destChainIDs), failed)
+
+	if len(step.paidTransfers()) == len(step.Transfers) {
+		step.Done = true
+		step.Sent = nil
+		return nil
+	}
+
+	// Send the rest in a new transaction
+	step.TxHash, step.Nonce, step.SubmittedAt, step.RawTx, step.Sent = "", 0, 0, "", nil
+	if failed > 0 {
+		return fmt.Errorf("%d recipients of batch failed: %s", failed, lastReason)
+	}
+	return errBatchRemaining
+}
@@ -0,0 +1,252 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"blockchess/internal/client"
+	"blockchess/internal/money"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// PermitState is where a player's Permit2 permit is in its lifecycle
+type PermitState string
+
+const (
+	PermitRequested PermitState = "requested" // Typed data sent to the player, not signed yet
+	PermitSigned    PermitState = "signed"    // Signature verified, permit not on-chain yet
+	PermitSubmitted PermitState = "submitted" // Permit transaction sent, waiting to be mined
+	PermitActive    PermitState = "active"    // Allowance live on-chain, stakes draw from it
+	PermitExhausted PermitState = "exhausted" // Allowance left is below one stake
+	PermitExpired   PermitState = "expired"   // Signature deadline or allowance expiration passed
+)
+
+// Permit error codes added by the allowance lifecycle
+const (
+	PermitErrorExhausted = "permit_exhausted" // The allowance is used up, a new permit must be signed
+)
+
+// playerPermit tracks one player's permit and the allowance it granted the vault
+type playerPermit struct {
+	mu        sync.Mutex
+	data      *client.PermitSignatureData
+	state     PermitState
+	remaining *big.Int // Allowance left after the stakes reserved so far
+	expiresAt int64    // Unix time the on-chain allowance expires
+	txHash    string   // Transaction that put the permit on-chain
+}
+
+// newPlayerPermit wraps permit data in its initial state
+func newPlayerPermit(data *client.PermitSignatureData) *playerPermit {
+	state := PermitRequested
+	if data.Signature != "" {
+		state = PermitSigned
+	}
+	return &playerPermit{data: data, state: state}
+}
+
+// usableUnsafe reports whether the permit can still pay a stake without asking the player to sign again,
+// expiring it when its deadline passed (caller must hold the lock)
+func (p *playerPermit) usableUnsafe(chainID uint32, amount *big.Int) *PermitError {
+	if p.data.ChainID != uint64(chainID) {
+		return &PermitError{
+			Code:    client.PermitErrorChainMismatch,
+			Message: fmt.Sprintf("permit is for chain %d, but player is on chain %d", p.data.ChainID, chainID),
+		}
+	}
+
+	now := time.Now().Unix()
+	switch p.state {
+	case PermitRequested:
+		return &PermitError{Code: client.PermitErrorNotFound, Message: "permit signature is missing - please sign the permit"}
+	case PermitSigned, PermitSubmitted:
+		if p.state == PermitSigned && p.data.SigDeadline.Int64() <= now {
+			p.state = PermitExpired
+		}
+	case PermitActive:
+		if p.expiresAt <= now {
+			p.state = PermitExpired
+		} else if p.remaining.Cmp(amount) < 0 {
+			p.state = PermitExhausted
+		}
+	}
+
+	switch p.state {
+	case PermitExpired:
+		return &PermitError{Code: client.PermitErrorExpired, Message: "permit has expired - please sign a new permit"}
+	case PermitExhausted:
+		return &PermitError{Code: PermitErrorExhausted, Message: "permit allowance is used up - please sign a new permit"}
+	}
+	return nil
+}
+
+// playerPermitFor returns the permit record of a player
+func (m *Manager) playerPermitFor(walletAddress string) *playerPermit {
+	m.permitMutex.RLock()
+	defer m.permitMutex.RUnlock()
+	return m.playerPermits[walletAddress]
+}
+
+// checkPermit reports whether a player's permit can pay a stake of the given amount
+func (m *Manager) checkPermit(walletAddress string, chainID uint32, stake money.Amount) error {
+	permit := m.playerPermitFor(walletAddress)
+	if permit == nil {
+		return &PermitError{Code: client.PermitErrorNotFound, Message: "no permit found - please sign permit before voting"}
+	}
+	amount, err := client.USDCUnits(uint64(chainID), stake)
+	if err != nil {
+		return err
+	}
+
+	permit.mu.Lock()
+	defer permit.mu.Unlock()
+	if err := permit.usableUnsafe(chainID, amount); err != nil {
+		return err
+	}
+	return nil
+}
+
+// reserveAllowance takes a stake's amount from the player's allowance, putting the permit on-chain first if it is
+// only signed. The live allowance is re-read from Permit2 before the player is asked for a new permit.
+func (m *Manager) reserveAllowance(stake pendingStake, vault *client.Vault) error {
+	permit := m.playerPermitFor(stake.Wallet)
+	if permit == nil {
+		return &PermitError{Code: client.PermitErrorNotFound, Message: "no permit found - please sign permit before voting"}
+	}
+
+	// Holding the permit's lock keeps concurrent stakes of a player from submitting the same permit twice
+	permit.mu.Lock()
+	defer permit.mu.Unlock()
+
+	amount, err := client.USDCUnits(uint64(stake.ChainID), stake.Amount)
+	if err != nil {
+		return err
+	}
+	if permit.state == PermitSigned || permit.state == PermitSubmitted {
+		if err := m.activatePermitUnsafe(stake.Wallet, permit, vault); err != nil {
+			return err
+		}
+	}
+
+	if permit.state == PermitActive && permit.remaining.Cmp(amount) < 0 {
+		// Stakes may have failed after their reservation or the player may have changed the allowance themselves
+		m.refreshAllowanceUnsafe(stake.Wallet, permit)
+	}
+
+	if err := permit.usableUnsafe(stake.ChainID, amount); err != nil {
+		return err
+	}
+
+	permit.remaining = new(big.Int).Sub(permit.remaining, amount)
+	return nil
+}
+
+// stakeWithAllowance stakes a vote from the allowance of the player's permit, which is put on-chain once
+func (m *Manager) stakeWithAllowance(stake pendingStake, vault *client.Vault) (common.Hash, error) {
+	if err := m.reserveAllowance(stake, vault); err != nil {
+		return common.Hash{}, err
+	}
+
+	amount, err := client.USDCUnits(uint64(stake.ChainID), stake.Amount)
+	if err != nil {
+		m.releaseAllowance(stake)
+		return common.Hash{}, err
+	}
+	txHash, err := vault.Stake(common.HexToAddress(stake.Wallet), stake.ChainGame, amount)
+	if err != nil {
+		m.releaseAllowance(stake)
+		return common.Hash{}, fmt.Errorf("staking failed: %w", err)
+	}
+	return txHash, nil
+}
+
+// releaseAllowance returns the amount of a stake that failed to the player's allowance
+func (m *Manager) releaseAllowance(stake pendingStake) {
+	permit := m.playerPermitFor(stake.Wallet)
+	if permit == nil {
+		return
+	}
+	amount, err := client.USDCUnits(uint64(stake.ChainID), stake.Amount)
+	if err != nil {
+		log.Printf("Warning: Failed to release allowance of %s: %v", stake.Wallet, err)
+		return
+	}
+
+	permit.mu.Lock()
+	defer permit.mu.Unlock()
+
+	if permit.state == PermitActive || permit.state == PermitExhausted {
+		permit.remaining = new(big.Int).Add(permit.remaining, amount)
+		if permit.state == PermitExhausted && permit.expiresAt > time.Now().Unix() {
+			permit.state = PermitActive
+		}
+	}
+}
+
+// activatePermitUnsafe puts a signed permit on-chain, unless Permit2 shows it was already used (caller must hold the
+// permit's lock)
+func (m *Manager) activatePermitUnsafe(walletAddress string, permit *playerPermit, vault *client.Vault) error {
+	allowance, err := m.readAllowance(permit.data)
+	if err == nil && allowance.Nonce.Cmp(permit.data.Nonce) > 0 {
+		// The permit's nonce was consumed, for example by a submission before a restart
+		log.Printf("Permit of %s on chain %d is already on-chain, using its allowance", walletAddress, permit.data.ChainID)
+		permit.applyAllowance(allowance)
+		return nil
+	}
+	if err != nil {
+		log.Printf("Warning: Failed to read allowance of %s on chain %d, submitting permit: %v", walletAddress, permit.data.ChainID, err)
+	}
+
+	if permit.data.SigDeadline.Int64() <= time.Now().Unix() {
+		permit.state = PermitExpired
+		return nil
+	}
+
+	permit.state = PermitSubmitted
+	txHash, err := vault.SubmitPermit(common.HexToAddress(walletAddress), permit.data)
+	if err != nil {
+		// Stay submitted: the next stake re-reads the nonce before trying again
+		return fmt.Errorf("failed to submit permit: %w", err)
+	}
+
+	permit.state = PermitActive
+	permit.txHash = txHash.Hex()
+	m.funds.invalidate(uint32(permit.data.ChainID), walletAddress)
+	permit.remaining = new(big.Int).Set(permit.data.Amount)
+	permit.expiresAt = permit.data.Expiration.Int64()
+	log.Printf("Permit of %s on chain %d is active with %s USDC base units", walletAddress, permit.data.ChainID, permit.remaining)
+	return nil
+}
+
+// refreshAllowanceUnsafe replaces the tracked allowance with the one on-chain (caller must hold the permit's lock)
+func (m *Manager) refreshAllowanceUnsafe(walletAddress string, permit *playerPermit) {
+	allowance, err := m.readAllowance(permit.data)
+	if err != nil {
+		log.Printf("Warning: Failed to refresh allowance of %s on chain %d: %v", walletAddress, permit.data.ChainID, err)
+		return
+	}
+	permit.applyAllowance(allowance)
+}
+
+// applyAllowance makes an on-chain allowance the permit's active allowance
+func (p *playerPermit) applyAllowance(allowance client.Allowance) {
+	p.state = PermitActive
+	p.remaining = new(big.Int).Set(allowance.Amount)
+	p.expiresAt = allowance.Expiration.Int64()
+}
+
+// readAllowance reads the vault's live allowance on a permit's token
+func (m *Manager) readAllowance(data *client.PermitSignatureData) (client.Allowance, error) {
+	if m.permit2Manager == nil {
+		return client.Allowance{}, fmt.Errorf("Permit2 manager not available")
+	}
+	permit2Client, err := m.permit2Manager.GetPermit2Client(data.ChainID)
+	if err != nil {
+		return client.Allowance{}, err
+	}
+	return permit2Client.GetPermitAllowance(data)
+}
@@ -0,0 +1,354 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"blockchess/internal/client"
+	"blockchess/internal/ledger"
+	"blockchess/internal/money"
+
+	"github.com/corentings/chess/v2"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// RefundMode decides how much of their stake players get back
+type RefundMode string
+
+const (
+	RefundFull    RefundMode = "full"     // Return each player's full stake
+	RefundProRata RefundMode = "pro_rata" // Share what is left in the pot in proportion to each stake
+	RefundNone    RefundMode = "none"     // Leave the stakes in the vaults
+)
+
+// RefundReason is why a game is refunded instead of paid out
+type RefundReason string
+
+const (
+	RefundReasonDraw             RefundReason = "draw"
+	RefundReasonAbort            RefundReason = "abort"
+	RefundReasonCancel           RefundReason = "cancel"
+	RefundReasonSettlementFailed RefundReason = "settlement_failed"
+	RefundReasonUnstakedWin      RefundReason = "unstaked_win"
+)
+
+// RefundConfig maps each refund reason to the refund mode applied
+type RefundConfig map[RefundReason]RefundMode
+
+// LoadRefundConfig loads the refund rules from environment variables
+func LoadRefundConfig() RefundConfig {
+	return RefundConfig{
+		RefundReasonDraw:             parseRefundMode("REFUND_ON_DRAW", RefundFull),
+		RefundReasonAbort:            parseRefundMode("REFUND_ON_ABORT", RefundFull),
+		RefundReasonCancel:           parseRefundMode("REFUND_ON_CANCEL", RefundFull),
+		RefundReasonSettlementFailed: parseRefundMode("REFUND_ON_SETTLEMENT_FAILURE", RefundProRata),
+		RefundReasonUnstakedWin:      parseRefundMode("REFUND_ON_UNSTAKED_WIN", RefundFull),
+	}
+}
+
+// parseRefundMode reads a refund mode from the environment, falling back to the default for unknown values
+func parseRefundMode(key string, defaultMode RefundMode) RefundMode {
+	mode := RefundMode(client.GetEnv(key, string(defaultMode)))
+	switch mode {
+	case RefundFull, RefundProRata, RefundNone:
+		return mode
+	default:
+		log.Printf("Warning: Unknown %s %q, using %q", key, mode, defaultMode)
+		return defaultMode
+	}
+}
+
+// Refund is a single stake returned to a player
+type Refund struct {
+	WalletAddress string       `json:"walletAddress"`
+	ChainID       uint64       `json:"chainId"`
+	Amount        money.Amount `json:"amount"`
+	TxHash        string       `json:"txHash,omitempty"`
+}
+
+// refundClaim is what a player can still claim back from a game on the chain they staked from
+type refundClaim struct {
+	account ledger.Account
+	amount  money.Amount
+}
+
+// refundReasonFor maps a game outcome that is not a win to its refund reason
+func refundReasonFor(outcome string) RefundReason {
+	switch outcome {
+	case "aborted":
+		return RefundReasonAbort
+	case "cancelled":
+		return RefundReasonCancel
+	default:
+		return RefundReasonDraw
+	}
+}
+
+// planRefundSteps plans settlement steps returning stakes recorded in the ledger to each player's own chain.
+// It also returns the on-chain game ID found in the ledger.
+func (m *Manager) planRefundSteps(gameID string, reason RefundReason, idPrefix string) ([]SettlementStep, uint64, error) {
+	if m.ledger == nil {
+		return nil, 0, fmt.Errorf("no ledger available to compute refunds")
+	}
+
+	// Work out the remaining stake per wallet and the funds left in each pot
+	claimed := make(map[ledger.Account]money.Amount)
+	potBalances := make(map[uint64]money.Amount)
+	chainGame := uint64(0)
+	for _, entry := range m.ledger.Entries(gameID) {
+		if entry.ChainGame != 0 {
+			chainGame = entry.ChainGame
+		}
+		switch entry.Kind {
+		case ledger.KindStake:
+			claimed[entry.From] = claimed[entry.From].Add(entry.Amount)
+		case ledger.KindRefund:
+			claimed[entry.To] = claimed[entry.To].Sub(entry.Amount)
+		}
+		if entry.From.Type == ledger.AccountPot {
+			potBalances[entry.From.ChainID] = potBalances[entry.From.ChainID].Sub(entry.Amount)
+		}
+		if entry.To.Type == ledger.AccountPot {
+			potBalances[entry.To.ChainID] = potBalances[entry.To.ChainID].Add(entry.Amount)
+		}
+	}
+
+	mode := m.refunds[reason]
+	if mode == "" || mode == RefundNone {
+		log.Printf("Refunds disabled for %s in game %s, stakes stay in the vaults", reason, gameID)
+		return nil, chainGame, nil
+	}
+
+	var claims []refundClaim
+	totalClaims := money.USDC(0)
+	for account, amount := range claimed {
+		if amount.IsPositive() {
+			claims = append(claims, refundClaim{account: account, amount: amount})
+			totalClaims = totalClaims.Add(amount)
+		}
+	}
+	if len(claims) == 0 {
+		log.Printf("No stakes left to refund for game %s", gameID)
+		return nil, chainGame, nil
+	}
+	sort.Slice(claims, func(i, j int) bool {
+		return claims[i].account.String() < claims[j].account.String()
+	})
+
+	available := money.USDC(0)
+	for _, balance := range potBalances {
+		available = available.Add(balance)
+	}
+
+	// Pro-rata refunds share whatever is left when the pot no longer covers every stake
+	if mode == RefundProRata && available.Cmp(totalClaims) < 0 {
+		distributed := money.USDC(0)
+		for i := range claims {
+			share, err := claims[i].amount.MulDiv(available.Units(), totalClaims.Units())
+			if err != nil {
+				return nil, 0, err
+			}
+			claims[i].amount = share
+			distributed = distributed.Add(claims[i].amount)
+		}
+		claims[0].amount = claims[0].amount.Add(available.Sub(distributed))
+	}
+
+	log.Printf("Planning refunds for %d players in game %s (%s, %s mode, %s USDC left in pots)",
+		len(claims), gameID, reason, mode, available)
+
+	var steps []SettlementStep
+	var unfunded int
+	for _, claim := range claims {
+		if !claim.amount.IsPositive() {
+			continue
+		}
+
+		sourceChainID, ok := pickRefundSource(potBalances, claim.account.ChainID, claim.amount)
+		if !ok {
+			log.Printf("Warning: No pot holds %s USDC to refund %s in game %s", claim.amount, claim.account, gameID)
+			unfunded++
+			continue
+		}
+		potBalances[sourceChainID] = potBalances[sourceChainID].Sub(claim.amount)
+
+		recipient := common.HexToAddress(claim.account.Owner)
+		steps = append(steps, SettlementStep{
+			ID:            fmt.Sprintf("%s:%s@%d", idPrefix, recipient.Hex(), claim.account.ChainID),
+			Kind:          StepRefund,
+			SourceChainID: sourceChainID,
+			DestChainID:   claim.account.ChainID,
+			Recipient:     recipient.Hex(),
+			Amount:        claim.amount,
+		})
+	}
+
+	if unfunded > 0 {
+		return steps, chainGame, fmt.Errorf("%d of %d refunds have no funded pot in game %s", unfunded, len(claims), gameID)
+	}
+	return steps, chainGame, nil
+}
+
+// pickRefundSource picks the pot a refund is paid from, preferring the player's own chain
+func pickRefundSource(potBalances map[uint64]money.Amount, ownChainID uint64, amount money.Amount) (uint64, bool) {
+	if potBalances[ownChainID].Cmp(amount) >= 0 {
+		return ownChainID, true
+	}
+
+	chainIDs := make([]uint64, 0, len(potBalances))
+	for chainID := range potBalances {
+		chainIDs = append(chainIDs, chainID)
+	}
+	sort.Slice(chainIDs, func(i, j int) bool { return chainIDs[i] < chainIDs[j] })
+
+	for _, chainID := range chainIDs {
+		if potBalances[chainID].Cmp(amount) >= 0 {
+			return chainID, true
+		}
+	}
+	return 0, false
+}
+
+// planLedgerRefund builds a refund settlement job for a game only known to the ledger
+func (m *Manager) planLedgerRefund(gameID string, reason RefundReason) (SettlementJob, error) {
+	steps, chainGame, err := m.planRefundSteps(gameID, reason, "refund")
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	if chainGame == 0 {
+		return SettlementJob{}, fmt.Errorf("no on-chain game recorded for game %s", gameID)
+	}
+
+	now := time.Now().Unix()
+	job := SettlementJob{
+		GameID:    gameID,
+		ChainGame: chainGame,
+		Outcome:   string(reason),
+		Status:    SettlementPending,
+		Steps:     steps,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if m.gameFactory != nil {
+		job.Steps = append(job.Steps, SettlementStep{ID: "end_game", Kind: StepEndGame, Result: client.GameResultDraw})
+	}
+	return job, nil
+}
+
+// refundLateStake adds a refund of a stake mined after its game was settled to the game's settlement job. The job
+// may be running, so it is claimed once it is free.
+func (m *Manager) refundLateStake(stake pendingStake, txHash common.Hash) {
+	stepID := "late-refund:" + txHash.Hex()
+	deadline := time.Now().Add(settlementStuckAfter)
+	for {
+		job, ok := m.settlements.Claim(stake.GameID)
+		if ok {
+			added := addLateRefund(&job, stepID, stake)
+			if added {
+				m.saveSettlement(job)
+			}
+			m.settlements.Release(stake.GameID)
+
+			if added {
+				log.Printf("Stake of %s USDC by %s in game %s was mined after the game was settled, refunding it",
+					stake.Amount, stake.Wallet, stake.GameID)
+				go m.processSettlement(stake.GameID)
+			}
+			return
+		}
+
+		// Archived jobs are never run again
+		if _, exists := m.settlements.Get(stake.GameID); !exists || time.Now().After(deadline) {
+			log.Printf("Error: Stake %s of %s in game %s was mined after the game was settled and could not be added to its settlement, an operator must refund it",
+				txHash.Hex(), stake.Wallet, stake.GameID)
+			return
+		}
+		time.Sleep(settlementPollInterval)
+	}
+}
+
+// addLateRefund appends the refund step of a late stake to a job, reporting false if it was already added
+func addLateRefund(job *SettlementJob, stepID string, stake pendingStake) bool {
+	for _, step := range job.Steps {
+		if step.ID == stepID {
+			return false
+		}
+	}
+
+	recipient := common.HexToAddress(stake.Wallet)
+	job.Steps = append(job.Steps, SettlementStep{
+		ID:            stepID,
+		Kind:          StepRefund,
+		SourceChainID: uint64(stake.ChainID),
+		DestChainID:   uint64(stake.ChainID),
+		Recipient:     recipient.Hex(),
+		Amount:        stake.Amount,
+	})
+	if job.Status == SettlementCompleted {
+		job.Status = SettlementPending
+	}
+	job.NextAttemptAt = 0
+	job.UpdatedAt = time.Now().Unix()
+	return true
+}
+
+// CancelGame stops a live game and refunds its stakes, or refunds a game only known to the ledger
+func (m *Manager) CancelGame(gameID string) ([]Refund, error) {
+	game := m.GetGame(gameID)
+	if game == nil {
+		if m.ledger == nil || len(m.ledger.Entries(gameID)) == 0 {
+			return nil, fmt.Errorf("game %s not found", gameID)
+		}
+		if m.settlements.Has(gameID) {
+			return nil, fmt.Errorf("game %s is already being settled", gameID)
+		}
+
+		log.Printf("Cancelling game %s found only in the ledger", gameID)
+		job, err := m.planLedgerRefund(gameID, RefundReasonCancel)
+		if err != nil {
+			return nil, err
+		}
+		return m.startSettlement(job).Refunds(), nil
+	}
+
+	game.mu.Lock()
+	if game.Cancelled || game.Game.Outcome() != chess.NoOutcome {
+		game.mu.Unlock()
+		return nil, fmt.Errorf("game %s has already ended", gameID)
+	}
+	game.Cancelled = true
+	gameStats := m.getGameStatsUnsafe(game, true)
+	game.mu.Unlock()
+
+	log.Printf("Game %s cancelled by operator", gameID)
+	m.handleGameEnd(gameID, gameStats)
+
+	refunds, _ := gameStats["refunds"].([]Refund)
+	return refunds, nil
+}
+
+// refundOpenGames refunds games left with funds in their pots by a previous run of the server
+func (m *Manager) refundOpenGames() {
+	if m.ledger == nil {
+		return
+	}
+
+	for _, gameID := range m.ledger.OpenGames() {
+		if m.GetGame(gameID) != nil {
+			continue
+		}
+		if m.settlements.Has(gameID) {
+			continue
+		}
+
+		log.Printf("Game %s has unsettled funds from a previous run, refunding as aborted", gameID)
+		job, err := m.planLedgerRefund(gameID, RefundReasonAbort)
+		if err != nil {
+			log.Printf("Warning: Failed to plan refund for aborted game %s: %v", gameID, err)
+			continue
+		}
+		m.startSettlement(job)
+	}
+}
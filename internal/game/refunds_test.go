@@ -0,0 +1,71 @@
+package game
+
+import (
+	"testing"
+
+	"blockchess/internal/money"
+)
+
+func TestPickRefundSourcePrefersOwnChain(t *testing.T) {
+	const base, sepolia = uint64(84532), uint64(11155111)
+
+	tests := []struct {
+		name      string
+		balances  map[uint64]money.Amount
+		ownChain  uint64
+		wantChain uint64
+		wantOK    bool
+	}{
+		{
+			name:      "own chain covers the refund",
+			balances:  map[uint64]money.Amount{base: money.USDC(500), sepolia: money.USDC(100)},
+			ownChain:  sepolia,
+			wantChain: sepolia,
+			wantOK:    true,
+		},
+		{
+			name:      "gathered stake comes from the lowest funded chain",
+			balances:  map[uint64]money.Amount{1: money.USDC(0), base: money.USDC(500), sepolia: money.USDC(50)},
+			ownChain:  sepolia,
+			wantChain: base,
+			wantOK:    true,
+		},
+		{
+			name:     "no single pot is large enough",
+			balances: map[uint64]money.Amount{base: money.USDC(60), sepolia: money.USDC(60)},
+			ownChain: sepolia,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chainID, ok := pickRefundSource(tt.balances, tt.ownChain, money.USDC(100))
+			if ok != tt.wantOK || chainID != tt.wantChain {
+				t.Fatalf("pickRefundSource() = %d, %v, want %d, %v", chainID, ok, tt.wantChain, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestAddLateRefundReopensJobOnce(t *testing.T) {
+	job := SettlementJob{GameID: "game", Status: SettlementCompleted, NextAttemptAt: 100}
+	stake := pendingStake{GameID: "game", Wallet: "0x00000000000000000000000000000000000000aa", ChainID: 84532, Amount: money.USDC(250000)}
+
+	if !addLateRefund(&job, "late-refund:0x01", stake) {
+		t.Fatal("addLateRefund() = false, want the refund added")
+	}
+	if addLateRefund(&job, "late-refund:0x01", stake) {
+		t.Fatal("addLateRefund() added the same stake twice")
+	}
+
+	if job.Status != SettlementPending || job.NextAttemptAt != 0 {
+		t.Fatalf("job status %s next attempt %d, want pending now", job.Status, job.NextAttemptAt)
+	}
+	if len(job.Steps) != 1 {
+		t.Fatalf("job has %d steps, want 1", len(job.Steps))
+	}
+	step := job.Steps[0]
+	if step.Kind != StepRefund || step.SourceChainID != 84532 || step.DestChainID != 84532 || step.Amount != stake.Amount {
+		t.Fatalf("refund step = %+v", step)
+	}
+}
@@ -0,0 +1,804 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"time"
+
+	"blockchess/internal/cctp"
+	"blockchess/internal/client"
+	"blockchess/internal/ledger"
+	"blockchess/internal/money"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	settlementPollInterval   = 5 * time.Second  // How often due settlement jobs are picked up
+	settlementConfirmTimeout = 2 * time.Minute  // How long an attempt waits for its transaction to be mined
+	settlementStuckAfter     = 30 * time.Minute // When a pending transaction needs an operator
+	settlementMintPoll       = 15 * time.Second // How often a gather checks whether CCTP minted it on the settlement chain
+	settlementArchiveAfter   = 24 * time.Hour   // When a completed job moves from the jobs file to the archive
+)
+
+// errStepPending means a step's transaction is still waiting to be mined
+var errStepPending = errors.New("transaction pending")
+
+// errAwaitingMint means a gather was burned but CCTP has not minted it on the settlement chain yet
+var errAwaitingMint = errors.New("waiting for CCTP mint")
+
+// errBatchRemaining means a payout batch was mined but recipients that did not fit in it are still unpaid
+var errBatchRemaining = errors.New("payout batch has recipients left")
+
+// SettlementStatus is the overall state of a settlement job
+type SettlementStatus string
+
+const (
+	SettlementPending       SettlementStatus = "pending"
+	SettlementCompleted     SettlementStatus = "completed"
+	SettlementNeedsOperator SettlementStatus = "needs_operator"
+)
+
+// StepKind is the on-chain action a settlement step performs
+type StepKind string
+
+const (
+	StepGather      StepKind = "gather"       // Move a chain's stakes to the settlement vault
+	StepFee         StepKind = "fee"          // Pay the platform fee to the treasury
+	StepPayout      StepKind = "payout"       // Pay a winner
+	StepPayoutBatch StepKind = "payout_batch" // Pay several winners in one transaction
+	StepRefund      StepKind = "refund"       // Return a stake
+	StepEndGame     StepKind = "end_game"     // Record the result in the GameFactory
+)
+
+// SettlementStep is one idempotent on-chain action of a settlement job
+type SettlementStep struct {
+	ID            string       `json:"id"`
+	Kind          StepKind     `json:"kind"`
+	SourceChainID uint64       `json:"sourceChainId,omitempty"`
+	DestChainID   uint64       `json:"destChainId,omitempty"`
+	Recipient     string       `json:"recipient,omitempty"`
+	Amount        money.Amount `json:"amount"`
+	Result        uint8        `json:"result,omitempty"` // On-chain game result for end_game steps
+	Done          bool         `json:"done"`
+	TxHash        string       `json:"txHash,omitempty"`
+	Nonce         uint64       `json:"nonce,omitempty"`
+	RawTx         string       `json:"rawTx,omitempty"` // Signed vault transaction, recorded before it is broadcast
+	SubmittedAt   int64        `json:"submittedAt,omitempty"`
+	MintedAt      int64        `json:"mintedAt,omitempty"` // When CCTP minted a gather on the settlement chain
+	Attempts      int          `json:"attempts"`
+	LastError     string       `json:"lastError,omitempty"`
+
+	// Recipients of payout_batch steps and the ones included in the transaction in flight
+	Transfers []BatchTransfer `json:"transfers,omitempty"`
+	Sent      []int           `json:"sent,omitempty"`
+}
+
+// SettlementJob is the persisted plan for paying out or refunding one game
+type SettlementJob struct {
+	GameID        string           `json:"gameId"`
+	ChainGame     uint64           `json:"chainGame"`
+	Outcome       string           `json:"outcome"` // "white", "black", "draw", "aborted" or "cancelled"
+	Status        SettlementStatus `json:"status"`
+	Steps         []SettlementStep `json:"steps"`
+	VoteBatches   []VoteBatch      `json:"voteBatches,omitempty"` // How the game's votes were recorded on-chain
+	NextAttemptAt int64            `json:"nextAttemptAt"`
+	LastError     string           `json:"lastError,omitempty"`
+	CreatedAt     int64            `json:"createdAt"`
+	UpdatedAt     int64            `json:"updatedAt"`
+}
+
+// Refunds returns the refunds planned by the job, with tx hashes for those already sent
+func (j SettlementJob) Refunds() []Refund {
+	var refunds []Refund
+	for _, step := range j.Steps {
+		if step.Kind != StepRefund {
+			continue
+		}
+		refund := Refund{
+			WalletAddress: step.Recipient,
+			ChainID:       step.DestChainID,
+			Amount:        step.Amount,
+		}
+		if step.Done {
+			refund.TxHash = step.TxHash
+		}
+		refunds = append(refunds, refund)
+	}
+	return refunds
+}
+
+// SettlementConfig controls settlement retries
+type SettlementConfig struct {
+	MaxAttempts int           // Failed attempts per step before an operator is needed
+	RetryBase   time.Duration // Delay after the first failure, doubled on every retry
+	RetryMax    time.Duration // Upper bound for the retry delay
+
+	PayoutMode     string // One of PayoutModeBatch, PayoutModePerRecipient
+	BatchGasLimit  uint64 // Gas budget of one payout batch transaction
+	GasPerTransfer uint64 // Expected gas per recipient, used to size batches when planning
+}
+
+// LoadSettlementConfig loads the settlement retry settings from environment variables
+func LoadSettlementConfig() SettlementConfig {
+	payoutMode := client.GetEnv("PAYOUT_MODE", PayoutModeBatch)
+	switch payoutMode {
+	case PayoutModeBatch, PayoutModePerRecipient:
+	default:
+		log.Printf("Warning: Unknown PAYOUT_MODE %q, using %q", payoutMode, PayoutModeBatch)
+		payoutMode = PayoutModeBatch
+	}
+
+	return SettlementConfig{
+		MaxAttempts: client.GetEnvInt("SETTLEMENT_MAX_ATTEMPTS", 8),
+		RetryBase:   time.Duration(client.GetEnvInt("SETTLEMENT_RETRY_BASE_SECONDS", 10)) * time.Second,
+		RetryMax:    time.Duration(client.GetEnvInt("SETTLEMENT_RETRY_MAX_SECONDS", 600)) * time.Second,
+
+		PayoutMode:     payoutMode,
+		BatchGasLimit:  uint64(max(client.GetEnvInt("PAYOUT_BATCH_GAS_LIMIT", 3000000), 1)),
+		GasPerTransfer: uint64(max(client.GetEnvInt("PAYOUT_GAS_PER_TRANSFER", 200000), 1)),
+	}
+}
+
+// backoff returns the delay before the next attempt after the given number of failures
+func (c SettlementConfig) backoff(attempts int) time.Duration {
+	delay := c.RetryBase
+	for i := 1; i < attempts && delay < c.RetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, c.RetryMax)
+}
+
+// planSettlement builds the settlement job for a finished game
+func (m *Manager) planSettlement(gameID string, chainGame uint64, outcome, chainResult string, gameStats map[string]any) SettlementJob {
+	now := time.Now().Unix()
+	job := SettlementJob{
+		GameID:    gameID,
+		ChainGame: chainGame,
+		Outcome:   outcome,
+		Status:    SettlementPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if m.vaultManager != nil {
+		switch outcome {
+		case "white", "black":
+			if winnersStaked(outcome, gameStats) {
+				job.Steps = append(job.Steps, m.planPayoutSteps(gameID, outcome, gameStats)...)
+				break
+			}
+			// Nobody on the winning team can be paid, so the losers get their stakes back
+			log.Printf("Game %s was won by %s without stakes, refunding the pot", gameID, outcome)
+			steps, _, err := m.planRefundSteps(gameID, RefundReasonUnstakedWin, "refund")
+			if err != nil {
+				log.Printf("Warning: %v", err)
+			}
+			job.Steps = append(job.Steps, steps...)
+		default:
+			steps, _, err := m.planRefundSteps(gameID, refundReasonFor(outcome), "refund")
+			if err != nil {
+				log.Printf("Warning: %v", err)
+			}
+			job.Steps = append(job.Steps, steps...)
+		}
+	}
+
+	if m.gameFactory != nil {
+		result, err := client.ResultStringToUint8(chainResult)
+		if err != nil {
+			log.Printf("Warning: Failed to convert result '%s' to uint8: %v", chainResult, err)
+		} else {
+			job.Steps = append(job.Steps, SettlementStep{ID: "end_game", Kind: StepEndGame, Result: result})
+		}
+	}
+
+	return job
+}
+
+// winnersStaked reports whether any player on the winning team has a stake mined into the vaults, bot teams never stake
+func winnersStaked(winner string, gameStats map[string]any) bool {
+	players, _ := gameStats[winner+"TeamPlayers"].([]map[string]any)
+	for _, player := range players {
+		if staked, ok := player["confirmedStake"].(money.Amount); ok && staked.IsPositive() {
+			return true
+		}
+	}
+	return false
+}
+
+// confirmedPot returns the sum of the stakes mined into every chain's vault
+func confirmedPot(gameStats map[string]any) money.Amount {
+	chainStakes, _ := gameStats["chainStakes"].(map[uint64]money.Amount)
+	total := money.USDC(0)
+	for _, amount := range chainStakes {
+		total = total.Add(amount)
+	}
+	return total
+}
+
+// planPayoutSteps gathers every chain's stakes to Base Sepolia, then pays the fee and the winners from there
+func (m *Manager) planPayoutSteps(gameID, winner string, gameStats map[string]any) []SettlementStep {
+	// Rewards are paid from the vault on the GameFactory chain
+	baseSepoliaChainID := client.GameFactoryChainID
+	baseVaultAddress := common.HexToAddress(client.GetVaultAddress(baseSepoliaChainID))
+
+	var steps []SettlementStep
+
+	chainStakes, _ := gameStats["chainStakes"].(map[uint64]money.Amount)
+	var chainIDs []uint64
+	for chainID, amount := range chainStakes {
+		if chainID != baseSepoliaChainID && amount.IsPositive() {
+			chainIDs = append(chainIDs, chainID)
+		}
+	}
+	sort.Slice(chainIDs, func(i, j int) bool { return chainIDs[i] < chainIDs[j] })
+
+	for _, chainID := range chainIDs {
+		steps = append(steps, SettlementStep{
+			ID:            fmt.Sprintf("gather:%d", chainID),
+			Kind:          StepGather,
+			SourceChainID: chainID,
+			DestChainID:   baseSepoliaChainID,
+			Recipient:     baseVaultAddress.Hex(),
+			Amount:        chainStakes[chainID],
+		})
+	}
+
+	var payouts []SettlementStep
+	for _, transfer := range m.planRewardTransfers(gameID, winner, gameStats) {
+		step := SettlementStep{
+			ID:            fmt.Sprintf("payout:%s@%d", transfer.Recipient.Hex(), transfer.DestinationChain),
+			Kind:          StepPayout,
+			SourceChainID: baseSepoliaChainID,
+			DestChainID:   transfer.DestinationChain,
+			Recipient:     transfer.Recipient.Hex(),
+			Amount:        transfer.Amount,
+		}
+		if transfer.Kind == ledger.KindFee {
+			step.ID = "fee"
+			step.Kind = StepFee
+			steps = append(steps, step)
+			continue
+		}
+		payouts = append(payouts, step)
+	}
+
+	if m.settlementConfig.PayoutMode == PayoutModeBatch {
+		payouts = m.batchPayoutSteps(payouts)
+	}
+	return append(steps, payouts...)
+}
+
+// startSettlement persists a new job and makes the first attempt in the background, returning the job as planned
+func (m *Manager) startSettlement(job SettlementJob) SettlementJob {
+	if err := m.settlements.Add(job); err != nil {
+		log.Printf("Warning: %v", err)
+		current, _ := m.settlements.Get(job.GameID)
+		return current
+	}
+
+	// Steps wait for confirmations and mints, which must not hold up the game end broadcast or the caller
+	log.Printf("Settlement of game %s planned with %d steps", job.GameID, len(job.Steps))
+	go m.processSettlement(job.GameID)
+	return job
+}
+
+// runSettlements retries due settlement jobs until the server stops
+func (m *Manager) runSettlements() {
+	ticker := time.NewTicker(settlementPollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, gameID := range m.settlements.Due(time.Now()) {
+			m.processSettlement(gameID)
+		}
+
+		if archived, err := m.settlements.Archive(time.Now().Add(-settlementArchiveAfter)); err != nil {
+			log.Printf("Warning: Failed to archive settlement jobs: %v", err)
+		} else if archived > 0 {
+			log.Printf("Archived %d completed settlement jobs", archived)
+		}
+	}
+}
+
+// processSettlement runs the remaining steps of a job in order until one fails or is still pending
+func (m *Manager) processSettlement(gameID string) {
+	job, ok := m.settlements.Claim(gameID)
+	if !ok {
+		return
+	}
+	defer m.settlements.Release(gameID)
+
+	if job.Status != SettlementPending {
+		return
+	}
+
+	for i := range job.Steps {
+		step := &job.Steps[i]
+		if step.Done {
+			continue
+		}
+
+		err := m.runSettlementStep(&job, step)
+		now := time.Now()
+		job.UpdatedAt = now.Unix()
+
+		switch {
+		case errors.Is(err, errBatchRemaining):
+			// Send the recipients that did not fit on the next pass
+			step.LastError = ""
+			job.NextAttemptAt = now.Unix()
+			m.saveSettlement(job)
+			return
+
+		case errors.Is(err, errAwaitingMint):
+			// Later steps pay from the gathered funds, so they wait for the relayer; it reports failed mints itself
+			step.LastError = ""
+			job.NextAttemptAt = now.Add(settlementMintPoll).Unix()
+			m.saveSettlement(job)
+			return
+
+		case errors.Is(err, errStepPending):
+			if now.Sub(time.Unix(step.SubmittedAt, 0)) > settlementStuckAfter {
+				job.Status = SettlementNeedsOperator
+				job.LastError = fmt.Sprintf("%s: transaction %s pending for over %s", step.ID, step.TxHash, settlementStuckAfter)
+				log.Printf("Error: Settlement of game %s needs an operator: %s", gameID, job.LastError)
+			} else {
+				job.NextAttemptAt = now.Add(settlementPollInterval).Unix()
+			}
+			m.saveSettlement(job)
+			return
+
+		case err != nil:
+			step.Attempts++
+			step.LastError = err.Error()
+			job.LastError = fmt.Sprintf("%s: %v", step.ID, err)
+			if step.Attempts >= m.settlementConfig.MaxAttempts {
+				job.Status = SettlementNeedsOperator
+				log.Printf("Error: Settlement of game %s needs an operator, step %s failed %d times: %v",
+					gameID, step.ID, step.Attempts, err)
+			} else {
+				delay := m.settlementConfig.backoff(step.Attempts)
+				job.NextAttemptAt = now.Add(delay).Unix()
+				log.Printf("Warning: Settlement step %s of game %s failed (attempt %d), retrying in %s: %v",
+					step.ID, gameID, step.Attempts, delay, err)
+			}
+			m.saveSettlement(job)
+			return
+		}
+
+		step.LastError = ""
+		step.RawTx = ""
+		m.saveSettlement(job)
+	}
+
+	job.Status = SettlementCompleted
+	job.LastError = ""
+	m.saveSettlement(job)
+	log.Printf("Settlement of game %s completed", gameID)
+
+	if m.ledger != nil {
+		if err := m.ledger.CheckGame(gameID); err != nil {
+			log.Printf("Warning: Ledger invariant failed: %v", err)
+		}
+	}
+}
+
+// runSettlementStep reconciles a step against chain state, then sends it if it has not taken effect yet
+func (m *Manager) runSettlementStep(job *SettlementJob, step *SettlementStep) error {
+	// Reconcile the transaction sent by an earlier attempt before sending anything new
+	if step.TxHash != "" {
+		state, err := m.settlementTxState(step)
+		if err != nil {
+			return err
+		}
+
+		switch state {
+		case client.TxSucceeded:
+			return m.completeSettlementStep(job, step)
+		case client.TxPending:
+			m.rebroadcastSettlementStep(step)
+			return errStepPending
+		case client.TxReverted:
+			txHash := step.TxHash
+			step.TxHash, step.Nonce, step.SubmittedAt, step.RawTx = "", 0, 0, ""
+			return fmt.Errorf("transaction %s reverted", txHash)
+		case client.TxDropped:
+			log.Printf("Warning: Transaction %s of settlement step %s was dropped, resending", step.TxHash, step.ID)
+			step.TxHash, step.Nonce, step.SubmittedAt, step.RawTx = "", 0, 0, ""
+		}
+	}
+
+	// Check chain state so steps that already took effect are not repeated
+	switch step.Kind {
+	case StepEndGame:
+		active, err := m.gameFactory.IsGameActive(job.ChainGame)
+		if err != nil {
+			return err
+		}
+		if !active {
+			log.Printf("Blockchain game %d already ended", job.ChainGame)
+			step.Done = true
+			return nil
+		}
+	case StepGather:
+		vault, err := m.getSettlementVault(step.SourceChainID)
+		if err != nil {
+			return err
+		}
+		totalStakes, err := vault.GetTotalStakes()
+		if err != nil {
+			return err
+		}
+		need, err := client.USDCUnits(step.SourceChainID, step.Amount)
+		if err != nil {
+			return err
+		}
+		if totalStakes.Cmp(need) < 0 {
+			return fmt.Errorf("vault on chain %d holds %s USDC base units, need %s USDC",
+				step.SourceChainID, totalStakes.String(), step.Amount)
+		}
+	case StepFee, StepPayout, StepPayoutBatch:
+		// Gathered stakes are minted to the vault without being staked, so check what it really holds
+		vault, err := m.getSettlementVault(step.SourceChainID)
+		if err != nil {
+			return err
+		}
+		balance, err := vault.USDCBalance()
+		if err != nil {
+			return err
+		}
+		need := step.unpaidAmount()
+		needUnits, err := client.USDCUnits(step.SourceChainID, need)
+		if err != nil {
+			return err
+		}
+		if balance.Cmp(needUnits) < 0 {
+			return fmt.Errorf("vault on chain %d holds %s USDC base units, need %s USDC",
+				step.SourceChainID, balance.String(), need)
+		}
+	}
+
+	tx, err := m.sendSettlementStep(job, step)
+	if err != nil {
+		return err
+	}
+
+	// Persist the hash before waiting so a restart reconciles instead of resending
+	step.TxHash = tx.Hash().Hex()
+	step.Nonce = tx.Nonce()
+	step.SubmittedAt = time.Now().Unix()
+	m.saveSettlement(*job)
+
+	return m.waitSettlementStep(job, step)
+}
+
+// sendSettlementStep submits the transaction for a step
+func (m *Manager) sendSettlementStep(job *SettlementJob, step *SettlementStep) (*types.Transaction, error) {
+	if step.Kind == StepEndGame {
+		if m.gameFactory == nil {
+			return nil, fmt.Errorf("no GameFactory available")
+		}
+		return m.gameFactory.SendEndGame(job.ChainGame, step.Result)
+	}
+
+	if step.Kind == StepPayoutBatch {
+		return m.sendPayoutBatch(job, step)
+	}
+
+	vault, err := m.getSettlementVault(step.SourceChainID)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := client.USDCUnits(step.SourceChainID, step.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	useFastTransfer := false // Use standard transfer for lower fees
+	maxFee := big.NewInt(0)  // Let the contract determine the fee
+	return vault.SendTransferRewards(job.ChainGame, amount, step.DestChainID,
+		common.HexToAddress(step.Recipient), useFastTransfer, maxFee, m.recordSignedStep(job, step))
+}
+
+// recordSignedStep returns a hook persisting a step's signed transaction before it is broadcast, so a restart
+// rebroadcasts or reconciles it instead of paying the recipient twice
+func (m *Manager) recordSignedStep(job *SettlementJob, step *SettlementStep) func(tx *types.Transaction) error {
+	return func(tx *types.Transaction) error {
+		rawTx, err := tx.MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("failed to encode transaction: %w", err)
+		}
+		step.TxHash = tx.Hash().Hex()
+		step.Nonce = tx.Nonce()
+		step.SubmittedAt = time.Now().Unix()
+		step.RawTx = hexutil.Encode(rawTx)
+		if err := m.settlements.Save(*job); err != nil {
+			step.TxHash, step.Nonce, step.SubmittedAt, step.RawTx = "", 0, 0, ""
+			return err
+		}
+		return nil
+	}
+}
+
+// rebroadcastSettlementStep sends a recorded transaction again when a restart may have interrupted its broadcast
+func (m *Manager) rebroadcastSettlementStep(step *SettlementStep) {
+	if step.RawTx == "" {
+		return
+	}
+	rawTx, err := hexutil.Decode(step.RawTx)
+	if err != nil {
+		log.Printf("Warning: Recorded transaction of settlement step %s is malformed: %v", step.ID, err)
+		return
+	}
+	var tx types.Transaction
+	if err := tx.UnmarshalBinary(rawTx); err != nil {
+		log.Printf("Warning: Recorded transaction of settlement step %s is malformed: %v", step.ID, err)
+		return
+	}
+	// A fee-bumped replacement means the recorded version was broadcast
+	if tx.Hash().Hex() != step.TxHash {
+		return
+	}
+
+	vault, err := m.getSettlementVault(step.SourceChainID)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+	if err := vault.Rebroadcast(&tx); err != nil {
+		log.Printf("Warning: Failed to rebroadcast transaction of settlement step %s: %v", step.ID, err)
+	}
+}
+
+// waitSettlementStep polls a step's transaction until it is mined or the confirmation timeout passes
+func (m *Manager) waitSettlementStep(job *SettlementJob, step *SettlementStep) error {
+	deadline := time.Now().Add(settlementConfirmTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(2 * time.Second)
+
+		sentHash := step.TxHash
+		state, err := m.settlementTxState(step)
+		if err != nil {
+			log.Printf("Warning: Failed to check settlement transaction %s: %v", step.TxHash, err)
+			continue
+		}
+		if step.TxHash != sentHash {
+			// Persist fee-bumped replacements so a restart reconciles the right transaction
+			m.saveSettlement(*job)
+		}
+
+		switch state {
+		case client.TxSucceeded:
+			return m.completeSettlementStep(job, step)
+		case client.TxReverted, client.TxDropped:
+			txHash := step.TxHash
+			step.TxHash, step.Nonce, step.SubmittedAt, step.RawTx = "", 0, 0, ""
+			return fmt.Errorf("transaction %s %s", txHash, state)
+		}
+	}
+	return errStepPending
+}
+
+// settlementTxState reports the on-chain state of a step's transaction, following fee-bumped replacements
+func (m *Manager) settlementTxState(step *SettlementStep) (client.TxState, error) {
+	var state client.TxState
+	var txHash common.Hash
+	var err error
+	if step.Kind == StepEndGame {
+		if m.gameFactory == nil {
+			return client.TxPending, fmt.Errorf("no GameFactory available")
+		}
+		state, txHash, err = m.gameFactory.TransactionState(common.HexToHash(step.TxHash), step.Nonce)
+	} else {
+		vault, vaultErr := m.getSettlementVault(step.SourceChainID)
+		if vaultErr != nil {
+			return client.TxPending, vaultErr
+		}
+		state, txHash, err = vault.TransactionState(common.HexToHash(step.TxHash), step.Nonce)
+	}
+	if err != nil {
+		return state, err
+	}
+
+	step.TxHash = txHash.Hex()
+	return state, nil
+}
+
+// getSettlementVault returns the vault a step sends funds from
+func (m *Manager) getSettlementVault(chainID uint64) (*client.Vault, error) {
+	if m.vaultManager == nil {
+		return nil, fmt.Errorf("no vault manager available")
+	}
+	return m.vaultManager.GetVault(chainID)
+}
+
+// completeSettlementStep marks a step as done and records its transfer in the ledger exactly once
+func (m *Manager) completeSettlementStep(job *SettlementJob, step *SettlementStep) error {
+	if step.Kind == StepPayoutBatch {
+		return m.completePayoutBatch(job, step)
+	}
+
+	if step.Kind == StepEndGame {
+		step.Done = true
+		log.Printf("Settlement step %s of game %s confirmed in %s", step.ID, job.GameID, step.TxHash)
+		return nil
+	}
+
+	// Vault transfers burn USDC through CCTP, relay them so the recipient actually gets minted funds
+	if m.relayer != nil {
+		burnTxHash := common.HexToHash(step.TxHash)
+		if _, tracked := m.relayer.BurnStatus(burnTxHash); !tracked {
+			if err := m.relayer.Track(job.GameID, step.SourceChainID, []uint64{step.DestChainID}, burnTxHash); err != nil {
+				if step.Kind == StepGather {
+					return fmt.Errorf("failed to track CCTP transfer: %w", err)
+				}
+				log.Printf("Warning: Failed to track CCTP transfer of step %s in game %s: %v", step.ID, job.GameID, err)
+			}
+		}
+	}
+
+	m.recordSettlementEntry(job, step)
+
+	if step.Kind == StepGather {
+		return m.awaitGatherMint(job, step)
+	}
+	step.Done = true
+	log.Printf("Settlement step %s of game %s confirmed in %s", step.ID, job.GameID, step.TxHash)
+	return nil
+}
+
+// awaitGatherMint marks a gather as done once CCTP minted it on the settlement chain, so nothing is paid from funds still in transit
+func (m *Manager) awaitGatherMint(job *SettlementJob, step *SettlementStep) error {
+	if m.relayer == nil {
+		// Mints are relayed elsewhere and cannot be observed here
+		step.Done = true
+		log.Printf("Settlement step %s of game %s confirmed in %s", step.ID, job.GameID, step.TxHash)
+		return nil
+	}
+
+	status, tracked := m.relayer.BurnStatus(common.HexToHash(step.TxHash))
+	switch {
+	case !tracked:
+		return fmt.Errorf("burn %s is not tracked by the CCTP relayer", step.TxHash)
+	case status == cctp.TransferFailed:
+		return fmt.Errorf("CCTP transfer of burn %s failed, retry it in the relayer", step.TxHash)
+	case status != cctp.TransferMinted:
+		return errAwaitingMint
+	}
+
+	step.Done = true
+	step.MintedAt = time.Now().Unix()
+	log.Printf("Settlement step %s of game %s minted on chain %d", step.ID, job.GameID, step.DestChainID)
+	return nil
+}
+
+// recordSettlementEntry records the transfer of a confirmed step in the ledger exactly once
+func (m *Manager) recordSettlementEntry(job *SettlementJob, step *SettlementStep) {
+	if m.ledger == nil {
+		return
+	}
+
+	ref := job.GameID + "/" + step.ID
+	if m.ledger.HasRef(ref) {
+		return
+	}
+
+	entry := ledger.Entry{
+		GameID:    job.GameID,
+		ChainGame: job.ChainGame,
+		From:      ledger.Pot(job.GameID, step.SourceChainID),
+		Amount:    step.Amount,
+		TxHash:    step.TxHash,
+		Ref:       ref,
+	}
+	switch step.Kind {
+	case StepGather:
+		entry.Kind = ledger.KindGather
+		entry.To = ledger.Pot(job.GameID, step.DestChainID)
+	case StepFee:
+		entry.Kind = ledger.KindFee
+		entry.To = ledger.Treasury(step.Recipient, step.DestChainID)
+	case StepPayout:
+		entry.Kind = ledger.KindPayout
+		entry.To = ledger.Wallet(step.Recipient, step.DestChainID)
+	case StepRefund:
+		entry.Kind = ledger.KindRefund
+		entry.To = ledger.Wallet(step.Recipient, step.DestChainID)
+	}
+	m.recordLedgerEntry(entry)
+}
+
+// saveSettlement persists a job, logging instead of failing the caller
+func (m *Manager) saveSettlement(job SettlementJob) {
+	if err := m.settlements.Save(job); err != nil {
+		log.Printf("Warning: Failed to save settlement of game %s: %v", job.GameID, err)
+	}
+}
+
+// SettlementJobs returns all settlement jobs, oldest first
+func (m *Manager) SettlementJobs() []SettlementJob {
+	return m.settlements.List()
+}
+
+// RetrySettlement resets the attempt counters of a job and runs it again
+func (m *Manager) RetrySettlement(gameID string) error {
+	job, ok := m.settlements.Claim(gameID)
+	if !ok {
+		return fmt.Errorf("settlement of game %s not found or busy", gameID)
+	}
+	if job.Status == SettlementCompleted {
+		m.settlements.Release(gameID)
+		return fmt.Errorf("settlement of game %s is already completed", gameID)
+	}
+
+	for i := range job.Steps {
+		job.Steps[i].Attempts = 0
+	}
+	job.Status = SettlementPending
+	job.NextAttemptAt = 0
+	job.UpdatedAt = time.Now().Unix()
+	m.saveSettlement(job)
+	m.settlements.Release(gameID)
+
+	log.Printf("Operator retrying settlement of game %s", gameID)
+	go m.processSettlement(gameID)
+	return nil
+}
+
+// RefundSettlement replaces the unfinished transfers of a stuck job with refunds of what is left in the pots
+func (m *Manager) RefundSettlement(gameID string) error {
+	job, ok := m.settlements.Claim(gameID)
+	if !ok {
+		return fmt.Errorf("settlement of game %s not found or busy", gameID)
+	}
+	defer m.settlements.Release(gameID)
+
+	if job.Status == SettlementCompleted {
+		return fmt.Errorf("settlement of game %s is already completed", gameID)
+	}
+
+	// Keep finished steps and the end game step, refusing while a transfer may still land
+	var kept []SettlementStep
+	var endGame []SettlementStep
+	for _, step := range job.Steps {
+		switch {
+		case step.Done:
+			kept = append(kept, step)
+		case step.Kind == StepEndGame:
+			step.Attempts = 0
+			endGame = append(endGame, step)
+		case step.TxHash != "":
+			return fmt.Errorf("step %s has transaction %s in flight, retry the settlement first", step.ID, step.TxHash)
+		case step.Kind == StepPayoutBatch:
+			// Keep the recipients a batch already paid
+			if paid := step.paidTransfers(); len(paid) > 0 {
+				step.Transfers, step.Sent, step.Done = paid, nil, true
+				kept = append(kept, step)
+			}
+		}
+	}
+
+	refunds, _, err := m.planRefundSteps(gameID, RefundReasonSettlementFailed, fmt.Sprintf("refund-%d", time.Now().Unix()))
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	job.Steps = append(append(kept, refunds...), endGame...)
+	job.Status = SettlementPending
+	job.NextAttemptAt = 0
+	job.LastError = ""
+	job.UpdatedAt = time.Now().Unix()
+	m.saveSettlement(job)
+
+	log.Printf("Operator switched settlement of game %s to %d refunds", gameID, len(refunds))
+	go m.processSettlement(gameID)
+	return nil
+}
@@ -0,0 +1,272 @@
+package game
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// settlementStore persists settlement jobs as a JSON file and tracks which jobs are being processed.
+// Completed jobs are moved to an append-only archive so the file rewritten on every save stays small.
+type settlementStore struct {
+	mu       sync.Mutex
+	path     string
+	jobs     map[string]SettlementJob // gameID -> job
+	running  map[string]bool          // gameID -> true while a goroutine processes the job
+	archived map[string]bool          // gameID -> true once the completed job was archived
+}
+
+// newSettlementStore loads the jobs stored at path and the games archived next to it. An empty path keeps jobs in memory only.
+func newSettlementStore(path string) (*settlementStore, error) {
+	store := &settlementStore{
+		path:     path,
+		jobs:     make(map[string]SettlementJob),
+		running:  make(map[string]bool),
+		archived: make(map[string]bool),
+	}
+	if path == "" {
+		return store, nil
+	}
+
+	if err := store.loadArchive(); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settlement jobs: %w", err)
+	}
+
+	var jobs []SettlementJob
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse settlement jobs: %w", err)
+	}
+	for _, job := range jobs {
+		store.jobs[job.GameID] = job
+	}
+	return store, nil
+}
+
+// archivePath returns the file completed jobs are appended to, e.g. data/settlements.archive.jsonl
+func (s *settlementStore) archivePath() string {
+	return strings.TrimSuffix(s.path, filepath.Ext(s.path)) + ".archive.jsonl"
+}
+
+// loadArchive reads which games have archived jobs
+func (s *settlementStore) loadArchive() error {
+	data, err := os.ReadFile(s.archivePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read settlement archive: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var job struct {
+			GameID string `json:"gameId"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &job); err != nil {
+			return fmt.Errorf("failed to parse settlement archive: %w", err)
+		}
+		s.archived[job.GameID] = true
+	}
+	return scanner.Err()
+}
+
+// Has reports whether a game has a settlement job, including archived ones
+func (s *settlementStore) Has(gameID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, exists := s.jobs[gameID]
+	return exists || s.archived[gameID]
+}
+
+// Get returns a copy of the job for a game
+func (s *settlementStore) Get(gameID string) (SettlementJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, exists := s.jobs[gameID]
+	return cloneJob(job), exists
+}
+
+// List returns copies of all jobs, oldest first
+func (s *settlementStore) List() []SettlementJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]SettlementJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, cloneJob(job))
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt < jobs[j].CreatedAt
+	})
+	return jobs
+}
+
+// Add stores a new job, failing if the game already has one
+func (s *settlementStore) Add(job SettlementJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[job.GameID]; exists || s.archived[job.GameID] {
+		return fmt.Errorf("game %s already has a settlement job", job.GameID)
+	}
+	s.jobs[job.GameID] = cloneJob(job)
+	return s.persistUnsafe()
+}
+
+// Save replaces a job and persists all jobs
+func (s *settlementStore) Save(job SettlementJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[job.GameID] = cloneJob(job)
+	return s.persistUnsafe()
+}
+
+// Claim marks a job as being processed and returns a copy, failing if another goroutine holds it
+func (s *settlementStore) Claim(gameID string) (SettlementJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, exists := s.jobs[gameID]
+	if !exists || s.running[gameID] {
+		return SettlementJob{}, false
+	}
+	s.running[gameID] = true
+	return cloneJob(job), true
+}
+
+// Release marks a claimed job as no longer being processed
+func (s *settlementStore) Release(gameID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.running, gameID)
+}
+
+// Due returns the games whose pending jobs are ready for another attempt
+func (s *settlementStore) Due(now time.Time) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var gameIDs []string
+	for gameID, job := range s.jobs {
+		if job.Status == SettlementPending && !s.running[gameID] && job.NextAttemptAt <= now.Unix() {
+			gameIDs = append(gameIDs, gameID)
+		}
+	}
+	sort.Strings(gameIDs)
+	return gameIDs
+}
+
+// Archive moves jobs completed before the cutoff to the archive file, returning how many were moved
+func (s *settlementStore) Archive(cutoff time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var done []SettlementJob
+	for gameID, job := range s.jobs {
+		if job.Status == SettlementCompleted && !s.running[gameID] && job.UpdatedAt < cutoff.Unix() {
+			done = append(done, job)
+		}
+	}
+	if len(done) == 0 {
+		return 0, nil
+	}
+	sort.Slice(done, func(i, j int) bool {
+		return done[i].GameID < done[j].GameID
+	})
+
+	// Append before dropping the jobs, a crash in between only leaves a job in both files
+	if s.path != "" {
+		var buf bytes.Buffer
+		for _, job := range done {
+			data, err := json.Marshal(job)
+			if err != nil {
+				return 0, fmt.Errorf("failed to encode settlement job: %w", err)
+			}
+			buf.Write(data)
+			buf.WriteByte('\n')
+		}
+		if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+			return 0, fmt.Errorf("failed to create settlement directory: %w", err)
+		}
+		file, err := os.OpenFile(s.archivePath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open settlement archive: %w", err)
+		}
+		_, err = file.Write(buf.Bytes())
+		if syncErr := file.Sync(); err == nil {
+			err = syncErr
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to write settlement archive: %w", err)
+		}
+	}
+
+	for _, job := range done {
+		delete(s.jobs, job.GameID)
+		s.archived[job.GameID] = true
+	}
+	return len(done), s.persistUnsafe()
+}
+
+// persistUnsafe atomically rewrites the jobs file (caller must hold the lock)
+func (s *settlementStore) persistUnsafe() error {
+	if s.path == "" {
+		return nil
+	}
+
+	jobs := make([]SettlementJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].GameID < jobs[j].GameID
+	})
+
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode settlement jobs: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create settlement directory: %w", err)
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write settlement jobs: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to replace settlement jobs: %w", err)
+	}
+	return nil
+}
+
+// cloneJob copies a job so callers can modify its steps without racing the store
+func cloneJob(job SettlementJob) SettlementJob {
+	job.Steps = slices.Clone(job.Steps)
+	return job
+}
@@ -0,0 +1,256 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"blockchess/internal/client"
+	"blockchess/internal/ledger"
+	"blockchess/internal/money"
+
+	"github.com/corentings/chess/v2"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	stakeQueueSize    = 1024            // Votes that can wait for a stake worker before VoteForMove refuses new ones
+	stakeDrainTimeout = 3 * time.Minute // How long a finished game waits for its in-flight stakes before settling
+	stakeDrainPoll    = 200 * time.Millisecond
+)
+
+// pendingStake is a vote accepted optimistically while its stake and on-chain vote are sent in the background
+type pendingStake struct {
+	GameID    string
+	ChainGame uint64
+	Wallet    string
+	Move      string
+	Team      string
+	ChainID   uint32
+	Round     int // Move number the vote was cast for
+	Amount    money.Amount
+	Transfer  *client.PermitTransferData // Signed one-shot Permit2 transfer, nil when the stake draws from the allowance
+}
+
+// VoteRejection tells a player their vote was withdrawn because its stake failed
+type VoteRejection struct {
+	GameID        string
+	WalletAddress string
+	Move          string
+	ChainID       uint32
+	Reason        string
+	Code          string      // Set when the player must act, e.g. "permit_exhausted" asks for a new permit
+	Funds         *FundsError // Set when the player's balance or allowance cannot pay the stake
+	RolledBack    bool        // False when the round had already closed and the move was played
+}
+
+// stakeOutbox queues stakes for the workers and tracks how many are still in flight per game
+type stakeOutbox struct {
+	queue    chan pendingStake
+	mu       sync.Mutex
+	inFlight map[string]int // gameID -> stakes queued or being sent
+}
+
+// newStakeOutbox creates an empty stake outbox
+func newStakeOutbox() *stakeOutbox {
+	return &stakeOutbox{
+		queue:    make(chan pendingStake, stakeQueueSize),
+		inFlight: make(map[string]int),
+	}
+}
+
+// enqueue adds a stake to the queue without blocking, failing when the queue is full
+func (o *stakeOutbox) enqueue(stake pendingStake) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	select {
+	case o.queue <- stake:
+		o.inFlight[stake.GameID]++
+		return nil
+	default:
+		return fmt.Errorf("too many votes waiting to be staked, please retry")
+	}
+}
+
+// done marks a stake of a game as processed
+func (o *stakeOutbox) done(gameID string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.inFlight[gameID]--
+	if o.inFlight[gameID] <= 0 {
+		delete(o.inFlight, gameID)
+	}
+}
+
+// pending returns the number of stakes of a game that are still queued or being sent
+func (o *stakeOutbox) pending(gameID string) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.inFlight[gameID]
+}
+
+// waitForGame blocks until every stake of a game is processed, reporting false on timeout
+func (o *stakeOutbox) waitForGame(gameID string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for o.pending(gameID) > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(stakeDrainPoll)
+	}
+	return true
+}
+
+// SetVoteRejectedCallback sets the callback for telling players their vote was withdrawn
+func (m *Manager) SetVoteRejectedCallback(callback func(rejection VoteRejection)) {
+	m.voteRejectedCallback = callback
+}
+
+// runStakeWorker sends queued stakes until the server stops
+func (m *Manager) runStakeWorker() {
+	for stake := range m.stakes.queue {
+		m.processStake(stake)
+		m.stakes.done(stake.GameID)
+	}
+}
+
+// processStake stakes a vote's USDC in the player's vault and records the vote on-chain, rolling the vote back on failure
+func (m *Manager) processStake(stake pendingStake) {
+	if m.vaultManager != nil && stake.ChainID != 0 {
+		vault, err := m.vaultManager.GetVault(uint64(stake.ChainID))
+		if err != nil {
+			// Without a vault nothing can be staked, so the vote must not stay in the pot
+			log.Printf("Error: Failed to get vault for chain %d: %v", stake.ChainID, err)
+			if stake.Transfer != nil {
+				m.releaseVotePermitNonce(stake.Transfer)
+			}
+			m.rejectVote(stake, fmt.Errorf("no vault available on chain %d: %w", stake.ChainID, err))
+			return
+		}
+
+		// Refuse stakes the player cannot pay before sending them, the balance and allowance reads are cached
+		if err := m.checkFunds(stake.Wallet, stake.ChainID, stake.Amount, stake.Transfer != nil); err != nil {
+			log.Printf("Vote of %s in game %s cannot be paid: %v", stake.Wallet, stake.GameID, err)
+			if stake.Transfer != nil {
+				m.releaseVotePermitNonce(stake.Transfer)
+			}
+			m.rejectVote(stake, err)
+			return
+		}
+
+		// One-shot permits carry their own signature, everyone else draws from their allowance
+		var txHash common.Hash
+		if stake.Transfer != nil {
+			txHash, err = m.stakeWithTransfer(stake, vault)
+		} else {
+			txHash, err = m.stakeWithAllowance(stake, vault)
+		}
+		if err != nil {
+			log.Printf("Error: Failed to stake for %s to vault on chain %d: %v", stake.Wallet, stake.ChainID, err)
+			m.rejectVote(stake, err)
+			return
+		}
+
+		log.Printf("Successfully staked %s USDC for player %s on chain %d using Permit2", stake.Amount, stake.Wallet, stake.ChainID)
+		m.confirmStake(stake, txHash)
+	}
+
+	// Record the vote on-chain with the round's batch, or on its own
+	m.recordChainVote(stake)
+}
+
+// confirmStake adds a mined stake to the game's per-chain stakes and the ledger
+func (m *Manager) confirmStake(stake pendingStake, txHash common.Hash) {
+	m.funds.invalidate(stake.ChainID, stake.Wallet)
+
+	if game := m.GetGame(stake.GameID); game != nil {
+		game.mu.Lock()
+		game.ChainStakes[uint64(stake.ChainID)] = game.ChainStakes[uint64(stake.ChainID)].Add(stake.Amount)
+		game.PlayerConfirmedStakes[stake.Wallet] = game.PlayerConfirmedStakes[stake.Wallet].Add(stake.Amount)
+		game.mu.Unlock()
+	}
+
+	m.recordLedgerEntry(ledger.Entry{
+		GameID:    stake.GameID,
+		ChainGame: stake.ChainGame,
+		Kind:      ledger.KindStake,
+		From:      ledger.Wallet(stake.Wallet, uint64(stake.ChainID)),
+		To:        ledger.Pot(stake.GameID, uint64(stake.ChainID)),
+		Amount:    stake.Amount,
+		TxHash:    txHash.Hex(),
+	})
+
+	// The settlement no longer counts this stake, so it is paid back like any other refund
+	if m.settlements.Has(stake.GameID) {
+		go m.refundLateStake(stake, txHash)
+	}
+}
+
+// rejectVote withdraws a vote whose stake failed and notifies the player.
+// The vote is only taken out of the round if the round is still open, but the unpaid stake never counts toward the pot.
+func (m *Manager) rejectVote(stake pendingStake, cause error) {
+	game := m.GetGame(stake.GameID)
+	if game == nil {
+		return
+	}
+
+	game.mu.Lock()
+	rolledBack := game.CurrentMove == stake.Round && !game.Cancelled && game.Game.Outcome() == chess.NoOutcome
+	if rolledBack {
+		game.Votes[stake.Move]--
+		if game.Votes[stake.Move] <= 0 {
+			delete(game.Votes, stake.Move)
+		}
+		delete(game.PlayerVotedThisRound, stake.Wallet)
+	}
+
+	game.PlayerTotalVotes[stake.Wallet]--
+	game.PlayerTotalSpent[stake.Wallet] = game.PlayerTotalSpent[stake.Wallet].Sub(stake.Amount)
+	game.TotalPot = game.TotalPot.Sub(stake.Amount)
+	switch stake.Team {
+	case "white":
+		if rolledBack {
+			game.WhiteVotesThisTurn--
+		}
+		game.WhiteTeamTotalVotes--
+		game.WhitePot = game.WhitePot.Sub(stake.Amount)
+	case "black":
+		if rolledBack {
+			game.BlackVotesThisTurn--
+		}
+		game.BlackTeamTotalVotes--
+		game.BlackPot = game.BlackPot.Sub(stake.Amount)
+	}
+	game.mu.Unlock()
+
+	if rolledBack {
+		log.Printf("Vote of %s for move %s in game %s rolled back: %v", stake.Wallet, stake.Move, stake.GameID, cause)
+	} else {
+		log.Printf("Warning: Stake of %s in game %s failed after move %d was played: %v", stake.Wallet, stake.GameID, stake.Round, cause)
+	}
+
+	if m.voteRejectedCallback != nil {
+		rejection := VoteRejection{
+			GameID:        stake.GameID,
+			WalletAddress: stake.Wallet,
+			Move:          stake.Move,
+			ChainID:       stake.ChainID,
+			Reason:        cause.Error(),
+			RolledBack:    rolledBack,
+		}
+		var permitErr *PermitError
+		var fundsErr *FundsError
+		switch {
+		case errors.As(cause, &permitErr):
+			rejection.Code = permitErr.Code
+		case errors.As(cause, &fundsErr):
+			rejection.Code = fundsErr.Code
+			rejection.Funds = fundsErr
+		}
+		m.voteRejectedCallback(rejection)
+	}
+}
@@ -0,0 +1,187 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"blockchess/internal/client"
+	"blockchess/internal/money"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// votePermitKey identifies the one-shot permit of a player in a game
+func votePermitKey(gameID, walletAddress string) string {
+	return gameID + ":" + walletAddress
+}
+
+// UsesVotePermits reports whether a player stakes each vote with its own signature transfer instead of an allowance
+func (m *Manager) UsesVotePermits(walletAddress string) bool {
+	m.permitMutex.RLock()
+	defer m.permitMutex.RUnlock()
+	return m.votePermitWallets[walletAddress]
+}
+
+// CreateVotePermit creates the signature transfer of one vote's stake in a game for the player to sign. It replaces
+// the player's unused permit of the game and switches the player to one-shot permits.
+func (m *Manager) CreateVotePermit(gameID, walletAddress string, chainID uint32) (*client.PermitTransferData, any, error) {
+	if m.permit2Manager == nil {
+		return nil, nil, fmt.Errorf("Permit2 manager not available")
+	}
+
+	game := m.GetGame(gameID)
+	if game == nil {
+		return nil, nil, fmt.Errorf("game not found: %s", gameID)
+	}
+	game.mu.RLock()
+	stake := game.Stake
+	game.mu.RUnlock()
+
+	permit2Client, err := m.permit2Manager.GetPermit2Client(uint64(chainID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get Permit2 client for chain %d: %w", chainID, err)
+	}
+
+	vaultAddress := client.GetVaultAddress(uint64(chainID))
+	if vaultAddress == "" {
+		return nil, nil, fmt.Errorf("no vault address configured for chain %d", chainID)
+	}
+
+	amount, err := client.USDCUnits(uint64(chainID), stake)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	owner := common.HexToAddress(walletAddress)
+	transfer, typedData, err := permit2Client.CreateVoteStakePermit(owner, common.HexToAddress(vaultAddress), amount)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create vote permit: %w", err)
+	}
+
+	m.permitMutex.Lock()
+	m.pruneVotePermitsUnsafe()
+	key := votePermitKey(gameID, walletAddress)
+	if previous := m.votePermits[key]; previous != nil {
+		m.releaseVotePermitNonce(previous)
+	}
+	m.votePermits[key] = transfer
+	m.votePermitWallets[walletAddress] = true
+	m.permitMutex.Unlock()
+
+	log.Printf("Created vote permit for player %s in game %s on chain %d (nonce %s)", walletAddress, gameID, chainID, transfer.Nonce)
+	return transfer, typedData, nil
+}
+
+// AttachVotePermit verifies a player's signature over their vote permit of a game and keeps it for the next vote
+func (m *Manager) AttachVotePermit(gameID, walletAddress string, chainID uint32, signature string) error {
+	key := votePermitKey(gameID, walletAddress)
+
+	m.permitMutex.RLock()
+	transfer := m.votePermits[key]
+	m.permitMutex.RUnlock()
+
+	if transfer == nil {
+		return &PermitError{Code: client.PermitErrorNotFound, Message: "No vote permit found. Please request a new vote permit."}
+	}
+	if transfer.ChainID != uint64(chainID) {
+		return &PermitError{
+			Code:    client.PermitErrorChainMismatch,
+			Message: fmt.Sprintf("Vote permit is for chain %d, not chain %d", transfer.ChainID, chainID),
+		}
+	}
+	if m.permit2Manager == nil {
+		return &PermitError{Code: client.PermitErrorInvalid, Message: "Permit2 manager not available"}
+	}
+
+	permit2Client, err := m.permit2Manager.GetPermit2Client(transfer.ChainID)
+	if err != nil {
+		return &PermitError{Code: client.PermitErrorInvalid, Message: err.Error()}
+	}
+	if err := permit2Client.VerifyPermitTransferSignature(transfer, signature); err != nil {
+		return err
+	}
+
+	// Store a copy so a concurrent reader never sees a half-updated permit
+	signed := *transfer
+	signed.Signature = signature
+
+	m.permitMutex.Lock()
+	defer m.permitMutex.Unlock()
+	if m.votePermits[key] != transfer {
+		return &PermitError{Code: client.PermitErrorNotFound, Message: "Vote permit was replaced. Please sign the new vote permit."}
+	}
+	m.votePermits[key] = &signed
+	return nil
+}
+
+// takeVotePermit removes and returns the signed vote permit that pays a vote. Players using allowances get nil.
+func (m *Manager) takeVotePermit(gameID, walletAddress string, chainID uint32, stake money.Amount) (*client.PermitTransferData, error) {
+	amount, err := client.USDCUnits(uint64(chainID), stake)
+	if err != nil {
+		return nil, err
+	}
+
+	m.permitMutex.Lock()
+	defer m.permitMutex.Unlock()
+
+	if !m.votePermitWallets[walletAddress] {
+		return nil, nil
+	}
+
+	key := votePermitKey(gameID, walletAddress)
+	transfer := m.votePermits[key]
+	switch {
+	case transfer == nil || transfer.Signature == "":
+		return nil, &PermitError{Code: client.PermitErrorNotFound, Message: "vote permit signature is missing - please sign a vote permit"}
+	case transfer.ChainID != uint64(chainID):
+		return nil, &PermitError{
+			Code:    client.PermitErrorChainMismatch,
+			Message: fmt.Sprintf("vote permit is for chain %d, but player is on chain %d", transfer.ChainID, chainID),
+		}
+	case transfer.Deadline.Int64() <= time.Now().Unix():
+		delete(m.votePermits, key)
+		return nil, &PermitError{Code: client.PermitErrorExpired, Message: "vote permit has expired - please sign a new vote permit"}
+	case transfer.Amount.Cmp(amount) != 0:
+		delete(m.votePermits, key)
+		m.releaseVotePermitNonce(transfer)
+		return nil, &PermitError{Code: client.PermitErrorInvalid, Message: "vote permit does not match the game's stake - please sign a new vote permit"}
+	}
+
+	// Each signature pays exactly one vote
+	delete(m.votePermits, key)
+	return transfer, nil
+}
+
+// stakeWithTransfer stakes a vote by submitting its signed Permit2 transfer to the vault
+func (m *Manager) stakeWithTransfer(stake pendingStake, vault *client.Vault) (common.Hash, error) {
+	txHash, err := vault.StakeWithSignature(common.HexToAddress(stake.Wallet), stake.ChainGame, stake.Transfer)
+	if err != nil {
+		// A reverted transfer leaves the nonce unused, the bitmap is checked again before it is handed out
+		m.releaseVotePermitNonce(stake.Transfer)
+		return common.Hash{}, fmt.Errorf("staking failed: %w", err)
+	}
+	return txHash, nil
+}
+
+// releaseVotePermitNonce frees the nonce of a vote permit that will not be submitted
+func (m *Manager) releaseVotePermitNonce(transfer *client.PermitTransferData) {
+	if m.permit2Manager == nil {
+		return
+	}
+	permit2Client, err := m.permit2Manager.GetPermit2Client(transfer.ChainID)
+	if err != nil {
+		return
+	}
+	permit2Client.ReleaseNonce(transfer.Owner, transfer.Nonce)
+}
+
+// pruneVotePermitsUnsafe drops vote permits past their deadline (caller must hold the lock)
+func (m *Manager) pruneVotePermitsUnsafe() {
+	now := time.Now().Unix()
+	for key, transfer := range m.votePermits {
+		if transfer.Deadline.Int64() <= now {
+			delete(m.votePermits, key)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package game
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"blockchess/internal/client"
+	"blockchess/internal/money"
+)
+
+func TestTakeVotePermitPaysOneVote(t *testing.T) {
+	const wallet = "0xAA00000000000000000000000000000000000001"
+	signed := func(chainID uint64, amount int64, deadline time.Time) *client.PermitTransferData {
+		return &client.PermitTransferData{
+			Amount:    big.NewInt(amount),
+			Nonce:     big.NewInt(7),
+			Deadline:  big.NewInt(deadline.Unix()),
+			ChainID:   chainID,
+			Signature: "0x01",
+		}
+	}
+	later := time.Now().Add(time.Minute)
+
+	tests := []struct {
+		name     string
+		transfer *client.PermitTransferData
+		wantCode string
+	}{
+		{name: "valid", transfer: signed(84532, 1_000_000, later)},
+		{name: "unsigned", transfer: &client.PermitTransferData{ChainID: 84532, Amount: big.NewInt(1_000_000), Deadline: big.NewInt(later.Unix())}, wantCode: client.PermitErrorNotFound},
+		{name: "wrong chain", transfer: signed(11155111, 1_000_000, later), wantCode: client.PermitErrorChainMismatch},
+		{name: "expired deadline", transfer: signed(84532, 1_000_000, time.Now().Add(-time.Second)), wantCode: client.PermitErrorExpired},
+		{name: "wrong stake", transfer: signed(84532, 2_000_000, later), wantCode: client.PermitErrorInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manager{
+				votePermits:       map[string]*client.PermitTransferData{votePermitKey("g", wallet): tt.transfer},
+				votePermitWallets: map[string]bool{wallet: true},
+			}
+
+			transfer, err := m.takeVotePermit("g", wallet, 84532, money.USDC(1_000_000))
+			var permitErr *PermitError
+			if tt.wantCode != "" {
+				if !errors.As(err, &permitErr) || permitErr.Code != tt.wantCode {
+					t.Fatalf("takeVotePermit() error = %v, want %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil || transfer != tt.transfer {
+				t.Fatalf("takeVotePermit() = %v, %v, want the signed permit", transfer, err)
+			}
+
+			// The signature was used up, a replay has to sign a new permit
+			if _, err := m.takeVotePermit("g", wallet, 84532, money.USDC(1_000_000)); !errors.As(err, &permitErr) || permitErr.Code != client.PermitErrorNotFound {
+				t.Fatalf("replayed takeVotePermit() error = %v, want %s", err, client.PermitErrorNotFound)
+			}
+		})
+	}
+
+	t.Run("allowance player", func(t *testing.T) {
+		m := &Manager{votePermits: make(map[string]*client.PermitTransferData), votePermitWallets: make(map[string]bool)}
+		if transfer, err := m.takeVotePermit("g", wallet, 84532, money.USDC(1_000_000)); transfer != nil || err != nil {
+			t.Fatalf("takeVotePermit() = %v, %v, want nil for a player without vote permits", transfer, err)
+		}
+	})
+}
@@ -0,0 +1,287 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"blockchess/internal/client"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Vote recording modes
+const (
+	VoteRecordingBatch   = "batch"    // Buffer votes and record each round in one transaction
+	VoteRecordingPerVote = "per_vote" // Record every vote in its own transaction
+)
+
+const (
+	voteFlushTimeout = 2 * time.Minute // How long a finished game waits for its vote batches before settling
+	voteClosedKeep   = 1 * time.Hour   // How long a finished game's late votes are recorded one by one, well past any stake in flight
+)
+
+// VoteBatchStatus is the state of a batch of on-chain votes
+type VoteBatchStatus string
+
+const (
+	VoteBatchPending  VoteBatchStatus = "pending"
+	VoteBatchRecorded VoteBatchStatus = "recorded"
+	VoteBatchFallback VoteBatchStatus = "fallback" // The batch failed and its votes were recorded one by one
+	VoteBatchFailed   VoteBatchStatus = "failed"
+)
+
+// BatchedVote is a staked vote waiting to be recorded on-chain
+type BatchedVote struct {
+	WalletAddress string `json:"walletAddress"`
+	ChainID       uint32 `json:"chainId"`
+	Team          string `json:"team"`
+}
+
+// VoteBatch is one round of votes recorded in the GameFactory
+type VoteBatch struct {
+	ID          string          `json:"id"`
+	Round       int             `json:"round"`
+	Votes       []BatchedVote   `json:"votes"`
+	Status      VoteBatchStatus `json:"status"`
+	TxHash      string          `json:"txHash,omitempty"`
+	FailedVotes int             `json:"failedVotes,omitempty"` // Votes the per-vote fallback could not record
+	LastError   string          `json:"lastError,omitempty"`
+	CreatedAt   int64           `json:"createdAt"`
+	UpdatedAt   int64           `json:"updatedAt"`
+}
+
+// VoteBatchConfig controls how votes are recorded in the GameFactory
+type VoteBatchConfig struct {
+	Mode    string // One of VoteRecordingBatch, VoteRecordingPerVote
+	MaxSize int    // Most votes per batch transaction, larger rounds are split
+}
+
+// LoadVoteBatchConfig loads the vote recording settings from environment variables
+func LoadVoteBatchConfig() VoteBatchConfig {
+	mode := client.GetEnv("VOTE_RECORDING", VoteRecordingBatch)
+	switch mode {
+	case VoteRecordingBatch, VoteRecordingPerVote:
+	default:
+		log.Printf("Warning: Unknown VOTE_RECORDING %q, using %q", mode, VoteRecordingBatch)
+		mode = VoteRecordingBatch
+	}
+
+	return VoteBatchConfig{
+		Mode:    mode,
+		MaxSize: max(client.GetEnvInt("VOTE_BATCH_MAX_SIZE", 100), 1),
+	}
+}
+
+// voteBatcher buffers staked votes per game until their round closes and tracks the batches sent
+type voteBatcher struct {
+	mu       sync.Mutex
+	pending  map[string][]BatchedVote // gameID -> votes waiting for the round to close
+	batches  map[string][]*VoteBatch  // gameID -> batches sent so far
+	flushing map[string]int           // gameID -> batches still being sent
+	closed   map[string]time.Time     // gameID -> when the game's batches were handed to settlement
+}
+
+// newVoteBatcher creates an empty vote batcher
+func newVoteBatcher() *voteBatcher {
+	return &voteBatcher{
+		pending:  make(map[string][]BatchedVote),
+		batches:  make(map[string][]*VoteBatch),
+		flushing: make(map[string]int),
+		closed:   make(map[string]time.Time),
+	}
+}
+
+// add buffers a vote for the next flush, failing once the game was closed
+func (b *voteBatcher) add(gameID string, vote BatchedVote) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, closed := b.closed[gameID]; closed {
+		return false
+	}
+	b.pending[gameID] = append(b.pending[gameID], vote)
+	return true
+}
+
+// take turns the buffered votes of a game into batches of at most maxSize votes
+func (b *voteBatcher) take(gameID string, round, maxSize int) []*VoteBatch {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	votes := b.pending[gameID]
+	delete(b.pending, gameID)
+
+	var taken []*VoteBatch
+	now := time.Now().Unix()
+	for start := 0; start < len(votes); start += maxSize {
+		end := min(start+maxSize, len(votes))
+		batch := &VoteBatch{
+			ID:        fmt.Sprintf("%s/round-%d", gameID, round),
+			Round:     round,
+			Votes:     votes[start:end],
+			Status:    VoteBatchPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if len(votes) > maxSize {
+			batch.ID = fmt.Sprintf("%s/round-%d-%d", gameID, round, start/maxSize+1)
+		}
+		taken = append(taken, batch)
+	}
+
+	b.batches[gameID] = append(b.batches[gameID], taken...)
+	b.flushing[gameID] += len(taken)
+	return taken
+}
+
+// complete records the outcome of a batch
+func (b *voteBatcher) complete(gameID string, batch *VoteBatch, status VoteBatchStatus, txHash string, failedVotes int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	batch.Status = status
+	batch.TxHash = txHash
+	batch.FailedVotes = failedVotes
+	batch.LastError = ""
+	if err != nil {
+		batch.LastError = err.Error()
+	}
+	batch.UpdatedAt = time.Now().Unix()
+
+	b.flushing[gameID]--
+	if b.flushing[gameID] <= 0 {
+		delete(b.flushing, gameID)
+	}
+}
+
+// waitForGame blocks until every batch of a game was sent, reporting false on timeout
+func (b *voteBatcher) waitForGame(gameID string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		b.mu.Lock()
+		flushing := b.flushing[gameID]
+		b.mu.Unlock()
+
+		if flushing == 0 {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(stakeDrainPoll)
+	}
+}
+
+// finish closes a game and returns a copy of its batches, later votes are recorded one by one
+func (b *voteBatcher) finish(gameID string) []VoteBatch {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.pruneClosedUnsafe(now)
+	b.closed[gameID] = now
+
+	batches := make([]VoteBatch, 0, len(b.batches[gameID]))
+	for _, batch := range b.batches[gameID] {
+		batches = append(batches, *batch)
+	}
+	delete(b.batches, gameID)
+	return batches
+}
+
+// pruneClosedUnsafe forgets games closed long enough ago that none of their stakes can still be in flight (caller must hold the lock)
+func (b *voteBatcher) pruneClosedUnsafe(now time.Time) {
+	for gameID, closedAt := range b.closed {
+		if now.Sub(closedAt) > voteClosedKeep {
+			delete(b.closed, gameID)
+		}
+	}
+}
+
+// recordChainVote buffers a staked vote for the round's batch, or records it right away in per-vote mode
+func (m *Manager) recordChainVote(stake pendingStake) {
+	if m.gameFactory == nil || stake.ChainGame == 0 {
+		return
+	}
+
+	if m.voteBatchConfig.Mode == VoteRecordingBatch {
+		vote := BatchedVote{WalletAddress: stake.Wallet, ChainID: stake.ChainID, Team: stake.Team}
+		if m.votes.add(stake.GameID, vote) {
+			return
+		}
+		log.Printf("Warning: Vote batches of game %s are already closed, recording vote of %s on its own", stake.GameID, stake.Wallet)
+	}
+
+	if err := m.addChainVote(stake.ChainGame, stake.Wallet, stake.ChainID, stake.Team); err != nil {
+		// The stake is in the vault, so the vote stands even if the blockchain record fails
+		log.Printf("Warning: Failed to add vote to blockchain: %v", err)
+	} else {
+		log.Printf("Successfully added vote to blockchain for player %s", stake.Wallet)
+	}
+}
+
+// flushVotes sends the votes buffered for a game as batches in the background
+func (m *Manager) flushVotes(gameID string, chainGame uint64, round int) {
+	if m.gameFactory == nil || chainGame == 0 {
+		return
+	}
+
+	for _, batch := range m.votes.take(gameID, round, m.voteBatchConfig.MaxSize) {
+		go m.sendVoteBatch(gameID, chainGame, batch)
+	}
+}
+
+// sendVoteBatch records a batch in one transaction, falling back to one transaction per vote if it fails
+func (m *Manager) sendVoteBatch(gameID string, chainGame uint64, batch *VoteBatch) {
+	records := make([]client.VoteRecord, 0, len(batch.Votes))
+	for _, vote := range batch.Votes {
+		team, err := client.TeamStringToUint8(vote.Team)
+		if err != nil {
+			log.Printf("Warning: Failed to convert team '%s' to uint8: %v", vote.Team, err)
+			continue
+		}
+		records = append(records, client.VoteRecord{
+			Player:  common.HexToAddress(vote.WalletAddress),
+			ChainID: vote.ChainID,
+			Team:    team,
+		})
+	}
+
+	txHash, err := m.gameFactory.AddVotes(chainGame, records)
+	if err == nil {
+		log.Printf("Recorded vote batch %s with %d votes in tx %s", batch.ID, len(records), txHash.Hex())
+		m.votes.complete(gameID, batch, VoteBatchRecorded, txHash.Hex(), 0, nil)
+		return
+	}
+
+	log.Printf("Warning: Vote batch %s failed, recording its %d votes one by one: %v", batch.ID, len(batch.Votes), err)
+	failed := 0
+	var lastErr error
+	for _, vote := range batch.Votes {
+		if voteErr := m.addChainVote(chainGame, vote.WalletAddress, vote.ChainID, vote.Team); voteErr != nil {
+			failed++
+			lastErr = voteErr
+		}
+	}
+
+	if failed == len(batch.Votes) {
+		log.Printf("Error: Failed to record any vote of batch %s: %v", batch.ID, lastErr)
+		m.votes.complete(gameID, batch, VoteBatchFailed, "", failed, fmt.Errorf("batch: %v; per vote: %w", err, lastErr))
+		return
+	}
+	if failed > 0 {
+		log.Printf("Warning: %d of %d votes of batch %s could not be recorded: %v", failed, len(batch.Votes), batch.ID, lastErr)
+	}
+	m.votes.complete(gameID, batch, VoteBatchFallback, "", failed, err)
+}
+
+// addChainVote records a single vote in the GameFactory
+func (m *Manager) addChainVote(chainGame uint64, wallet string, chainID uint32, team string) error {
+	teamUint8, err := client.TeamStringToUint8(team)
+	if err != nil {
+		return fmt.Errorf("failed to convert team '%s' to uint8: %w", team, err)
+	}
+	return m.gameFactory.AddVote(chainGame, common.HexToAddress(wallet), chainID, teamUint8)
+}
@@ -0,0 +1,36 @@
+package ledger
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleWalletStatement serves the statement of the wallet in the {wallet} route variable
+func (l *Ledger) HandleWalletStatement(w http.ResponseWriter, r *http.Request) {
+	wallet := mux.Vars(r)["wallet"]
+	if wallet == "" {
+		http.Error(w, "wallet is required", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, l.WalletStatement(wallet))
+}
+
+// HandleGameBalanceSheet serves the balance sheet of the game in the {gameId} route variable
+func (l *Ledger) HandleGameBalanceSheet(w http.ResponseWriter, r *http.Request) {
+	gameID := mux.Vars(r)["gameId"]
+	if gameID == "" {
+		http.Error(w, "gameId is required", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, l.GameBalanceSheet(gameID))
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("Warning: Failed to encode ledger response: %v", err)
+	}
+}
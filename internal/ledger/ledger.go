@@ -0,0 +1,346 @@
+package ledger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"blockchess/internal/money"
+)
+
+// Kind classifies what a ledger entry records
+type Kind string
+
+const (
+	KindStake  Kind = "stake"  // Player stake moved from the wallet into the game pot
+	KindGather Kind = "gather" // Pot moved cross-chain into the settlement vault
+	KindPayout Kind = "payout" // Pot paid out to a winning player
+	KindRefund Kind = "refund" // Pot returned to a player
+	KindFee    Kind = "fee"    // Pot paid to the treasury
+)
+
+// Account types
+const (
+	AccountWallet   = "wallet"
+	AccountPot      = "pot"
+	AccountTreasury = "treasury"
+)
+
+// Account identifies a balance held by a wallet, a game pot or the treasury on a chain
+type Account struct {
+	Type    string `json:"type"`
+	Owner   string `json:"owner"` // wallet address, game ID or treasury address
+	ChainID uint64 `json:"chainId"`
+}
+
+// Wallet returns the account of a player wallet on a chain
+func Wallet(address string, chainID uint64) Account {
+	return Account{Type: AccountWallet, Owner: strings.ToLower(address), ChainID: chainID}
+}
+
+// Pot returns the pot account of a game on a chain
+func Pot(gameID string, chainID uint64) Account {
+	return Account{Type: AccountPot, Owner: gameID, ChainID: chainID}
+}
+
+// Treasury returns the treasury account on a chain
+func Treasury(address string, chainID uint64) Account {
+	return Account{Type: AccountTreasury, Owner: strings.ToLower(address), ChainID: chainID}
+}
+
+// String formats the account as type:owner@chain
+func (a Account) String() string {
+	return fmt.Sprintf("%s:%s@%d", a.Type, a.Owner, a.ChainID)
+}
+
+// Entry is a balanced movement of funds: From is credited and To is debited by Amount
+type Entry struct {
+	ID        uint64       `json:"id"`
+	GameID    string       `json:"gameId"`
+	ChainGame uint64       `json:"chainGame,omitempty"` // On-chain game ID the vault transfers were made for
+	Kind      Kind         `json:"kind"`
+	From      Account      `json:"from"`
+	To        Account      `json:"to"`
+	Amount    money.Amount `json:"amount"`
+	TxHash    string       `json:"txHash,omitempty"`
+	Ref       string       `json:"ref,omitempty"` // Idempotency key of the settlement step that produced the entry
+	CreatedAt int64        `json:"createdAt"`     // Unix timestamp
+}
+
+// StatementLine is a single entry in a wallet statement with its effect on the wallet
+type StatementLine struct {
+	Entry   Entry        `json:"entry"`
+	Change  money.Amount `json:"change"`
+	Balance money.Amount `json:"balance"`
+}
+
+// Statement lists every entry touching a wallet with a running balance
+type Statement struct {
+	Wallet  string          `json:"wallet"`
+	Lines   []StatementLine `json:"lines"`
+	Balance money.Amount    `json:"balance"`
+}
+
+// AccountBalance is the net balance of one account
+type AccountBalance struct {
+	Account Account      `json:"account"`
+	Balance money.Amount `json:"balance"`
+}
+
+// BalanceSheet summarises every entry recorded for a game
+type BalanceSheet struct {
+	GameID     string           `json:"gameId"`
+	Staked     money.Amount     `json:"staked"`
+	PaidOut    money.Amount     `json:"paidOut"`
+	Refunded   money.Amount     `json:"refunded"`
+	Fees       money.Amount     `json:"fees"`
+	PotBalance money.Amount     `json:"potBalance"`
+	Balanced   bool             `json:"balanced"`
+	Accounts   []AccountBalance `json:"accounts"`
+	Entries    []Entry          `json:"entries"`
+}
+
+// Ledger is an append-only double-entry journal persisted as JSON lines
+type Ledger struct {
+	mu      sync.RWMutex
+	entries []Entry
+	nextID  uint64
+	file    *os.File
+}
+
+// New opens the ledger at path, replaying existing entries. An empty path keeps the ledger in memory.
+func New(path string) (*Ledger, error) {
+	l := &Ledger{nextID: 1}
+	if path == "" {
+		return l, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create ledger directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ledger file: %w", err)
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to parse ledger entry: %w", err)
+		}
+		l.entries = append(l.entries, entry)
+		if entry.ID >= l.nextID {
+			l.nextID = entry.ID + 1
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read ledger file: %w", err)
+	}
+
+	l.file = file
+	return l, nil
+}
+
+// Close closes the ledger file
+func (l *Ledger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Record validates, persists and appends an entry, returning it with its ID and timestamp set
+func (l *Ledger) Record(entry Entry) (Entry, error) {
+	if !entry.Amount.IsPositive() {
+		return Entry{}, fmt.Errorf("ledger entry amount must be positive, got %s", entry.Amount)
+	}
+	if entry.From == entry.To {
+		return Entry{}, fmt.Errorf("ledger entry moves funds from %s to itself", entry.From)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry.ID = l.nextID
+	if entry.CreatedAt == 0 {
+		entry.CreatedAt = time.Now().Unix()
+	}
+
+	if l.file != nil {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return Entry{}, fmt.Errorf("failed to encode ledger entry: %w", err)
+		}
+		if _, err := l.file.Write(append(data, '\n')); err != nil {
+			return Entry{}, fmt.Errorf("failed to write ledger entry: %w", err)
+		}
+	}
+
+	l.nextID++
+	l.entries = append(l.entries, entry)
+	return entry, nil
+}
+
+// Entries returns all entries recorded for a game
+func (l *Ledger) Entries(gameID string) []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var entries []Entry
+	for _, entry := range l.entries {
+		if entry.GameID == gameID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// HasRef reports whether an entry with the given idempotency key was already recorded
+func (l *Ledger) HasRef(ref string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, entry := range l.entries {
+		if entry.Ref == ref {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenGames returns the IDs of games whose pot accounts still hold funds
+func (l *Ledger) OpenGames() []string {
+	l.mu.RLock()
+	pots := make(map[string]money.Amount)
+	for _, entry := range l.entries {
+		if entry.From.Type == AccountPot {
+			pots[entry.GameID] = pots[entry.GameID].Sub(entry.Amount)
+		}
+		if entry.To.Type == AccountPot {
+			pots[entry.GameID] = pots[entry.GameID].Add(entry.Amount)
+		}
+	}
+	l.mu.RUnlock()
+
+	var gameIDs []string
+	for gameID, balance := range pots {
+		if !balance.IsZero() {
+			gameIDs = append(gameIDs, gameID)
+		}
+	}
+	sort.Strings(gameIDs)
+	return gameIDs
+}
+
+// WalletStatement returns every entry touching a wallet on any chain, oldest first
+func (l *Ledger) WalletStatement(wallet string) Statement {
+	wallet = strings.ToLower(wallet)
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	statement := Statement{Wallet: wallet, Lines: []StatementLine{}, Balance: money.USDC(0)}
+	for _, entry := range l.entries {
+		var change money.Amount
+		switch {
+		case entry.From.Type == AccountWallet && entry.From.Owner == wallet:
+			change = money.USDC(0).Sub(entry.Amount)
+		case entry.To.Type == AccountWallet && entry.To.Owner == wallet:
+			change = entry.Amount
+		default:
+			continue
+		}
+
+		statement.Balance = statement.Balance.Add(change)
+		statement.Lines = append(statement.Lines, StatementLine{
+			Entry:   entry,
+			Change:  change,
+			Balance: statement.Balance,
+		})
+	}
+	return statement
+}
+
+// GameBalanceSheet returns the totals and per-account balances of a game
+func (l *Ledger) GameBalanceSheet(gameID string) BalanceSheet {
+	entries := l.Entries(gameID)
+
+	sheet := BalanceSheet{
+		GameID:     gameID,
+		Staked:     money.USDC(0),
+		PaidOut:    money.USDC(0),
+		Refunded:   money.USDC(0),
+		Fees:       money.USDC(0),
+		PotBalance: money.USDC(0),
+		Accounts:   []AccountBalance{},
+		Entries:    entries,
+	}
+	if sheet.Entries == nil {
+		sheet.Entries = []Entry{}
+	}
+
+	balances := make(map[Account]money.Amount)
+	for _, entry := range entries {
+		balances[entry.From] = balances[entry.From].Sub(entry.Amount)
+		balances[entry.To] = balances[entry.To].Add(entry.Amount)
+
+		switch entry.Kind {
+		case KindStake:
+			sheet.Staked = sheet.Staked.Add(entry.Amount)
+		case KindPayout:
+			sheet.PaidOut = sheet.PaidOut.Add(entry.Amount)
+		case KindRefund:
+			sheet.Refunded = sheet.Refunded.Add(entry.Amount)
+		case KindFee:
+			sheet.Fees = sheet.Fees.Add(entry.Amount)
+		}
+	}
+
+	for account, balance := range balances {
+		sheet.Accounts = append(sheet.Accounts, AccountBalance{Account: account, Balance: balance})
+		if account.Type == AccountPot {
+			sheet.PotBalance = sheet.PotBalance.Add(balance)
+		}
+	}
+	sort.Slice(sheet.Accounts, func(i, j int) bool {
+		return sheet.Accounts[i].Account.String() < sheet.Accounts[j].Account.String()
+	})
+
+	sheet.Balanced = sheet.PotBalance.IsZero()
+	return sheet
+}
+
+// CheckGame verifies that every pot account of a settled game nets to zero
+func (l *Ledger) CheckGame(gameID string) error {
+	sheet := l.GameBalanceSheet(gameID)
+
+	var open []string
+	for _, balance := range sheet.Accounts {
+		if balance.Account.Type == AccountPot && !balance.Balance.IsZero() {
+			open = append(open, fmt.Sprintf("%s=%s", balance.Account, balance.Balance))
+		}
+	}
+	if len(open) > 0 {
+		return fmt.Errorf("game %s ledger does not net to zero: %s", gameID, strings.Join(open, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package ledger
+
+import (
+	"testing"
+
+	"blockchess/internal/money"
+)
+
+func TestGameBalanceSheet(t *testing.T) {
+	const (
+		base    = uint64(84532)
+		sepolia = uint64(11155111)
+		alice   = "0xAA00000000000000000000000000000000000001"
+		bob     = "0xBB00000000000000000000000000000000000002"
+	)
+	usdc := money.USDC
+
+	tests := []struct {
+		name         string
+		entries      []Entry
+		wantPot      int64
+		wantBalanced bool
+	}{
+		{
+			name: "stakes waiting for settlement",
+			entries: []Entry{
+				{Kind: KindStake, From: Wallet(alice, base), To: Pot("g", base), Amount: usdc(100000)},
+				{Kind: KindStake, From: Wallet(bob, sepolia), To: Pot("g", sepolia), Amount: usdc(100000)},
+			},
+			wantPot: 200000,
+		},
+		{
+			name: "gathered but not paid out",
+			entries: []Entry{
+				{Kind: KindStake, From: Wallet(bob, sepolia), To: Pot("g", sepolia), Amount: usdc(100000)},
+				{Kind: KindGather, From: Pot("g", sepolia), To: Pot("g", base), Amount: usdc(100000)},
+			},
+			wantPot: 100000,
+		},
+		{
+			name: "paid out with a fee",
+			entries: []Entry{
+				{Kind: KindStake, From: Wallet(alice, base), To: Pot("g", base), Amount: usdc(100000)},
+				{Kind: KindStake, From: Wallet(bob, sepolia), To: Pot("g", sepolia), Amount: usdc(100000)},
+				{Kind: KindGather, From: Pot("g", sepolia), To: Pot("g", base), Amount: usdc(100000)},
+				{Kind: KindFee, From: Pot("g", base), To: Treasury(alice, base), Amount: usdc(10000)},
+				{Kind: KindPayout, From: Pot("g", base), To: Wallet(alice, base), Amount: usdc(190000)},
+			},
+			wantBalanced: true,
+		},
+		{
+			name: "refunded on each chain",
+			entries: []Entry{
+				{Kind: KindStake, From: Wallet(alice, base), To: Pot("g", base), Amount: usdc(100000)},
+				{Kind: KindStake, From: Wallet(bob, sepolia), To: Pot("g", sepolia), Amount: usdc(50000)},
+				{Kind: KindRefund, From: Pot("g", base), To: Wallet(alice, base), Amount: usdc(100000)},
+				{Kind: KindRefund, From: Pot("g", sepolia), To: Wallet(bob, sepolia), Amount: usdc(50000)},
+			},
+			wantBalanced: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := New("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, entry := range tt.entries {
+				entry.GameID = "g"
+				if _, err := l.Record(entry); err != nil {
+					t.Fatalf("Record(%+v) failed: %v", entry, err)
+				}
+			}
+
+			sheet := l.GameBalanceSheet("g")
+			if sheet.PotBalance.Units() != tt.wantPot || sheet.Balanced != tt.wantBalanced {
+				t.Fatalf("pot = %s, balanced = %v, want %d units, %v", sheet.PotBalance, sheet.Balanced, tt.wantPot, tt.wantBalanced)
+			}
+			if err := l.CheckGame("g"); (err == nil) != tt.wantBalanced {
+				t.Fatalf("CheckGame() error = %v, want balanced %v", err, tt.wantBalanced)
+			}
+		})
+	}
+}
@@ -0,0 +1,708 @@
+package matchmaking
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"blockchess/internal/client"
+)
+
+// Fallback modes applied when a ticket waits longer than MaxWait
+const (
+	FallbackNone  = "none"  // Keep waiting in the queue
+	FallbackLobby = "lobby" // Open a lobby game other players can join
+	FallbackBot   = "bot"   // Start a game against a bot
+)
+
+// Rating constants
+const (
+	DefaultRating = 1200
+	eloKFactor    = 32
+)
+
+// Config holds matchmaking tuning parameters
+type Config struct {
+	BaseRatingBand     int           // Allowed rating difference for a fresh ticket
+	RatingBandGrowth   int           // Rating points the band widens per second of waiting
+	MaxRatingBand      int           // Upper bound for the rating band
+	MaxWait            time.Duration // Wait time before the fallback kicks in
+	MaxSkipWait        time.Duration // Wait time after which newer tickets may no longer be matched past the oldest, 0 for never
+	Fallback           string        // One of FallbackNone, FallbackLobby, FallbackBot
+	DefaultStakeLevel  int64         // Stake per vote in USDC base units when the client sends none
+	DefaultTimeControl int           // Seconds per turn when the client sends none
+	DefaultTeamSize    int           // Target players per side when the client sends none
+	TeamSizes          []int         // Allowed target players per side, e.g. 1, 3 and 10
+	StakeLevels        []int64       // Allowed stakes per vote in USDC base units
+	TimeControls       []int         // Allowed seconds per turn
+	MinTeamFillPercent int           // Percentage of the target team size needed to start a game
+	MaxImbalance       int           // Maximum player difference between sides, 0 for unlimited
+}
+
+// Defaults are the game settings a ticket gets when the client leaves them out
+type Defaults struct {
+	StakeLevel   int64 // Stake per vote in USDC base units
+	TimeControl  int   // Seconds per turn
+	TeamSize     int   // Target players per side
+	MaxImbalance int   // Maximum player difference between sides, 0 for unlimited
+}
+
+// LoadConfig loads the matchmaking configuration from environment variables,
+// taking stake, time control and team limits from the caller's defaults
+func LoadConfig(defaults Defaults) Config {
+	if defaults.TeamSize <= 0 {
+		defaults.TeamSize = 1
+	}
+
+	fallback := client.GetEnv("MATCHMAKING_FALLBACK", FallbackLobby)
+	switch fallback {
+	case FallbackNone, FallbackLobby, FallbackBot:
+	default:
+		log.Printf("Warning: Unknown MATCHMAKING_FALLBACK %q, using %q", fallback, FallbackLobby)
+		fallback = FallbackLobby
+	}
+
+	return Config{
+		BaseRatingBand:     client.GetEnvInt("MATCHMAKING_BASE_RATING_BAND", 100),
+		RatingBandGrowth:   client.GetEnvInt("MATCHMAKING_RATING_BAND_GROWTH", 10),
+		MaxRatingBand:      client.GetEnvInt("MATCHMAKING_MAX_RATING_BAND", 800),
+		MaxWait:            time.Duration(client.GetEnvInt("MATCHMAKING_MAX_WAIT_SECONDS", 60)) * time.Second,
+		MaxSkipWait:        time.Duration(client.GetEnvInt("MATCHMAKING_MAX_SKIP_SECONDS", 30)) * time.Second,
+		Fallback:           fallback,
+		DefaultStakeLevel:  defaults.StakeLevel,
+		DefaultTimeControl: defaults.TimeControl,
+		DefaultTeamSize:    defaults.TeamSize,
+		TeamSizes:          parseAllowed(client.GetEnv("MATCHMAKING_TEAM_SIZES", "1,3,5,10"), 1, defaults.TeamSize),
+		StakeLevels:        parseAllowed(client.GetEnv("MATCHMAKING_STAKE_LEVELS", "10000,100000,1000000"), defaults.StakeLevel),
+		TimeControls:       parseAllowed(client.GetEnv("MATCHMAKING_TIME_CONTROLS", "15,30,60"), defaults.TimeControl),
+		MinTeamFillPercent: client.GetEnvInt("MATCHMAKING_MIN_TEAM_FILL_PERCENT", 50),
+		MaxImbalance:       defaults.MaxImbalance,
+	}
+}
+
+// parseAllowed parses a comma separated list of positive values, always allowing the given defaults
+func parseAllowed[T int | int64](value string, defaults ...T) []T {
+	var allowed []T
+	for _, v := range defaults {
+		if v > 0 && !slices.Contains(allowed, v) {
+			allowed = append(allowed, v)
+		}
+	}
+	for _, part := range strings.Split(value, ",") {
+		parsed, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil || parsed <= 0 {
+			continue
+		}
+		if v := T(parsed); !slices.Contains(allowed, v) {
+			allowed = append(allowed, v)
+		}
+	}
+	slices.Sort(allowed)
+	return allowed
+}
+
+// Segment groups tickets that are allowed to be matched together
+type Segment struct {
+	ChainID     uint32
+	StakeLevel  int64
+	TimeControl int
+	TeamSize    int // Target players per side
+}
+
+// Ticket is a solo player or a party waiting in the matchmaking queue.
+// Party members always end up on the same side.
+type Ticket struct {
+	PartyID    string   // Empty for solo players
+	PartySize  int      // Number of members expected before the ticket can be matched
+	Members    []string // Wallet addresses that have joined so far
+	Segment    Segment
+	Rating     int // Average rating of the members
+	EnqueuedAt time.Time
+}
+
+// Size returns the number of members in the ticket
+func (t *Ticket) Size() int {
+	return len(t.Members)
+}
+
+// Ready reports whether every expected party member has joined
+func (t *Ticket) Ready() bool {
+	return len(t.Members) >= t.PartySize
+}
+
+// Match is a new game with balanced sides
+type Match struct {
+	Segment Segment
+	White   []*Ticket
+	Black   []*Ticket
+}
+
+// Assignment places a ticket into an already running game that is below its target size
+type Assignment struct {
+	GameID string
+	Ticket *Ticket
+	Side   string
+}
+
+// QueueStatus describes a ticket's place in its queue
+type QueueStatus struct {
+	Position      int           // 1-based position in the segment queue
+	QueueSize     int           // Number of players in the segment queue
+	EstimatedWait time.Duration // Zero when there is no history to estimate from
+	PartyMembers  int           // Members of the wallet's party that have joined so far
+	PartySize     int           // Members the wallet's party is waiting for
+}
+
+// openGame tracks a running game that late joiners can still fill
+type openGame struct {
+	segment      Segment
+	whitePlayers int
+	blackPlayers int
+	ratingTotal  int
+	createdAt    time.Time
+}
+
+// Service keeps ordered matchmaking queues and groups compatible tickets into games
+type Service struct {
+	config Config
+
+	// Ordered queues - segment -> tickets in arrival order
+	queues map[Segment][]*Ticket
+
+	// Ticket lookup - walletAddress -> ticket
+	tickets map[string]*Ticket
+
+	// Party lookup - partyID -> ticket
+	parties map[string]*Ticket
+
+	// Games below their target team size - gameID -> open game
+	openGames map[string]*openGame
+
+	// Player ratings - walletAddress -> rating
+	ratings map[string]int
+
+	// Average wait time of matched tickets per segment
+	averageWait map[Segment]time.Duration
+
+	mu sync.Mutex
+}
+
+// NewService creates a new matchmaking service
+func NewService(config Config) *Service {
+	return &Service{
+		config:      config,
+		queues:      make(map[Segment][]*Ticket),
+		tickets:     make(map[string]*Ticket),
+		parties:     make(map[string]*Ticket),
+		openGames:   make(map[string]*openGame),
+		ratings:     make(map[string]int),
+		averageWait: make(map[Segment]time.Duration),
+	}
+}
+
+// Config returns the service configuration
+func (s *Service) Config() Config {
+	return s.config
+}
+
+// NormalizeSegment fills in defaults for unset segment fields
+func (s *Service) NormalizeSegment(segment Segment) Segment {
+	if segment.StakeLevel <= 0 {
+		segment.StakeLevel = s.config.DefaultStakeLevel
+	}
+	if segment.TimeControl <= 0 {
+		segment.TimeControl = s.config.DefaultTimeControl
+	}
+	if segment.TeamSize <= 0 {
+		segment.TeamSize = s.config.DefaultTeamSize
+	}
+	return segment
+}
+
+// MinTeamSize returns the players needed on each side before a game can start
+func (s *Service) MinTeamSize(teamSize int) int {
+	minSize := int(math.Ceil(float64(teamSize*s.config.MinTeamFillPercent) / 100))
+	if minSize < 1 {
+		minSize = 1
+	}
+	if minSize > teamSize {
+		minSize = teamSize
+	}
+	return minSize
+}
+
+// Enqueue adds a wallet to the back of its segment queue, or to its party's ticket
+func (s *Service) Enqueue(walletAddress string, segment Segment, partyID string, partySize int) (*Ticket, error) {
+	segment = s.NormalizeSegment(segment)
+	if !slices.Contains(s.config.TeamSizes, segment.TeamSize) {
+		return nil, fmt.Errorf("unsupported team size %d, allowed sizes: %v", segment.TeamSize, s.config.TeamSizes)
+	}
+	if !slices.Contains(s.config.StakeLevels, segment.StakeLevel) {
+		return nil, fmt.Errorf("unsupported stake level %d, allowed levels: %v", segment.StakeLevel, s.config.StakeLevels)
+	}
+	if !slices.Contains(s.config.TimeControls, segment.TimeControl) {
+		return nil, fmt.Errorf("unsupported time control %d, allowed time controls: %v", segment.TimeControl, s.config.TimeControls)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.tickets[walletAddress]; exists {
+		return nil, fmt.Errorf("wallet %s already in matchmaking queue", walletAddress)
+	}
+
+	// Join an existing party ticket, keeping the party's place in the queue
+	if partyID != "" {
+		if ticket, exists := s.parties[partyID]; exists {
+			if ticket.Segment != segment {
+				return nil, fmt.Errorf("party %s is queued with different settings", partyID)
+			}
+			if ticket.Ready() {
+				return nil, fmt.Errorf("party %s is already full", partyID)
+			}
+
+			ticket.Members = append(ticket.Members, walletAddress)
+			ticket.Rating = s.averageRatingUnsafe(ticket.Members)
+			s.tickets[walletAddress] = ticket
+
+			log.Printf("Matchmaking: %s joined party %s (%d/%d)", walletAddress, partyID, ticket.Size(), ticket.PartySize)
+			return ticket, nil
+		}
+	}
+
+	if partyID == "" || partySize <= 0 {
+		partySize = 1
+	}
+	if partySize > segment.TeamSize {
+		return nil, fmt.Errorf("party of %d does not fit a team of %d", partySize, segment.TeamSize)
+	}
+
+	ticket := &Ticket{
+		PartyID:    partyID,
+		PartySize:  partySize,
+		Members:    []string{walletAddress},
+		Segment:    segment,
+		Rating:     s.ratingUnsafe(walletAddress),
+		EnqueuedAt: time.Now(),
+	}
+
+	s.queues[segment] = append(s.queues[segment], ticket)
+	s.tickets[walletAddress] = ticket
+	if partyID != "" {
+		s.parties[partyID] = ticket
+	}
+
+	log.Printf("Matchmaking: %s queued in segment %+v (rating %d, party %q, queue size %d)",
+		walletAddress, segment, ticket.Rating, partyID, len(s.queues[segment]))
+	return ticket, nil
+}
+
+// Remove takes a wallet out of the queue, returning false if it was not queued.
+// The rest of the wallet's party stays queued and waits for a replacement member.
+func (s *Service) Remove(walletAddress string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ticket, exists := s.tickets[walletAddress]
+	if !exists {
+		return false
+	}
+
+	delete(s.tickets, walletAddress)
+	ticket.Members = slices.DeleteFunc(ticket.Members, func(member string) bool {
+		return member == walletAddress
+	})
+
+	if len(ticket.Members) == 0 {
+		s.removeUnsafe(ticket)
+	} else {
+		ticket.Rating = s.averageRatingUnsafe(ticket.Members)
+	}
+	return true
+}
+
+// IsQueued reports whether a wallet is waiting in any queue
+func (s *Service) IsQueued(walletAddress string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, exists := s.tickets[walletAddress]
+	return exists
+}
+
+// Status returns the queue position and estimated wait for a wallet
+func (s *Service) Status(walletAddress string) (QueueStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ticket, exists := s.tickets[walletAddress]
+	if !exists {
+		return QueueStatus{}, false
+	}
+
+	queue := s.queues[ticket.Segment]
+	status := QueueStatus{
+		PartyMembers: ticket.Size(),
+		PartySize:    ticket.PartySize,
+	}
+
+	playersThrough := 0
+	for i, t := range queue {
+		status.QueueSize += t.Size()
+		if status.Position == 0 {
+			playersThrough += t.Size()
+			if t == ticket {
+				status.Position = i + 1
+			}
+		}
+	}
+
+	// Each match takes at least twice the minimum team size out of the queue,
+	// so a ticket waits for roughly that many average match intervals
+	if average, ok := s.averageWait[ticket.Segment]; ok {
+		perMatch := 2 * s.MinTeamSize(ticket.Segment.TeamSize)
+		rounds := time.Duration((playersThrough + perMatch - 1) / perMatch)
+		estimate := average*rounds - time.Since(ticket.EnqueuedAt)
+		if estimate < 0 {
+			estimate = 0
+		}
+		status.EstimatedWait = estimate
+	}
+
+	return status, true
+}
+
+// QueuedWallets returns every wallet currently waiting in a queue
+func (s *Service) QueuedWallets() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wallets := make([]string, 0, len(s.tickets))
+	for walletAddress := range s.tickets {
+		wallets = append(wallets, walletAddress)
+	}
+	return wallets
+}
+
+// RegisterOpenGame lets late joiners fill a game whose sides are below the target size
+func (s *Service) RegisterOpenGame(gameID string, match Match) {
+	game := &openGame{segment: match.Segment, createdAt: time.Now()}
+	for _, ticket := range match.White {
+		game.whitePlayers += ticket.Size()
+		game.ratingTotal += ticket.Rating * ticket.Size()
+	}
+	for _, ticket := range match.Black {
+		game.blackPlayers += ticket.Size()
+		game.ratingTotal += ticket.Rating * ticket.Size()
+	}
+
+	if game.whitePlayers >= match.Segment.TeamSize && game.blackPlayers >= match.Segment.TeamSize {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.openGames[gameID] = game
+}
+
+// CloseOpenGame stops placing late joiners into a game
+func (s *Service) CloseOpenGame(gameID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.openGames, gameID)
+}
+
+// Process fills open games, groups compatible tickets into new games in FIFO order
+// and expires tickets that waited too long
+func (s *Service) Process(now time.Time) (matches []Match, assignments []Assignment, timedOut []*Ticket) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for segment, queue := range s.queues {
+		used := make(map[*Ticket]bool)
+
+		// Late joiners fill the smaller side of running games first
+		for _, ticket := range queue {
+			if !ticket.Ready() {
+				continue
+			}
+			if assignment, ok := s.fillOpenGame(ticket, now); ok {
+				used[ticket] = true
+				assignments = append(assignments, assignment)
+			}
+		}
+
+		// Ready tickets anchor games in arrival order and pick their opponents before newer tickets do. An anchor
+		// without enough compatible opponents is skipped so newer tickets can still play, until it has waited
+		// MaxSkipWait; then no newer ticket is matched until its band widens enough or the fallback takes it.
+		for i, anchor := range queue {
+			if used[anchor] || !anchor.Ready() {
+				continue
+			}
+
+			band := s.ratingBand(anchor, now)
+			candidates := []*Ticket{anchor}
+			players := anchor.Size()
+			for _, candidate := range queue[i+1:] {
+				if players >= 2*segment.TeamSize {
+					break
+				}
+				if used[candidate] || !candidate.Ready() {
+					continue
+				}
+				if abs(anchor.Rating-candidate.Rating) > band || players+candidate.Size() > 2*segment.TeamSize {
+					continue
+				}
+				candidates = append(candidates, candidate)
+				players += candidate.Size()
+			}
+
+			match, ok := s.balanceSides(segment, candidates)
+			if !ok {
+				if s.config.MaxSkipWait > 0 && now.Sub(anchor.EnqueuedAt) >= s.config.MaxSkipWait {
+					break
+				}
+				continue
+			}
+
+			for _, ticket := range candidates {
+				used[ticket] = true
+			}
+			matches = append(matches, match)
+			s.recordWait(segment, now.Sub(anchor.EnqueuedAt))
+		}
+
+		for ticket := range used {
+			s.removeUnsafe(ticket)
+		}
+	}
+
+	if s.config.Fallback != FallbackNone && s.config.MaxWait > 0 {
+		for _, queue := range s.queues {
+			for _, ticket := range queue {
+				if now.Sub(ticket.EnqueuedAt) >= s.config.MaxWait {
+					timedOut = append(timedOut, ticket)
+				}
+			}
+		}
+		for _, ticket := range timedOut {
+			s.removeUnsafe(ticket)
+		}
+	}
+
+	return matches, assignments, timedOut
+}
+
+// fillOpenGame places a ticket on the smaller side of the oldest compatible open game (caller must hold the lock)
+func (s *Service) fillOpenGame(ticket *Ticket, now time.Time) (Assignment, bool) {
+	band := s.ratingBand(ticket, now)
+
+	// Fill the longest running games first
+	gameIDs := slices.SortedFunc(maps.Keys(s.openGames), func(a, b string) int {
+		if order := s.openGames[a].createdAt.Compare(s.openGames[b].createdAt); order != 0 {
+			return order
+		}
+		return strings.Compare(a, b)
+	})
+
+	for _, gameID := range gameIDs {
+		game := s.openGames[gameID]
+		if game.segment != ticket.Segment {
+			continue
+		}
+
+		players := game.whitePlayers + game.blackPlayers
+		if players > 0 && abs(ticket.Rating-game.ratingTotal/players) > band {
+			continue
+		}
+
+		side := "white"
+		sideCount := &game.whitePlayers
+		if game.blackPlayers < game.whitePlayers {
+			side = "black"
+			sideCount = &game.blackPlayers
+		}
+		if *sideCount+ticket.Size() > game.segment.TeamSize {
+			continue
+		}
+
+		*sideCount += ticket.Size()
+		game.ratingTotal += ticket.Rating * ticket.Size()
+		if game.whitePlayers >= game.segment.TeamSize && game.blackPlayers >= game.segment.TeamSize {
+			delete(s.openGames, gameID)
+		}
+
+		return Assignment{GameID: gameID, Ticket: ticket, Side: side}, true
+	}
+
+	return Assignment{}, false
+}
+
+// balanceSides splits tickets into two sides of similar size and rating, keeping parties together
+func (s *Service) balanceSides(segment Segment, tickets []*Ticket) (Match, bool) {
+	ordered := slices.Clone(tickets)
+	slices.SortStableFunc(ordered, func(a, b *Ticket) int {
+		if a.Size() != b.Size() {
+			return b.Size() - a.Size()
+		}
+		return b.Rating - a.Rating
+	})
+
+	match := Match{Segment: segment}
+	var whitePlayers, blackPlayers, whiteRating, blackRating int
+
+	for _, ticket := range ordered {
+		// Prefer the side with fewer players, then the side with the lower rating total
+		toWhite := whitePlayers < blackPlayers ||
+			(whitePlayers == blackPlayers && whiteRating <= blackRating)
+		if toWhite && whitePlayers+ticket.Size() > segment.TeamSize {
+			toWhite = false
+		}
+		if !toWhite && blackPlayers+ticket.Size() > segment.TeamSize {
+			if whitePlayers+ticket.Size() > segment.TeamSize {
+				return Match{}, false
+			}
+			toWhite = true
+		}
+
+		if toWhite {
+			match.White = append(match.White, ticket)
+			whitePlayers += ticket.Size()
+			whiteRating += ticket.Rating * ticket.Size()
+		} else {
+			match.Black = append(match.Black, ticket)
+			blackPlayers += ticket.Size()
+			blackRating += ticket.Rating * ticket.Size()
+		}
+	}
+
+	minSize := s.MinTeamSize(segment.TeamSize)
+	if whitePlayers < minSize || blackPlayers < minSize {
+		return Match{}, false
+	}
+	if s.config.MaxImbalance > 0 && abs(whitePlayers-blackPlayers) > s.config.MaxImbalance {
+		return Match{}, false
+	}
+	return match, true
+}
+
+// Rating returns the current rating for a wallet
+func (s *Service) Rating(walletAddress string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ratingUnsafe(walletAddress)
+}
+
+// RecordResult updates team ratings after a game; winner is "white", "black" or "draw"
+func (s *Service) RecordResult(whiteWallets, blackWallets []string, winner string) {
+	if len(whiteWallets) == 0 || len(blackWallets) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	whiteRating := s.averageRatingUnsafe(whiteWallets)
+	blackRating := s.averageRatingUnsafe(blackWallets)
+
+	var whiteScore float64
+	switch winner {
+	case "white":
+		whiteScore = 1
+	case "black":
+		whiteScore = 0
+	case "draw":
+		whiteScore = 0.5
+	default:
+		return
+	}
+
+	expectedWhite := 1 / (1 + math.Pow(10, float64(blackRating-whiteRating)/400))
+	delta := int(math.Round(eloKFactor * (whiteScore - expectedWhite)))
+
+	for _, walletAddress := range whiteWallets {
+		s.ratings[walletAddress] = s.ratingUnsafe(walletAddress) + delta
+	}
+	for _, walletAddress := range blackWallets {
+		s.ratings[walletAddress] = s.ratingUnsafe(walletAddress) - delta
+	}
+}
+
+// ratingBand returns the allowed rating difference for a ticket at a given time
+func (s *Service) ratingBand(ticket *Ticket, now time.Time) int {
+	waited := int(now.Sub(ticket.EnqueuedAt) / time.Second)
+	band := s.config.BaseRatingBand + waited*s.config.RatingBandGrowth
+	if s.config.MaxRatingBand > 0 && band > s.config.MaxRatingBand {
+		band = s.config.MaxRatingBand
+	}
+	return band
+}
+
+// recordWait folds a matched ticket's wait time into the segment average (caller must hold the lock)
+func (s *Service) recordWait(segment Segment, wait time.Duration) {
+	average, ok := s.averageWait[segment]
+	if !ok {
+		s.averageWait[segment] = wait
+		return
+	}
+	// Exponential moving average weighted towards recent matches
+	s.averageWait[segment] = (average*3 + wait) / 4
+}
+
+// removeUnsafe deletes a ticket and its members from the queue (caller must hold the lock)
+func (s *Service) removeUnsafe(ticket *Ticket) {
+	queue := s.queues[ticket.Segment]
+	for i, t := range queue {
+		if t == ticket {
+			queue = append(queue[:i], queue[i+1:]...)
+			break
+		}
+	}
+
+	if len(queue) == 0 {
+		delete(s.queues, ticket.Segment)
+	} else {
+		s.queues[ticket.Segment] = queue
+	}
+
+	for _, walletAddress := range ticket.Members {
+		delete(s.tickets, walletAddress)
+	}
+	if ticket.PartyID != "" && s.parties[ticket.PartyID] == ticket {
+		delete(s.parties, ticket.PartyID)
+	}
+}
+
+// ratingUnsafe returns a wallet's rating (caller must hold the lock)
+func (s *Service) ratingUnsafe(walletAddress string) int {
+	if rating, exists := s.ratings[walletAddress]; exists {
+		return rating
+	}
+	return DefaultRating
+}
+
+// averageRatingUnsafe returns the mean rating of a group of wallets (caller must hold the lock)
+func (s *Service) averageRatingUnsafe(wallets []string) int {
+	if len(wallets) == 0 {
+		return DefaultRating
+	}
+
+	total := 0
+	for _, walletAddress := range wallets {
+		total += s.ratingUnsafe(walletAddress)
+	}
+	return total / len(wallets)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,97 @@
+package matchmaking
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestProcessGivesOldestTicketFirstPick(t *testing.T) {
+	s := NewService(Config{
+		BaseRatingBand:     100,
+		Fallback:           FallbackNone,
+		DefaultStakeLevel:  100000,
+		DefaultTimeControl: 30,
+		TeamSizes:          []int{1},
+		StakeLevels:        []int64{100000},
+		TimeControls:       []int{30},
+		MinTeamFillPercent: 100,
+	})
+	// b is out of everyone's band, so a must skip it and take c before d can
+	s.ratings["b"] = 1500
+	for _, wallet := range []string{"a", "b", "c", "d"} {
+		if _, err := s.Enqueue(wallet, Segment{ChainID: 84532, TeamSize: 1}, "", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	matches, _, _ := s.Process(time.Now())
+	if len(matches) != 1 {
+		t.Fatalf("Process() made %d matches, want 1", len(matches))
+	}
+	var wallets []string
+	for _, ticket := range append(matches[0].White, matches[0].Black...) {
+		wallets = append(wallets, ticket.Members...)
+	}
+	slices.Sort(wallets)
+	if !slices.Equal(wallets, []string{"a", "c"}) {
+		t.Fatalf("matched %v, want [a c]", wallets)
+	}
+	if !s.IsQueued("b") || !s.IsQueued("d") {
+		t.Fatal("unmatched tickets left the queue")
+	}
+}
+
+func TestProcessStopsSkippingLongWaitingTicket(t *testing.T) {
+	s := NewService(Config{
+		BaseRatingBand:     100,
+		MaxSkipWait:        time.Minute,
+		Fallback:           FallbackNone,
+		DefaultStakeLevel:  100000,
+		DefaultTimeControl: 30,
+		TeamSizes:          []int{1},
+		StakeLevels:        []int64{100000},
+		TimeControls:       []int{30},
+		MinTeamFillPercent: 100,
+	})
+	// a has no opponent in its band, b and c could play each other
+	s.ratings["b"], s.ratings["c"] = 1500, 1500
+	for _, wallet := range []string{"a", "b", "c"} {
+		if _, err := s.Enqueue(wallet, Segment{ChainID: 84532, TeamSize: 1}, "", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if matches, _, _ := s.Process(time.Now().Add(time.Minute)); len(matches) != 0 {
+		t.Fatalf("Process() matched %d games past a ticket skipped for too long, want 0", len(matches))
+	}
+	if matches, _, _ := s.Process(time.Now()); len(matches) != 1 {
+		t.Fatalf("Process() made %d matches before the skip bound, want 1", len(matches))
+	}
+}
+
+func TestProcessFillsOldestOpenGameFirst(t *testing.T) {
+	s := NewService(Config{
+		BaseRatingBand:     100,
+		Fallback:           FallbackNone,
+		DefaultStakeLevel:  100000,
+		DefaultTimeControl: 30,
+		TeamSizes:          []int{3},
+		StakeLevels:        []int64{100000},
+		TimeControls:       []int{30},
+		MinTeamFillPercent: 50,
+	})
+	segment := Segment{ChainID: 84532, StakeLevel: 100000, TimeControl: 30, TeamSize: 3}
+	for gameID, created := range map[string]int64{"newer": 2, "older": 1, "newest": 3} {
+		s.RegisterOpenGame(gameID, Match{Segment: segment, White: []*Ticket{{Members: []string{"w"}, PartySize: 1, Rating: DefaultRating}}})
+		s.openGames[gameID].createdAt = time.Unix(created, 0)
+	}
+	if _, err := s.Enqueue("late", segment, "", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	_, assignments, _ := s.Process(time.Now())
+	if len(assignments) != 1 || assignments[0].GameID != "older" {
+		t.Fatalf("Process() assignments = %+v, want the late joiner in the older game", assignments)
+	}
+}
@@ -0,0 +1,180 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// USDCDecimals is the number of decimals amounts are kept at, the decimals of USDC on most chains
+const USDCDecimals = 6
+
+// Amount is a USDC amount stored as integer base units (1 USDC = 1000000 units).
+// Token amounts are converted to this scale when they are built and back to the token's decimals with BigIntFor,
+// so arithmetic never mixes scales.
+type Amount struct {
+	units int64
+}
+
+// USDC creates a USDC amount from base units (1 USDC = 1000000 units)
+func USDC(units int64) Amount {
+	return Amount{units: units}
+}
+
+// FromBig creates an amount from a big.Int of base units with the given decimals.
+// It fails if the amount is finer than a USDC base unit or does not fit in an int64.
+func FromBig(units *big.Int, decimals uint8) (Amount, error) {
+	if units == nil {
+		return USDC(0), nil
+	}
+
+	scaled := new(big.Int).Set(units)
+	switch {
+	case decimals < USDCDecimals:
+		scaled.Mul(scaled, pow10(USDCDecimals-decimals))
+	case decimals > USDCDecimals:
+		var remainder big.Int
+		scaled.QuoRem(scaled, pow10(decimals-USDCDecimals), &remainder)
+		if remainder.Sign() != 0 {
+			return Amount{}, fmt.Errorf("amount %s with %d decimals is finer than a USDC base unit", units.String(), decimals)
+		}
+	}
+
+	if !scaled.IsInt64() {
+		return Amount{}, fmt.Errorf("amount %s overflows int64 base units", scaled.String())
+	}
+	return USDC(scaled.Int64()), nil
+}
+
+// Parse creates an amount from a decimal string such as "0.37" with at most the given decimals
+func Parse(value string, decimals uint8) (Amount, error) {
+	value = strings.TrimSpace(value)
+	whole, fraction, _ := strings.Cut(strings.TrimPrefix(value, "-"), ".")
+
+	// Only a single leading minus is allowed, the rest must be digits on at least one side of the point
+	notDigit := func(r rune) bool { return r < '0' || r > '9' }
+	if whole+fraction == "" || strings.IndexFunc(whole+fraction, notDigit) >= 0 {
+		return Amount{}, fmt.Errorf("invalid amount: %q", value)
+	}
+	if len(fraction) > int(decimals) {
+		return Amount{}, fmt.Errorf("amount %s has more than %d decimals", value, decimals)
+	}
+	fraction += strings.Repeat("0", int(decimals)-len(fraction))
+
+	units, _ := new(big.Int).SetString(whole+fraction, 10)
+	if strings.HasPrefix(value, "-") {
+		units.Neg(units)
+	}
+	return FromBig(units, decimals)
+}
+
+// Units returns the amount in base units
+func (a Amount) Units() int64 {
+	return a.units
+}
+
+// BigIntFor returns the amount in base units of a token with the given decimals, for contract calls.
+// It fails when the token has too few decimals to hold the amount exactly.
+func (a Amount) BigIntFor(decimals uint8) (*big.Int, error) {
+	units := big.NewInt(a.units)
+	switch {
+	case decimals > USDCDecimals:
+		units.Mul(units, pow10(decimals-USDCDecimals))
+	case decimals < USDCDecimals:
+		var remainder big.Int
+		units.QuoRem(units, pow10(USDCDecimals-decimals), &remainder)
+		if remainder.Sign() != 0 {
+			return nil, fmt.Errorf("amount %s cannot be expressed with %d decimals", a, decimals)
+		}
+	}
+	return units, nil
+}
+
+// IsZero reports whether the amount is zero
+func (a Amount) IsZero() bool {
+	return a.units == 0
+}
+
+// IsPositive reports whether the amount is greater than zero
+func (a Amount) IsPositive() bool {
+	return a.units > 0
+}
+
+// Add returns a + b
+func (a Amount) Add(b Amount) Amount {
+	return USDC(a.units + b.units)
+}
+
+// Sub returns a - b
+func (a Amount) Sub(b Amount) Amount {
+	return USDC(a.units - b.units)
+}
+
+// Mul returns the amount multiplied by an integer, failing if the result does not fit in an int64
+func (a Amount) Mul(n int64) (Amount, error) {
+	return a.MulDiv(n, 1)
+}
+
+// MulDiv returns a * numerator / denominator rounded toward zero, without intermediate overflow.
+// It fails if the result does not fit in an int64 or the denominator is zero.
+func (a Amount) MulDiv(numerator, denominator int64) (Amount, error) {
+	if denominator == 0 {
+		return Amount{}, fmt.Errorf("amount %s divided by zero", a)
+	}
+	result := new(big.Int).Mul(big.NewInt(a.units), big.NewInt(numerator))
+	result.Quo(result, big.NewInt(denominator))
+	if !result.IsInt64() {
+		return Amount{}, fmt.Errorf("amount %s * %d / %d overflows int64 base units", a, numerator, denominator)
+	}
+	return USDC(result.Int64()), nil
+}
+
+// Cmp compares two amounts, returning -1, 0 or +1
+func (a Amount) Cmp(b Amount) int {
+	switch {
+	case a.units < b.units:
+		return -1
+	case a.units > b.units:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// String formats the amount as an exact decimal, e.g. "0.37"
+func (a Amount) String() string {
+	units := a.units
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+
+	digits := fmt.Sprintf("%0*d", USDCDecimals+1, units)
+	whole := digits[:len(digits)-USDCDecimals]
+	fraction := strings.TrimRight(digits[len(digits)-USDCDecimals:], "0")
+	if fraction == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + fraction
+}
+
+// MarshalJSON encodes the amount as an exact JSON number so clients keep receiving numeric values
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON decodes a JSON number or string as a USDC amount
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	parsed, err := Parse(strings.Trim(string(data), `"`), USDCDecimals)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// pow10 returns 10^n
+func pow10(n uint8) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,137 @@
+package money
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "0.37", want: 370000},
+		{value: " 2.5 ", want: 2500000},
+		{value: "-0.000001", want: -1},
+		{value: "0.1234567", wantErr: true},
+		{value: ".5", want: 500000},
+		{value: "5.", want: 5000000},
+		{value: "abc", wantErr: true},
+		{value: "--1", wantErr: true},
+		{value: "1-", wantErr: true},
+		{value: "0.-1", wantErr: true},
+		{value: "+1", wantErr: true},
+		{value: "", wantErr: true},
+		{value: ".", wantErr: true},
+		{value: "-", wantErr: true},
+		{value: "9223372036854.775808", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := Parse(tt.value, USDCDecimals)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) = %s, %v, want error %v", tt.value, got, err, tt.wantErr)
+			}
+			if err == nil && got.Units() != tt.want {
+				t.Fatalf("Parse(%q) = %d units, want %d", tt.value, got.Units(), tt.want)
+			}
+		})
+	}
+}
+
+func TestMulDivRoundsDown(t *testing.T) {
+	tests := []struct {
+		name        string
+		units       int64
+		numerator   int64
+		denominator int64
+		want        int64
+		wantErr     bool
+	}{
+		{name: "exact", units: 900000, numerator: 1, denominator: 3, want: 300000},
+		{name: "remainder dropped", units: 1000000, numerator: 1, denominator: 3, want: 333333},
+		{name: "negative rounds toward zero", units: -1000000, numerator: 1, denominator: 3, want: -333333},
+		{name: "no intermediate overflow", units: math.MaxInt64 / 2, numerator: 4, denominator: 8, want: math.MaxInt64 / 4},
+		{name: "zero denominator", units: 1000000, numerator: 1, denominator: 0, wantErr: true},
+		{name: "overflow", units: math.MaxInt64 / 2, numerator: 3, denominator: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := USDC(tt.units).MulDiv(tt.numerator, tt.denominator)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MulDiv(%d, %d) = %s, %v, want error %v", tt.numerator, tt.denominator, got, err, tt.wantErr)
+			}
+			if err == nil && got.Units() != tt.want {
+				t.Fatalf("MulDiv(%d, %d) = %d units, want %d", tt.numerator, tt.denominator, got.Units(), tt.want)
+			}
+		})
+	}
+}
+
+func TestMulChecksOverflow(t *testing.T) {
+	if got, err := USDC(1000000).Mul(3); err != nil || got.Units() != 3000000 {
+		t.Fatalf("Mul(3) = %s, %v, want 3", got, err)
+	}
+	if got, err := USDC(math.MaxInt64 / 2).Mul(3); err == nil {
+		t.Fatalf("Mul(3) = %s, want overflow error", got)
+	}
+}
+
+func TestBigIntForRescales(t *testing.T) {
+	tests := []struct {
+		name     string
+		units    int64
+		decimals uint8
+		want     string
+		wantErr  bool
+	}{
+		{name: "usdc", units: 370000, decimals: 6, want: "370000"},
+		{name: "more decimals", units: 370000, decimals: 18, want: "370000000000000000"},
+		{name: "fewer decimals", units: 370000, decimals: 2, want: "37"},
+		{name: "not expressible", units: 1, decimals: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := USDC(tt.units).BigIntFor(tt.decimals)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BigIntFor(%d) = %v, %v, want error %v", tt.decimals, got, err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Fatalf("BigIntFor(%d) = %s, want %s", tt.decimals, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromBigRescales(t *testing.T) {
+	tests := []struct {
+		name     string
+		units    *big.Int
+		decimals uint8
+		want     int64
+		wantErr  bool
+	}{
+		{name: "usdc", units: big.NewInt(370000), decimals: 6, want: 370000},
+		{name: "fewer decimals", units: big.NewInt(37), decimals: 2, want: 370000},
+		{name: "more decimals", units: big.NewInt(370000000000000000), decimals: 18, want: 370000},
+		{name: "finer than a base unit", units: big.NewInt(1), decimals: 18, wantErr: true},
+		{name: "overflow after scaling", units: big.NewInt(math.MaxInt64/10 + 1), decimals: 5, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromBig(tt.units, tt.decimals)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FromBig() = %s, %v, want error %v", got, err, tt.wantErr)
+			}
+			if err == nil && got.Units() != tt.want {
+				t.Fatalf("FromBig() = %d units, want %d", got.Units(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+
+	"blockchess/internal/auth"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// handleAuthChallenge issues an EIP-4361 sign-in message for the address the client wants to sign in with
+func (h *Hub) handleAuthChallenge(msg *Message, client *Client) {
+	if !common.IsHexAddress(msg.WalletAddress) {
+		h.sendErrorToClient(client, "A valid wallet address is required to sign in")
+		return
+	}
+
+	challenge, err := auth.NewChallenge(h.authConfig, common.HexToAddress(msg.WalletAddress), uint64(msg.ChainId))
+	if err != nil {
+		log.Printf("Failed to create sign-in challenge for client %s: %v", client.id, err)
+		h.sendErrorToClient(client, "Failed to create sign-in message")
+		return
+	}
+
+	// A new challenge replaces the pending one, so every nonce can be used once at most
+	h.authChallenges[client] = challenge
+
+	h.sendToClient(client, &Message{
+		Type:          TypeAuthChallenge,
+		WalletAddress: challenge.Address.Hex(),
+		ChainId:       msg.ChainId,
+		AuthMessage:   challenge.Message,
+		Nonce:         challenge.Nonce,
+	})
+}
+
+// handleAuthLogin verifies the signature over the client's pending challenge and binds the connection to the address
+func (h *Hub) handleAuthLogin(msg *Message, client *Client) {
+	challenge, exists := h.authChallenges[client]
+	if !exists {
+		h.sendErrorCodeToClient(client, auth.ErrorNoChallenge, "No sign-in message pending, please request one first")
+		return
+	}
+	delete(h.authChallenges, client)
+
+	address, err := challenge.Verify(msg.Signature)
+	if err != nil {
+		log.Printf("Sign-in of client %s as %s failed: %v", client.id, challenge.Address.Hex(), err)
+		var authErr *auth.Error
+		if errors.As(err, &authErr) {
+			h.sendErrorCodeToClient(client, authErr.Code, authErr.Message)
+		} else {
+			h.sendErrorToClient(client, err.Error())
+		}
+		return
+	}
+
+	walletAddress := address.Hex()
+	if previous, exists := h.authWallets[client]; exists && previous != walletAddress {
+		// Switching accounts leaves the previous wallet's team seat behind, like a disconnect would
+		delete(h.clientTeams, previous)
+	}
+	h.authWallets[client] = walletAddress
+	h.clientWallets[client] = walletAddress
+	if challenge.ChainID != 0 {
+		h.gameManager.SetPlayerChainID(walletAddress, uint32(challenge.ChainID))
+	}
+
+	log.Printf("Client %s signed in as %s", client.id, walletAddress)
+	h.sendToClient(client, &Message{
+		Type:          TypeAuthSuccess,
+		WalletAddress: walletAddress,
+		ChainId:       uint32(challenge.ChainID),
+	})
+}
+
+// authenticatedWallet returns the address the client signed in with, telling the client to sign in when it has not
+func (h *Hub) authenticatedWallet(client *Client) (string, bool) {
+	walletAddress, exists := h.authWallets[client]
+	if !exists {
+		log.Printf("Client %s sent a wallet message without signing in", client.id)
+		h.sendErrorCodeToClient(client, auth.ErrorRequired, "Please sign in with your wallet first")
+		return "", false
+	}
+	return walletAddress, true
+}
+
+// sendToClient sends a message to a single client without blocking
+func (h *Hub) sendToClient(client *Client, message *Message) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Failed to marshal %s for client %s: %v", message.Type, client.id, err)
+		return
+	}
+
+	select {
+	case client.send <- data:
+	default:
+		log.Printf("Failed to send %s to client %s: channel full", message.Type, client.id)
+	}
+}
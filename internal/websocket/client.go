@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
@@ -44,6 +45,9 @@ type Client struct {
 
 	// Client ID
 	id string
+
+	// Session the connection holds, which changes when it resumes another one. Only the hub loop touches it.
+	sessionID string
 }
 
 // ClientMessage wraps a message with its sender
@@ -146,6 +150,7 @@ func ServeWS(hub *Hub, w http.ResponseWriter, r *http.Request) {
 		send: make(chan []byte, 256),
 		id:   generateClientID(),
 	}
+	client.sessionID = client.id
 
 	client.hub.register <- client
 
@@ -155,6 +160,7 @@ func ServeWS(hub *Hub, w http.ResponseWriter, r *http.Request) {
 	go client.readPump()
 }
 
+// generateClientID returns a unique ID for a new connection, which is also its session ID
 func generateClientID() string {
-	return "client_" + time.Now().Format("20060102150405")
+	return "client_" + uuid.New().String()
 }
@@ -1,10 +1,17 @@
 package websocket
 
 import (
+	"blockchess/internal/auth"
 	"blockchess/internal/game"
+	"blockchess/internal/matchmaking"
+	"blockchess/internal/money"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -34,26 +41,41 @@ const (
 	TypeError                    = "error"
 	TypePermitSignature          = "permit_signature"
 	TypeRequestPermitSignature   = "request_permit_signature"
+	TypePermitValid              = "permit_valid"
+	TypeRequestVotePermit        = "request_vote_permit"
+	TypeVotePermit               = "vote_permit"
+	TypeQueueStatus              = "queue_status"
+	TypeVoteRejected             = "vote_rejected"
+	TypeAuthChallenge            = "auth_challenge"
+	TypeAuthLogin                = "auth_login"
+	TypeAuthSuccess              = "auth_success"
+	TypeResume                   = "resume"
+	TypeResumed                  = "resumed"
 )
 
+// matchmakingInterval is how often queued tickets are re-evaluated
+const matchmakingInterval = 1 * time.Second
+
 // GameInfo holds summary information about a single game
 type GameInfo struct {
-	GameID       string     `json:"gameId"`
-	WhitePlayers int        `json:"whitePlayers"`
-	BlackPlayers int        `json:"blackPlayers"`
-	TimeLeft     int        `json:"timeLeft"`
-	CurrentMove  int        `json:"currentMove"`
-	TotalPot     float64    `json:"totalPot"`
-	WhitePot     float64    `json:"whitePot"`
-	BlackPot     float64    `json:"blackPot"`
-	Spectators   int        `json:"spectators"`
-	CurrentTurn  string     `json:"currentTurn"`
-	Status       string     `json:"status"`              // "active" or "ended"
-	Winner       string     `json:"winner,omitempty"`    // "white", "black", "draw" (only for ended games)
-	EndReason    string     `json:"endReason,omitempty"` // "checkmate", "stalemate", etc. (only for ended games)
-	CreatedAt    int64      `json:"createdAt"`           // Unix timestamp when game was created
-	EndedAt      *int64     `json:"endedAt,omitempty"`   // Unix timestamp when game ended
-	Board        [][]string `json:"board,omitempty"`     // Current board state
+	GameID       string       `json:"gameId"`
+	WhitePlayers int          `json:"whitePlayers"`
+	BlackPlayers int          `json:"blackPlayers"`
+	TimeLeft     int          `json:"timeLeft"`
+	CurrentMove  int          `json:"currentMove"`
+	TotalPot     money.Amount `json:"totalPot"`
+	WhitePot     money.Amount `json:"whitePot"`
+	BlackPot     money.Amount `json:"blackPot"`
+	Spectators   int          `json:"spectators"`
+	CurrentTurn  string       `json:"currentTurn"`
+	Status       string       `json:"status"`                // "active" or "ended"
+	Winner       string       `json:"winner,omitempty"`      // "white", "black", "draw" (only for ended games)
+	EndReason    string       `json:"endReason,omitempty"`   // "checkmate", "stalemate", etc. (only for ended games)
+	CreatedAt    int64        `json:"createdAt"`             // Unix timestamp when game was created
+	EndedAt      *int64       `json:"endedAt,omitempty"`     // Unix timestamp when game ended
+	Board        [][]string   `json:"board,omitempty"`       // Current board state
+	MaxTeamSize  int          `json:"maxTeamSize,omitempty"` // Maximum players per team, 0 for unlimited
+	TeamsLocked  bool         `json:"teamsLocked,omitempty"` // True once the game no longer accepts players
 
 	// Player statistics per team (only for ended games)
 	WhiteTeamPlayers []PlayerStats `json:"whiteTeamPlayers,omitempty"`
@@ -77,11 +99,24 @@ type Message struct {
 	TotalConnections int            `json:"totalConnections,omitempty"`
 	Filter           string         `json:"filter,omitempty"`     // "active", "ended", or "" for all
 	Error            string         `json:"error,omitempty"`      // Error message
+	ErrorCode        string         `json:"errorCode,omitempty"`  // Machine-readable error, e.g. "permit_wrong_signer"
 	ValidMoves       []string       `json:"validMoves,omitempty"` // List of valid moves in coordinate notation
-	Signature        string         `json:"signature,omitempty"`  // Permit2 signature
+	Signature        string         `json:"signature,omitempty"`  // Permit2 signature, or the vote permit signature on vote_move
 	TypedData        interface{}    `json:"typedData,omitempty"`
 	ChainId          uint32         `json:"chainId,omitempty"` // EIP-712 typed data
 
+	// Matchmaking preferences and queue status
+	StakeLevel    int64  `json:"stakeLevel,omitempty"`    // Stake per vote in USDC base units
+	TimeControl   int    `json:"timeControl,omitempty"`   // Seconds per turn
+	QueuePosition int    `json:"queuePosition,omitempty"` // 1-based position in the matchmaking queue
+	QueueSize     int    `json:"queueSize,omitempty"`     // Number of players in the same queue
+	EstimatedWait int    `json:"estimatedWait,omitempty"` // Estimated seconds until a match is found
+	Fallback      string `json:"fallback,omitempty"`      // "lobby" or "bot" when no opponent was found in time
+	TeamSize      int    `json:"teamSize,omitempty"`      // Target players per side, e.g. 3 for 3v3
+	PartyID       string `json:"partyId,omitempty"`       // Friends sharing a party ID queue together and stay on one side
+	PartySize     int    `json:"partySize,omitempty"`     // Number of members the party is waiting for
+	PartyMembers  int    `json:"partyMembers,omitempty"`  // Number of party members that have joined the queue
+
 	// Game statistics
 	WhitePlayers          int             `json:"whitePlayers,omitempty"`
 	BlackPlayers          int             `json:"blackPlayers,omitempty"`
@@ -89,16 +124,23 @@ type Message struct {
 	BlackCurrentTurnVotes int             `json:"blackCurrentTurnVotes,omitempty"`
 	WhiteTeamTotalVotes   int             `json:"whiteTeamTotalVotes,omitempty"`
 	BlackTeamTotalVotes   int             `json:"blackTeamTotalVotes,omitempty"`
-	TotalPot              float64         `json:"totalPot,omitempty"`
-	WhitePot              float64         `json:"whitePot,omitempty"`
-	BlackPot              float64         `json:"blackPot,omitempty"`
+	TotalPot              *money.Amount   `json:"totalPot,omitempty"`
+	WhitePot              *money.Amount   `json:"whitePot,omitempty"`
+	BlackPot              *money.Amount   `json:"blackPot,omitempty"`
+	Fee                   *money.Amount   `json:"fee,omitempty"`              // Platform fee taken from the pot
+	Stake                 *money.Amount   `json:"stake,omitempty"`            // Stake of the refused vote
+	Available             *money.Amount   `json:"available,omitempty"`        // Balance or allowance the player has
+	TopUp                 *money.Amount   `json:"topUp,omitempty"`            // USDC missing to pay the stake
+	NetPayout             *money.Amount   `json:"netPayout,omitempty"`        // Pot left for winners after the fee
+	Refunds               []game.Refund   `json:"refunds,omitempty"`          // Stakes returned on draws, aborts and cancellations
+	SettlementStatus      string          `json:"settlementStatus,omitempty"` // State of the on-chain payout or refund
 	CurrentTurn           string          `json:"currentTurn,omitempty"`
 	CurrentMove           int             `json:"currentMove,omitempty"`
 	PlayerVotedThisRound  map[string]bool `json:"playerVotedThisRound,omitempty"`
 	PlayerTotalVotes      map[string]int  `json:"playerTotalVotes,omitempty"`
 
 	// Game end information
-	Winner        string `json:"winner,omitempty"`        // "white", "black", "draw"
+	Winner        string `json:"winner,omitempty"`        // "white", "black", "draw", "aborted", "cancelled"
 	GameEndReason string `json:"gameEndReason,omitempty"` // "checkmate", "stalemate", "draw"
 	PlayerVotes   int    `json:"playerVotes,omitempty"`   // Current player's total votes
 
@@ -109,12 +151,25 @@ type Message struct {
 	// Check and checkmate status
 	IsInCheck   bool `json:"isInCheck,omitempty"`
 	IsCheckmate bool `json:"isCheckmate,omitempty"`
+
+	// Vote rejection information
+	RolledBack bool `json:"rolledBack,omitempty"` // True when the rejected vote was removed from the current round
+
+	// Sign-In with Ethereum
+	AuthMessage string `json:"authMessage,omitempty"` // EIP-4361 message to sign with personal_sign
+	Nonce       string `json:"nonce,omitempty"`       // Nonce of the sign-in message
+
+	// Resumable sessions
+	Seq         uint64            `json:"seq,omitempty"`         // Number of the game event, increasing per game
+	Snapshot    bool              `json:"snapshot,omitempty"`    // True when the message carries a game's full state instead of an event
+	ResumeToken string            `json:"resumeToken,omitempty"` // Signed token a reconnect resumes the session with
+	LastSeq     map[string]uint64 `json:"lastSeq,omitempty"`     // Last event seen per game, sent with resume
 }
 
 type PlayerStats struct {
-	WalletAddress string  `json:"walletAddress"`
-	TotalVotes    int     `json:"totalVotes"`
-	TotalSpent    float64 `json:"totalSpent"`
+	WalletAddress string       `json:"walletAddress"`
+	TotalVotes    int          `json:"totalVotes"`
+	TotalSpent    money.Amount `json:"totalSpent"`
 }
 type Hub struct {
 	// Registered clients
@@ -138,28 +193,94 @@ type Hub struct {
 	// Ended games - gameID -> GameInfo
 	endedGames map[string]*GameInfo
 
-	// Matchmaking queue - wallet addresses with their clients
-	matchmakingQueue map[string]*Client
+	// Matchmaking service holding the ordered queues
+	matchmaker *matchmaking.Service
+
+	// Matchmaking clients - walletAddress -> client waiting in the queue
+	matchmakingClients map[string]*Client
 
 	// Client teams - walletAddress -> team
 	clientTeams map[string]string
 
 	// Client wallet addresses - client -> wallet address
 	clientWallets map[*Client]string
+
+	// Sign-In with Ethereum settings, pending challenges and the address each connection signed in with
+	authConfig     auth.Config
+	authChallenges map[*Client]*auth.Challenge
+	authWallets    map[*Client]string
+
+	// Resumable sessions - sessionID -> session, and each game's numbered events guarded by eventsMu
+	sessionTokens *auth.SessionTokens
+	sessions      map[string]*session
+	eventsMu      sync.Mutex
+	eventLogs     map[string]*eventLog
+
+	// Votes withdrawn by the game manager after their stake failed
+	voteRejected chan game.VoteRejection
+
+	// Matchmaking games whose contract was created in the background
+	createdGames chan createdGame
+
+	// Permit signatures verified in the background
+	permitChecks chan permitCheck
 }
 
-func NewHub(gm *game.Manager) *Hub {
+// createdGame is the outcome of creating a game for a match or for a ticket that timed out
+type createdGame struct {
+	gameState *game.GameState
+	err       error
+	match     *matchmaking.Match  // Set for balanced matches
+	ticket    *matchmaking.Ticket // Set for fallback games
+}
+
+// permitCheck is the outcome of verifying a permit signature a player sent
+type permitCheck struct {
+	client        *Client
+	walletAddress string
+	chainID       uint32
+	vote          *Message // Set when the signature pays for this vote
+	err           error
+}
+
+func NewHub(gm *game.Manager, matchmaker *matchmaking.Service, addr string) (*Hub, error) {
+	authConfig := auth.LoadConfig()
+	if authConfig.UseServerAddress(addr) {
+		log.Printf("Warning: AUTH_DOMAIN or AUTH_URI is not set, sign-in messages name %s (%s). Set both to the site's host and origin before going live",
+			authConfig.Domain, authConfig.URI)
+	}
+	if err := authConfig.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid sign-in configuration: %w", err)
+	}
+	sessionTokens, err := auth.NewSessionTokens(authConfig.SessionSecret, authConfig.SessionTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session tokens: %w", err)
+	}
+
 	h := &Hub{
-		broadcast:        make(chan *ClientMessage),
-		register:         make(chan *Client),
-		unregister:       make(chan *Client),
-		clients:          make(map[*Client]bool),
-		gameManager:      gm,
-		gameRooms:        make(map[string]map[*Client]bool),
-		endedGames:       make(map[string]*GameInfo),
-		matchmakingQueue: make(map[string]*Client),
-		clientTeams:      make(map[string]string),
-		clientWallets:    make(map[*Client]string),
+		broadcast:     make(chan *ClientMessage),
+		register:      make(chan *Client),
+		unregister:    make(chan *Client),
+		clients:       make(map[*Client]bool),
+		gameManager:   gm,
+		gameRooms:     make(map[string]map[*Client]bool),
+		endedGames:    make(map[string]*GameInfo),
+		clientTeams:   make(map[string]string),
+		clientWallets: make(map[*Client]string),
+		voteRejected:  make(chan game.VoteRejection, 64),
+		createdGames:  make(chan createdGame),
+		permitChecks:  make(chan permitCheck),
+
+		authConfig:     authConfig,
+		authChallenges: make(map[*Client]*auth.Challenge),
+		authWallets:    make(map[*Client]string),
+
+		sessionTokens: sessionTokens,
+		sessions:      make(map[string]*session),
+		eventLogs:     make(map[string]*eventLog),
+
+		matchmaker:         matchmaker,
+		matchmakingClients: make(map[string]*Client),
 	}
 
 	// Set up the move result callback
@@ -168,34 +289,41 @@ func NewHub(gm *game.Manager) *Hub {
 	// Set up the game end callback
 	gm.SetGameEndCallback(h.handleGameEnd)
 
+	// Hand rejected votes to the hub loop, which owns the client maps. Stake workers must never wait on a busy hub,
+	// so a rejection that does not fit is dropped. The vote is still withdrawn and the next update carries the pot.
+	gm.SetVoteRejectedCallback(func(rejection game.VoteRejection) {
+		select {
+		case h.voteRejected <- rejection:
+		default:
+			log.Printf("Warning: Dropped vote rejection of %s in game %s, hub queue full", rejection.WalletAddress, rejection.GameID)
+		}
+	})
+
 	// Start periodic updates
 	go h.startPeriodicUpdates()
 
-	return h
+	return h, nil
 }
 
 func (h *Hub) Run() {
+	matchmakingTicker := time.NewTicker(matchmakingInterval)
+	defer matchmakingTicker.Stop()
+	sessionTicker := time.NewTicker(sessionSweepInterval)
+	defer sessionTicker.Stop()
+
 	for {
 		select {
+		case <-matchmakingTicker.C:
+			h.runMatchmaking()
+
+		case <-sessionTicker.C:
+			h.sweepSessions()
+
 		case client := <-h.register:
 			h.clients[client] = true
 
-			// Send client ID to the newly connected client
-			clientMsg := &Message{
-				Type:     TypeClientConnected,
-				ClientID: client.id,
-			}
-			if data, err := json.Marshal(clientMsg); err == nil {
-				log.Printf("Sending client_connected to %s: %s", client.id, string(data))
-				select {
-				case client.send <- data:
-					log.Printf("✅ Successfully sent client_connected to %s", client.id)
-				default:
-					log.Printf("❌ Failed to send client_connected to %s - channel full", client.id)
-				}
-			} else {
-				log.Printf("❌ Failed to marshal client_connected for %s: %v", client.id, err)
-			}
+			// Send the session ID and resume token to the newly connected client
+			h.openSession(client)
 
 			h.broadcastToAll(&Message{
 				Type:             TypeNumberOfPlayers,
@@ -204,55 +332,75 @@ func (h *Hub) Run() {
 			log.Printf("Client registered: %s (Total connections: %d)", client.id, len(h.clients))
 
 		case client := <-h.unregister:
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
+			h.removeClient(client)
 
-				// Remember if this client was in any game
-				wasInGame := false
+		case clientMessage := <-h.broadcast:
+			// Handle incoming message from client
+			h.handleClientMessage(clientMessage)
 
-				// Remove from matchmaking queue
-				h.removeFromMatchmaking(client)
+		case rejection := <-h.voteRejected:
+			h.handleVoteRejected(rejection)
 
-				// Remove from game rooms
-				for _, room := range h.gameRooms {
-					if _, ok := room[client]; ok {
-						delete(room, client)
-						wasInGame = true
-						// Don't delete empty game rooms - games should persist even with no spectators
-						// Only delete rooms when games actually end
-					}
-				}
+		case created := <-h.createdGames:
+			h.handleCreatedGame(created)
 
-				// Remove from client teams using wallet address
-				if walletAddress, exists := h.clientWallets[client]; exists {
-					if h.clientTeams[walletAddress] != "" {
-						wasInGame = true
-					}
-					delete(h.clientTeams, walletAddress)
-				}
+		case check := <-h.permitChecks:
+			h.handlePermitCheck(check)
+		}
+	}
+}
 
-				// Remove from client wallets
-				delete(h.clientWallets, client)
+// removeClient unregisters a connection and removes it from matchmaking, game rooms and teams
+func (h *Hub) removeClient(client *Client) {
+	if _, ok := h.clients[client]; !ok {
+		return
+	}
+	delete(h.clients, client)
+	close(client.send)
 
-				log.Printf("Client unregistered: %s (Total connections: %d)", client.id, len(h.clients))
+	// Keep the session for a reconnect before its state is removed
+	h.detachSession(client)
 
-				// Broadcast updated total connections count to all remaining clients
-				h.broadcastToAll(&Message{
-					Type:             TypeNumberOfPlayers,
-					TotalConnections: h.GetTotalConnections(),
-				})
+	// Remember if this client was in any game
+	wasInGame := false
 
-				// Broadcast updated games list if client was in a game
-				if wasInGame {
-					h.broadcastGamesListUpdate()
-				}
-			}
+	// Remove from matchmaking queue
+	h.removeFromMatchmaking(client)
 
-		case clientMessage := <-h.broadcast:
-			// Handle incoming message from client
-			h.handleClientMessage(clientMessage)
+	// Remove from game rooms
+	for _, room := range h.gameRooms {
+		if _, ok := room[client]; ok {
+			delete(room, client)
+			wasInGame = true
+			// Don't delete empty game rooms - games should persist even with no spectators
+			// Only delete rooms when games actually end
+		}
+	}
+
+	// Remove from client teams using wallet address
+	if walletAddress, exists := h.clientWallets[client]; exists {
+		if h.clientTeams[walletAddress] != "" {
+			wasInGame = true
 		}
+		delete(h.clientTeams, walletAddress)
+	}
+
+	// Remove from client wallets and forget the sign-in
+	delete(h.clientWallets, client)
+	delete(h.authChallenges, client)
+	delete(h.authWallets, client)
+
+	log.Printf("Client unregistered: %s (Total connections: %d)", client.id, len(h.clients))
+
+	// Broadcast updated total connections count to all remaining clients
+	h.broadcastToAll(&Message{
+		Type:             TypeNumberOfPlayers,
+		TotalConnections: h.GetTotalConnections(),
+	})
+
+	// Broadcast updated games list if client was in a game
+	if wasInGame {
+		h.broadcastGamesListUpdate()
 	}
 }
 
@@ -268,6 +416,15 @@ func (h *Hub) handleClientMessage(clientMessage *ClientMessage) {
 
 func (h *Hub) handleMessage(msg *Message, client *Client) {
 	switch msg.Type {
+	case TypeAuthChallenge:
+		h.handleAuthChallenge(msg, client)
+
+	case TypeAuthLogin:
+		h.handleAuthLogin(msg, client)
+
+	case TypeResume:
+		h.handleResume(msg, client)
+
 	case TypeJoinGame:
 		log.Printf("Player %s joining game: %s", client.id, msg.GameID)
 
@@ -279,42 +436,19 @@ func (h *Hub) handleMessage(msg *Message, client *Client) {
 			return
 		}
 
-		// Add client to game room
-		h.AddClientToGame(client, msg.GameID)
-
-		// Send initial game state to the joining player
-		votes := h.gameManager.GetVotes(msg.GameID)
-		stats := h.gameManager.GetGameStats(msg.GameID)
-
-		initialMsg := &Message{
-			Type:   TypeVoteUpdate,
-			GameID: msg.GameID,
-			Votes:  votes,
-		}
-
-		h.updateStats(stats, initialMsg)
-
-		// Send initial state to the joining client
-		if data, err := json.Marshal(initialMsg); err == nil {
-			select {
-			case client.send <- data:
-			default:
-			}
-		}
+		// Add client to game room and send it the game's state, numbered with its last event
+		h.joinGameRoom(client, msg.GameID)
 
 		// Broadcast updated games list to all clients
 		h.broadcastGamesListUpdate()
 
 	case TypeVoteMove:
-		// Use player ID (wallet address) from message if provided, otherwise fall back to client ID
-		walletAddress := msg.PlayerID
-		if walletAddress == "" {
-			walletAddress = client.id
+		// Votes are cast by the signed-in wallet, never by the address in the payload
+		walletAddress, ok := h.authenticatedWallet(client)
+		if !ok {
+			return
 		}
 
-		// Store the wallet address mapping for this client
-		h.clientWallets[client] = walletAddress
-
 		log.Printf("Vote for move %s in game %s from wallet %s", msg.Move, msg.GameID, walletAddress)
 
 		// Get player's team from the game manager (authoritative source)
@@ -331,66 +465,47 @@ func (h *Hub) handleMessage(msg *Message, client *Client) {
 			chainID = h.gameManager.GetPlayerChainID(walletAddress)
 		}
 
-		// Attempt to vote
-		if err := h.gameManager.VoteForMove(msg.GameID, walletAddress, msg.Move, team, chainID); err != nil {
-			log.Printf("Vote failed for player %s: %v", walletAddress, err)
-			h.sendErrorToClient(client, err.Error())
+		// A vote carrying a signature pays its stake with the vote permit issued for this game, the vote is cast once
+		// the signature is verified
+		if msg.Signature != "" {
+			gameID, signature := msg.GameID, msg.Signature
+			h.checkPermit(permitCheck{client: client, walletAddress: walletAddress, chainID: chainID, vote: msg}, func() error {
+				return h.gameManager.AttachVotePermit(gameID, walletAddress, chainID, signature)
+			})
 			return
 		}
 
-		// Get updated votes and game stats
-		votes := h.gameManager.GetVotes(msg.GameID)
-		stats := h.gameManager.GetGameStats(msg.GameID)
-
-		// Create vote update message with all stats
-		updateMsg := &Message{
-			Type:   TypeVoteUpdate,
-			GameID: msg.GameID,
-			Votes:  votes,
-		}
-
-		h.updateStats(stats, updateMsg)
-
-		h.broadcastToGame(msg.GameID, updateMsg)
-
-		// Broadcast updated games list since pot has changed
-		h.broadcastGamesListUpdate()
+		h.castVote(client, walletAddress, msg, team, chainID)
 
 	case TypeJoinMatchmaking:
-		walletAddress := msg.WalletAddress
-		if walletAddress == "" {
-			log.Printf("No wallet address provided for matchmaking from client %s", client.id)
-			h.sendErrorToClient(client, "Wallet address is required for matchmaking")
+		walletAddress, ok := h.authenticatedWallet(client)
+		if !ok {
 			return
 		}
 
-		// Store the wallet address mapping for this client
-		h.clientWallets[client] = walletAddress
-
 		// Set the player's chain ID in the game manager if provided
 		if msg.ChainId != 0 {
 			h.gameManager.SetPlayerChainID(walletAddress, msg.ChainId)
 		}
 
 		log.Printf("Player %s (wallet: %s) joining matchmaking on chain %d", client.id, walletAddress, msg.ChainId)
-		h.addToMatchmaking(client, walletAddress)
+		h.addToMatchmaking(client, walletAddress, matchmaking.Segment{
+			ChainID:     msg.ChainId,
+			StakeLevel:  msg.StakeLevel,
+			TimeControl: msg.TimeControl,
+			TeamSize:    msg.TeamSize,
+		}, msg.PartyID, msg.PartySize)
 
 	case TypeLeaveMatchmaking:
 		log.Printf("Player %s leaving matchmaking", client.id)
 		h.removeFromMatchmaking(client)
 
 	case TypeJoinTeam:
-		// Use player ID (wallet address) from message if provided, otherwise fall back to client ID
-		walletAddress := msg.PlayerID
-		if walletAddress == "" {
-			log.Printf("No wallet address provided for team join from client %s", client.id)
-			h.sendErrorToClient(client, "Wallet address is required to join a team")
+		walletAddress, ok := h.authenticatedWallet(client)
+		if !ok {
 			return
 		}
 
-		// Store the wallet address mapping for this client
-		h.clientWallets[client] = walletAddress
-
 		log.Printf("Player %s (wallet: %s) joining %s team in game %s", client.id, walletAddress, msg.Team, msg.GameID)
 
 		// First check if player is already in the game
@@ -398,7 +513,7 @@ func (h *Hub) handleMessage(msg *Message, client *Client) {
 
 		if existingTeam != "" {
 			// Player is already in the game - this is a reconnection
-			if existingTeam != msg.Team {
+			if msg.Team != game.TeamAuto && existingTeam != msg.Team {
 				// Player is trying to join a different team than they're already on
 				log.Printf("Player %s is already on %s team, cannot join %s team", walletAddress, existingTeam, msg.Team)
 				h.sendErrorToClient(client, fmt.Sprintf("You are already on the %s team. Cannot switch teams.", existingTeam))
@@ -435,15 +550,30 @@ func (h *Hub) handleMessage(msg *Message, client *Client) {
 			log.Printf("Player %s successfully reconnected to %s team", walletAddress, existingTeam)
 		} else {
 			// Player is not in the game yet - attempt to add them to the requested team
-			if err := h.gameManager.AddPlayerToTeam(msg.GameID, walletAddress, msg.Team); err != nil {
+			team, err := h.gameManager.AddPlayerToTeam(msg.GameID, walletAddress, msg.Team)
+			if err != nil {
 				log.Printf("Failed to add player %s to team %s: %v", walletAddress, msg.Team, err)
 				h.sendErrorToClient(client, err.Error())
 				return
 			}
 
 			// Success - update local state
-			h.clientTeams[walletAddress] = msg.Team
-			log.Printf("Successfully added player %s to %s team", walletAddress, msg.Team)
+			h.clientTeams[walletAddress] = team
+			log.Printf("Successfully added player %s to %s team", walletAddress, team)
+
+			// Tell the player which team they ended up on, which matters for "auto" joins
+			statusMsg := &Message{
+				Type:          TypePlayerStatus,
+				GameID:        msg.GameID,
+				WalletAddress: walletAddress,
+				Team:          team,
+			}
+			if data, err := json.Marshal(statusMsg); err == nil {
+				select {
+				case client.send <- data:
+				default:
+				}
+			}
 		}
 
 		// Broadcast updated games list since player count may have changed
@@ -507,16 +637,11 @@ func (h *Hub) handleMessage(msg *Message, client *Client) {
 		})
 
 	case TypeCheckPlayerStatus:
-		walletAddress := msg.WalletAddress
-		if walletAddress == "" {
-			log.Printf("No wallet address provided for player status check from client %s", client.id)
-			h.sendErrorToClient(client, "Wallet address is required for player status check")
+		walletAddress, ok := h.authenticatedWallet(client)
+		if !ok {
 			return
 		}
 
-		// Store the wallet address mapping for this client
-		h.clientWallets[client] = walletAddress
-
 		log.Printf("Checking player status for wallet %s in game %s", walletAddress, msg.GameID)
 
 		// Check if player is already in the game
@@ -569,14 +694,11 @@ func (h *Hub) handleMessage(msg *Message, client *Client) {
 		}
 
 	case TypeRequestPermitSignature:
-		walletAddress := msg.WalletAddress
-		chainID := msg.ChainId
-
-		if walletAddress == "" {
-			log.Printf("No wallet address provided for permit signature request from client %s", client.id)
-			h.sendErrorToClient(client, "Wallet address is required for permit signature")
+		walletAddress, ok := h.authenticatedWallet(client)
+		if !ok {
 			return
 		}
+		chainID := msg.ChainId
 
 		if chainID == 0 {
 			log.Printf("No chain ID provided for permit signature request from client %s", client.id)
@@ -584,71 +706,39 @@ func (h *Hub) handleMessage(msg *Message, client *Client) {
 			return
 		}
 
-		// Store the wallet address mapping for this client
-		h.clientWallets[client] = walletAddress
-
-		log.Printf("Creating permit signature request for wallet %s on chain %d", walletAddress, chainID)
+		h.sendPermitRequest(client, walletAddress, chainID)
 
-		// Get or create permit signature data using manager's function
-		permitData, typedData, err := h.gameManager.GetOrCreatePlayerPermit(walletAddress, chainID)
-		if err != nil {
-			log.Printf("Failed to get/create permit for player %s: %v", walletAddress, err)
-			h.sendErrorToClient(client, fmt.Sprintf("Failed to create permit: %v", err))
+	case TypeRequestVotePermit:
+		walletAddress, ok := h.authenticatedWallet(client)
+		if !ok {
 			return
 		}
 
-		// If we got existing permit data (typedData will be nil), just confirm it's valid
-		if typedData == nil {
-			log.Printf("Player %s already has valid permit for chain %d", walletAddress, chainID)
-			// Send confirmation that permit is already valid
-			confirmMsg := &Message{
-				Type:          "permit_valid",
-				WalletAddress: walletAddress,
-				ChainId:       chainID,
-			}
-			if data, err := json.Marshal(confirmMsg); err == nil {
-				select {
-				case client.send <- data:
-					log.Printf("Sent permit confirmation to client %s", client.id)
-				default:
-					log.Printf("Failed to send permit confirmation to client %s - channel full", client.id)
-				}
-			}
+		if msg.GameID == "" {
+			log.Printf("No game ID provided for vote permit request from client %s", client.id)
+			h.sendErrorToClient(client, "Game ID is required for vote permit")
 			return
 		}
 
-		// Store the permit data in the game manager for later signature completion
-		h.gameManager.StorePlayerPermit(walletAddress, permitData)
-
-		// Send permit signature request to client
-		permitMsg := &Message{
-			Type:          TypePermitSignature,
-			WalletAddress: walletAddress,
-			ChainId:       chainID,
-			TypedData:     typedData,
+		chainID := msg.ChainId
+		if chainID == 0 {
+			chainID = h.gameManager.GetPlayerChainID(walletAddress)
 		}
-
-		if data, err := json.Marshal(permitMsg); err == nil {
-			select {
-			case client.send <- data:
-				log.Printf("Sent permit signature request to client %s", client.id)
-			default:
-				log.Printf("Failed to send permit signature request to client %s - channel full", client.id)
-			}
-		} else {
-			log.Printf("Failed to marshal permit signature request: %v", err)
+		if chainID == 0 {
+			log.Printf("No chain ID provided for vote permit request from client %s", client.id)
+			h.sendErrorToClient(client, "Chain ID is required for vote permit")
+			return
 		}
 
-	case TypePermitSignature:
-		walletAddress := msg.WalletAddress
-		signature := msg.Signature
-		chainID := msg.ChainId
+		h.sendVotePermitRequest(client, walletAddress, msg.GameID, chainID)
 
-		if walletAddress == "" {
-			log.Printf("No wallet address provided for permit signature from client %s", client.id)
-			h.sendErrorToClient(client, "Wallet address is required")
+	case TypePermitSignature:
+		walletAddress, ok := h.authenticatedWallet(client)
+		if !ok {
 			return
 		}
+		signature := msg.Signature
+		chainID := msg.ChainId
 
 		if signature == "" {
 			log.Printf("No signature provided for permit from client %s", client.id)
@@ -662,160 +752,432 @@ func (h *Hub) handleMessage(msg *Message, client *Client) {
 			return
 		}
 
-		// Store the wallet address mapping for this client
-		h.clientWallets[client] = walletAddress
-
 		log.Printf("Received permit signature from wallet %s on chain %d", walletAddress, chainID)
 
-		// Get the existing permit data for this player
-		permitData := h.gameManager.GetPlayerPermit(walletAddress)
-		if permitData == nil {
-			log.Printf("No permit data found for player %s", walletAddress)
-			h.sendErrorToClient(client, "No permit data found. Please request a new permit.")
+		// Only signatures made by the permit owner over the permit we issued are stored
+		h.checkPermit(permitCheck{client: client, walletAddress: walletAddress, chainID: chainID}, func() error {
+			return h.gameManager.SubmitPermitSignature(walletAddress, chainID, signature)
+		})
+	}
+}
+
+// checkPermit verifies a permit signature in the background, since rebuilding a USDC permit can read the chain, and
+// hands the outcome to the hub loop
+func (h *Hub) checkPermit(check permitCheck, verify func() error) {
+	go func() {
+		check.err = verify()
+		h.permitChecks <- check
+	}()
+}
+
+// handlePermitCheck answers a verified permit signature, casting the vote it pays for if there is one
+func (h *Hub) handlePermitCheck(check permitCheck) {
+	client, walletAddress, chainID := check.client, check.walletAddress, check.chainID
+	if h.authWallets[client] != walletAddress {
+		log.Printf("Dropping permit check of %s, client %s disconnected or signed out", walletAddress, client.id)
+		return
+	}
+
+	if check.vote != nil {
+		if check.err != nil {
+			log.Printf("Rejected vote permit from wallet %s in game %s: %v", walletAddress, check.vote.GameID, check.err)
+			var permitErr *game.PermitError
+			if errors.As(check.err, &permitErr) {
+				h.sendErrorCodeToClient(client, permitErr.Code, permitErr.Message)
+				h.sendVotePermitRequest(client, walletAddress, check.vote.GameID, chainID)
+			} else {
+				h.sendErrorToClient(client, check.err.Error())
+			}
 			return
 		}
 
-		// Verify the chain ID matches
-		if permitData.ChainID != uint64(chainID) {
-			log.Printf("Chain ID mismatch for player %s: expected %d, got %d", walletAddress, permitData.ChainID, chainID)
-			h.sendErrorToClient(client, "Chain ID mismatch")
+		// The player may have left their team while the signature was checked
+		team := h.gameManager.GetPlayerTeam(check.vote.GameID, walletAddress)
+		if team == "" {
+			h.sendErrorToClient(client, "You must join a team before voting")
 			return
 		}
+		h.castVote(client, walletAddress, check.vote, team, chainID)
+		return
+	}
 
-		// Store the signature
-		permitData.Signature = signature
-		h.gameManager.StorePlayerPermit(walletAddress, permitData)
+	if check.err != nil {
+		log.Printf("Rejected permit signature from wallet %s on chain %d: %v", walletAddress, chainID, check.err)
+		var permitErr *game.PermitError
+		if errors.As(check.err, &permitErr) {
+			h.sendErrorCodeToClient(client, permitErr.Code, permitErr.Message)
+		} else {
+			h.sendErrorToClient(client, check.err.Error())
+		}
+		return
+	}
+
+	log.Printf("Successfully stored permit signature for player %s on chain %d", walletAddress, chainID)
 
-		log.Printf("Successfully stored permit signature for player %s on chain %d", walletAddress, chainID)
+	validMsg := &Message{
+		Type:          TypePermitValid,
+		WalletAddress: walletAddress,
+		ChainId:       chainID,
+	}
+	if data, err := json.Marshal(validMsg); err == nil {
+		select {
+		case client.send <- data:
+		default:
+			log.Printf("Failed to send permit confirmation to client %s - channel full", client.id)
+		}
 	}
 }
 
-func (h *Hub) addToMatchmaking(client *Client, walletAddress string) {
-	// Check if wallet address is already in queue
-	if _, exists := h.matchmakingQueue[walletAddress]; exists {
-		log.Printf("Wallet %s already in matchmaking queue", walletAddress)
-		h.sendErrorToClient(client, "You are already in the matchmaking queue")
+// castVote records a player's vote and broadcasts the game's new votes and pot. The stake is paid in the background
+// and a vote it cannot pay is withdrawn through handleVoteRejected.
+func (h *Hub) castVote(client *Client, walletAddress string, msg *Message, team string, chainID uint32) {
+	if err := h.gameManager.VoteForMove(msg.GameID, walletAddress, msg.Move, team, chainID); err != nil {
+		log.Printf("Vote failed for player %s: %v", walletAddress, err)
+		var permitErr *game.PermitError
+		if errors.As(err, &permitErr) {
+			// The permit cannot pay this vote, so ask for a new signature right away
+			h.sendErrorCodeToClient(client, permitErr.Code, err.Error())
+			h.requestPlayerPermit(client, walletAddress, msg.GameID, chainID)
+			return
+		}
+		h.sendErrorToClient(client, err.Error())
 		return
 	}
 
-	h.matchmakingQueue[walletAddress] = client
-	log.Printf("Matchmaking queue size: %d", len(h.matchmakingQueue))
+	// Get updated votes and game stats
+	votes := h.gameManager.GetVotes(msg.GameID)
+	stats := h.gameManager.GetGameStats(msg.GameID)
 
-	// Check if we can create a match
-	if len(h.matchmakingQueue) >= 2 {
-		// Get first 2 wallet addresses
-		walletAddresses := make([]string, 0, 2)
-		clients := make([]*Client, 0, 2)
+	// Create vote update message with all stats
+	updateMsg := &Message{
+		Type:   TypeVoteUpdate,
+		GameID: msg.GameID,
+		Votes:  votes,
+	}
 
-		for walletAddr, client := range h.matchmakingQueue {
-			walletAddresses = append(walletAddresses, walletAddr)
-			clients = append(clients, client)
-			if len(walletAddresses) == 2 {
-				break
-			}
+	h.updateStats(stats, updateMsg)
+
+	h.broadcastToGame(msg.GameID, updateMsg)
+
+	// Broadcast updated games list since pot has changed
+	h.broadcastGamesListUpdate()
+}
+
+func (h *Hub) addToMatchmaking(client *Client, walletAddress string, segment matchmaking.Segment, partyID string, partySize int) {
+	if _, err := h.matchmaker.Enqueue(walletAddress, segment, partyID, partySize); err != nil {
+		log.Printf("Failed to queue wallet %s for matchmaking: %v", walletAddress, err)
+		h.sendErrorToClient(client, err.Error())
+		return
+	}
+
+	h.matchmakingClients[walletAddress] = client
+
+	// Try to match right away instead of waiting for the next tick
+	h.runMatchmaking()
+}
+
+func (h *Hub) removeFromMatchmaking(client *Client) {
+	// Find and remove the wallet address associated with this client
+	for walletAddr, c := range h.matchmakingClients {
+		if c == client {
+			delete(h.matchmakingClients, walletAddr)
+			h.matchmaker.Remove(walletAddr)
+			log.Printf("Removed wallet %s from matchmaking queue", walletAddr)
+			break
 		}
+	}
+}
 
-		// Remove matched players from queue
-		delete(h.matchmakingQueue, walletAddresses[0])
-		delete(h.matchmakingQueue, walletAddresses[1])
+// runMatchmaking starts matched games, places late joiners, applies timeouts and reports queue status
+func (h *Hub) runMatchmaking() {
+	matches, assignments, timedOut := h.matchmaker.Process(time.Now())
 
-		player1Wallet := walletAddresses[0]
-		player2Wallet := walletAddresses[1]
-		player1Client := clients[0]
-		player2Client := clients[1]
+	for _, match := range matches {
+		h.createMatchGame(gameOptionsForSegment(match.Segment), createdGame{match: &match})
+	}
 
-		// Create the game
-		gameState := h.gameManager.GetOrCreateGame()
+	for _, assignment := range assignments {
+		h.joinOpenGame(assignment)
+	}
 
-		// Create unique game ID
-		gameID := gameState.ID
-		// Assign sides randomly
-		assignedSides := []string{"white", "black"}
-		if time.Now().Unix()%2 == 0 {
-			assignedSides[0], assignedSides[1] = assignedSides[1], assignedSides[0]
+	for _, ticket := range timedOut {
+		log.Printf("Matchmaking timed out for %v after %s, falling back to %s game",
+			ticket.Members, time.Since(ticket.EnqueuedAt).Round(time.Second), h.matchmaker.Config().Fallback)
+
+		// A party is seated together on one side, so the game cannot hold it to the team imbalance limit
+		options := gameOptionsForSegment(ticket.Segment)
+		options.MaxImbalance = 0
+		h.createMatchGame(options, createdGame{ticket: ticket})
+	}
+
+	h.sendQueueStatuses()
+}
+
+// createMatchGame creates a game in the background, since its contract is created on-chain, and hands it back
+// to the hub loop once it exists
+func (h *Hub) createMatchGame(options game.GameOptions, pending createdGame) {
+	go func() {
+		pending.gameState, pending.err = h.gameManager.CreateGame(options)
+		h.createdGames <- pending
+	}()
+}
+
+// handleCreatedGame seats the matched players in their new game, or tells them it could not be created
+func (h *Hub) handleCreatedGame(created createdGame) {
+	if created.err == nil {
+		if created.match != nil {
+			h.startMatch(*created.match, created.gameState)
+		} else {
+			h.startFallbackGame(created.ticket, created.gameState)
 		}
+		return
+	}
 
-		// Add players to teams using their wallet addresses
-		if err := h.gameManager.AddPlayerToTeam(gameID, player1Wallet, assignedSides[0]); err != nil {
-			log.Printf("Failed to add player %s to team %s: %v", player1Wallet, assignedSides[0], err)
-			h.sendErrorToClient(player1Client, "Failed to join team")
-			return
+	var members []string
+	if created.match != nil {
+		for _, ticket := range slices.Concat(created.match.White, created.match.Black) {
+			members = append(members, ticket.Members...)
 		}
+	} else {
+		members = created.ticket.Members
+	}
 
-		if err := h.gameManager.AddPlayerToTeam(gameID, player2Wallet, assignedSides[1]); err != nil {
-			log.Printf("Failed to add player %s to team %s: %v", player2Wallet, assignedSides[1], err)
-			h.sendErrorToClient(player2Client, "Failed to join team")
-			return
+	log.Printf("Error: Failed to create matchmaking game for %v: %v", members, created.err)
+	for _, walletAddress := range members {
+		client := h.matchmakingClients[walletAddress]
+		delete(h.matchmakingClients, walletAddress)
+		if client != nil {
+			h.sendErrorToClient(client, "Failed to create game, please join the queue again")
 		}
+	}
+}
+
+// startMatch seats a balanced match in its new game and notifies every player
+func (h *Hub) startMatch(match matchmaking.Match, gameState *game.GameState) {
+	gameID := gameState.ID
 
-		// Notify both players
-		matchMsg1 := &Message{
-			Type:         TypeMatchFound,
-			GameID:       gameID,
-			Players:      []string{player1Client.id, player2Client.id},
-			AssignedSide: assignedSides[0],
+	// Randomly swap colors so the higher rated side does not always play white
+	white, black := match.White, match.Black
+	if time.Now().Unix()%2 == 0 {
+		white, black = black, white
+		match.White, match.Black = white, black
+	}
+
+	players := make([]string, 0)
+	joined := make(map[string]string) // walletAddress -> side
+	for _, seat := range interleaveSeats(white, black) {
+		walletAddress, side := seat[0], seat[1]
+		if _, err := h.gameManager.AddPlayerToTeam(gameID, walletAddress, side); err != nil {
+			log.Printf("Failed to add player %s to team %s: %v", walletAddress, side, err)
+			if client := h.matchmakingClients[walletAddress]; client != nil {
+				h.sendErrorToClient(client, "Failed to join team")
+			}
+			delete(h.matchmakingClients, walletAddress)
+			continue
+		}
+		joined[walletAddress] = side
+		if client := h.matchmakingClients[walletAddress]; client != nil {
+			players = append(players, client.sessionID)
 		}
+	}
 
-		matchMsg2 := &Message{
-			Type:         TypeMatchFound,
-			GameID:       gameID,
-			Players:      []string{player1Client.id, player2Client.id},
-			AssignedSide: assignedSides[1],
+	for walletAddress, side := range joined {
+		client := h.matchmakingClients[walletAddress]
+		delete(h.matchmakingClients, walletAddress)
+		if client == nil {
+			log.Printf("Matched wallet %s is no longer connected", walletAddress)
+			continue
 		}
+		h.sendMatchFound(client, walletAddress, gameID, side, players, "")
+	}
 
-		// Send match found messages
-		if data1, err := json.Marshal(matchMsg1); err == nil {
-			log.Printf("Sending match_found to player1 (%s): %s", player1Client.id, string(data1))
-			select {
-			case player1Client.send <- data1:
-				log.Printf("✅ Successfully sent match_found to player1 (%s)", player1Client.id)
-			default:
-				log.Printf("❌ Failed to send match_found to player1 (%s) - channel full", player1Client.id)
+	// Late joiners fill the smaller side until both reach the target size
+	h.matchmaker.RegisterOpenGame(gameID, match)
+
+	log.Printf("Match created: %s with %d white and %d black players (target %dv%d)",
+		gameID, countPlayers(white), countPlayers(black), match.Segment.TeamSize, match.Segment.TeamSize)
+
+	// Broadcast updated games list since a new game was created
+	h.broadcastGamesListUpdate()
+}
+
+// joinOpenGame adds a late joiner's party to the smaller side of a running game
+func (h *Hub) joinOpenGame(assignment matchmaking.Assignment) {
+	for _, walletAddress := range assignment.Ticket.Members {
+		client := h.matchmakingClients[walletAddress]
+		delete(h.matchmakingClients, walletAddress)
+
+		if _, err := h.gameManager.AddPlayerToTeam(assignment.GameID, walletAddress, assignment.Side); err != nil {
+			log.Printf("Failed to add late joiner %s to team %s: %v", walletAddress, assignment.Side, err)
+			if client != nil {
+				h.sendErrorToClient(client, "Failed to join team")
 			}
-		} else {
-			log.Printf("❌ Failed to marshal match_found for player1 (%s): %v", player1Client.id, err)
+			continue
 		}
 
-		if data2, err := json.Marshal(matchMsg2); err == nil {
-			log.Printf("Sending match_found to player2 (%s): %s", player2Client.id, string(data2))
-			select {
-			case player2Client.send <- data2:
-				log.Printf("✅ Successfully sent match_found to player2 (%s)", player2Client.id)
-			default:
-				log.Printf("❌ Failed to send match_found to player2 (%s) - channel full", player2Client.id)
+		if client != nil {
+			h.sendMatchFound(client, walletAddress, assignment.GameID, assignment.Side, []string{client.sessionID}, "")
+		}
+		log.Printf("Late joiner %s placed on %s team in game %s", walletAddress, assignment.Side, assignment.GameID)
+	}
+
+	// Broadcast updated games list since player count changed
+	h.broadcastGamesListUpdate()
+}
+
+// startFallbackGame puts a ticket that waited too long into its new lobby or bot game
+func (h *Hub) startFallbackGame(ticket *matchmaking.Ticket, gameState *game.GameState) {
+	fallback := h.matchmaker.Config().Fallback
+	gameID := gameState.ID
+
+	assignedSide := "white"
+	if time.Now().Unix()%2 == 0 {
+		assignedSide = "black"
+	}
+
+	players := make([]string, 0, ticket.Size()+1)
+	joined := make([]string, 0, ticket.Size())
+	for _, walletAddress := range ticket.Members {
+		if _, err := h.gameManager.AddPlayerToTeam(gameID, walletAddress, assignedSide); err != nil {
+			log.Printf("Failed to add player %s to team %s: %v", walletAddress, assignedSide, err)
+			if client := h.matchmakingClients[walletAddress]; client != nil {
+				h.sendErrorToClient(client, "Failed to join team")
 			}
+			delete(h.matchmakingClients, walletAddress)
+			continue
+		}
+		joined = append(joined, walletAddress)
+		if client := h.matchmakingClients[walletAddress]; client != nil {
+			players = append(players, client.sessionID)
+		}
+	}
+
+	if fallback == matchmaking.FallbackBot {
+		botSide := "black"
+		if assignedSide == "black" {
+			botSide = "white"
+		}
+		botID, err := h.gameManager.AddBotToTeam(gameID, botSide)
+		if err != nil {
+			log.Printf("Failed to add bot to game %s: %v", gameID, err)
 		} else {
-			log.Printf("❌ Failed to marshal match_found for player2 (%s): %v", player2Client.id, err)
+			players = append(players, botID)
 		}
+	}
 
-		// Add both players to the game
-		h.AddClientToGame(player1Client, gameID)
-		h.AddClientToGame(player2Client, gameID)
+	for _, walletAddress := range joined {
+		client := h.matchmakingClients[walletAddress]
+		delete(h.matchmakingClients, walletAddress)
+		if client != nil {
+			h.sendMatchFound(client, walletAddress, gameID, assignedSide, players, fallback)
+		}
+	}
 
-		// Set their teams locally using wallet addresses
-		h.clientTeams[player1Wallet] = assignedSides[0]
-		h.clientTeams[player2Wallet] = assignedSides[1]
+	// Broadcast updated games list so lobby games can be joined by others
+	h.broadcastGamesListUpdate()
+}
 
-		log.Printf("Match created: %s with players %s (%s) and %s (%s)",
-			gameID, player1Wallet, assignedSides[0], player2Wallet, assignedSides[1])
+// sendMatchFound notifies a player of their new game and adds them to its room
+func (h *Hub) sendMatchFound(client *Client, walletAddress, gameID, assignedSide string, players []string, fallback string) {
+	matchMsg := &Message{
+		Type:         TypeMatchFound,
+		GameID:       gameID,
+		Players:      players,
+		AssignedSide: assignedSide,
+		Fallback:     fallback,
+	}
 
-		// Broadcast updated games list since a new game was created
-		h.broadcastGamesListUpdate()
+	if data, err := json.Marshal(matchMsg); err == nil {
+		log.Printf("Sending match_found to %s: %s", client.id, string(data))
+		select {
+		case client.send <- data:
+			log.Printf("✅ Successfully sent match_found to %s", client.id)
+		default:
+			log.Printf("❌ Failed to send match_found to %s - channel full", client.id)
+		}
+	} else {
+		log.Printf("❌ Failed to marshal match_found for %s: %v", client.id, err)
 	}
+
+	h.AddClientToGame(client, gameID)
+	h.clientTeams[walletAddress] = assignedSide
 }
 
-func (h *Hub) removeFromMatchmaking(client *Client) {
-	// Find and remove the wallet address associated with this client
-	for walletAddr, c := range h.matchmakingQueue {
-		if c == client {
-			delete(h.matchmakingQueue, walletAddr)
-			log.Printf("Removed wallet %s from matchmaking queue", walletAddr)
-			break
+// sendQueueStatuses tells every queued client its position and estimated wait
+func (h *Hub) sendQueueStatuses() {
+	for walletAddress, client := range h.matchmakingClients {
+		status, queued := h.matchmaker.Status(walletAddress)
+		if !queued {
+			continue
+		}
+
+		statusMsg := &Message{
+			Type:          TypeQueueStatus,
+			WalletAddress: walletAddress,
+			QueuePosition: status.Position,
+			QueueSize:     status.QueueSize,
+			EstimatedWait: int(status.EstimatedWait / time.Second),
+			PartyMembers:  status.PartyMembers,
+			PartySize:     status.PartySize,
+		}
+
+		if data, err := json.Marshal(statusMsg); err == nil {
+			select {
+			case client.send <- data:
+			default:
+			}
+		}
+	}
+}
+
+// interleaveSeats orders wallets white, black, white, ... so team limits hold while a match is seated
+func interleaveSeats(white, black []*matchmaking.Ticket) [][2]string {
+	var whiteWallets, blackWallets []string
+	for _, ticket := range white {
+		whiteWallets = append(whiteWallets, ticket.Members...)
+	}
+	for _, ticket := range black {
+		blackWallets = append(blackWallets, ticket.Members...)
+	}
+
+	seats := make([][2]string, 0, len(whiteWallets)+len(blackWallets))
+	for i := 0; i < len(whiteWallets) || i < len(blackWallets); i++ {
+		if i < len(whiteWallets) {
+			seats = append(seats, [2]string{whiteWallets[i], "white"})
+		}
+		if i < len(blackWallets) {
+			seats = append(seats, [2]string{blackWallets[i], "black"})
 		}
 	}
+	return seats
+}
+
+// countPlayers returns the number of wallets across a list of tickets
+func countPlayers(tickets []*matchmaking.Ticket) int {
+	count := 0
+	for _, ticket := range tickets {
+		count += ticket.Size()
+	}
+	return count
+}
+
+// gameOptionsForSegment converts a matchmaking segment into game options
+func gameOptionsForSegment(segment matchmaking.Segment) game.GameOptions {
+	options := game.DefaultGameOptions()
+	options.TurnSeconds = segment.TimeControl
+	options.Stake = money.USDC(segment.StakeLevel)
+	options.MaxTeamSize = segment.TeamSize
+	options.Template = game.TemplateMatchmaking
+	return options
 }
 
 func (h *Hub) broadcastToGame(gameID string, msg *Message) {
+	h.eventsMu.Lock()
+	defer h.eventsMu.Unlock()
+
+	// Number the event and keep it for clients that resume later
+	h.stampEventUnsafe(gameID, msg)
+
 	data, err := json.Marshal(msg)
 	if err != nil {
 		log.Printf("Error marshaling message: %v", err)
@@ -898,6 +1260,11 @@ func (h *Hub) handleMoveResult(gameID, move string) {
 	// Broadcast the move result to all clients in the game
 	h.broadcastToGame(gameID, moveMsg)
 
+	// Stop placing late joiners once the game's teams are locked
+	if h.gameManager.AreTeamsLocked(gameID) {
+		h.matchmaker.CloseOpenGame(gameID)
+	}
+
 	// Broadcast updated games list since move number has changed
 	h.broadcastGamesListUpdate()
 }
@@ -916,6 +1283,18 @@ func (h *Hub) handleGameEnd(gameID, winner, reason string, gameStats map[string]
 
 	// Add all game statistics to the message
 	h.updateStats(gameStats, gameEndMsg)
+	if fee, ok := gameStats["fee"].(money.Amount); ok {
+		gameEndMsg.Fee = &fee
+	}
+	if netPayout, ok := gameStats["netPayout"].(money.Amount); ok {
+		gameEndMsg.NetPayout = &netPayout
+	}
+	if refunds, ok := gameStats["refunds"].([]game.Refund); ok {
+		gameEndMsg.Refunds = refunds
+	}
+	if settlementStatus, ok := gameStats["settlementStatus"].(string); ok {
+		gameEndMsg.SettlementStatus = settlementStatus
+	}
 
 	// Extract and add team player statistics
 	if whiteTeamPlayers, ok := gameStats["whiteTeamPlayers"].([]map[string]any); ok {
@@ -925,10 +1304,10 @@ func (h *Hub) handleGameEnd(gameID, winner, reason string, gameStats map[string]
 			gameEndMsg.WhiteTeamPlayers[i] = PlayerStats{
 				WalletAddress: player["walletAddress"].(string),
 				TotalVotes:    player["totalVotes"].(int),
-				TotalSpent:    player["totalSpent"].(float64),
+				TotalSpent:    player["totalSpent"].(money.Amount),
 			}
-			log.Printf("White player %d: %s - %d votes, %.3f USDC", i+1,
-				player["walletAddress"].(string), player["totalVotes"].(int), player["totalSpent"].(float64))
+			log.Printf("White player %d: %s - %d votes, %s USDC", i+1,
+				player["walletAddress"].(string), player["totalVotes"].(int), player["totalSpent"].(money.Amount))
 		}
 	} else {
 		log.Printf("No white team players found in game stats")
@@ -941,28 +1320,25 @@ func (h *Hub) handleGameEnd(gameID, winner, reason string, gameStats map[string]
 			gameEndMsg.BlackTeamPlayers[i] = PlayerStats{
 				WalletAddress: player["walletAddress"].(string),
 				TotalVotes:    player["totalVotes"].(int),
-				TotalSpent:    player["totalSpent"].(float64),
+				TotalSpent:    player["totalSpent"].(money.Amount),
 			}
-			log.Printf("Black player %d: %s - %d votes, %.3f USDC", i+1,
-				player["walletAddress"].(string), player["totalVotes"].(int), player["totalSpent"].(float64))
+			log.Printf("Black player %d: %s - %d votes, %s USDC", i+1,
+				player["walletAddress"].(string), player["totalVotes"].(int), player["totalSpent"].(money.Amount))
 		}
 	} else {
 		log.Printf("No black team players found in game stats")
 	}
 
+	// Number the game end as the game's last event, kept for the resume window
+	h.eventsMu.Lock()
+	h.stampEventUnsafe(gameID, gameEndMsg)
+	h.eventLogs[gameID].endedAt = time.Now()
+
 	// Broadcast to all clients in the game with their individual vote counts
 	if room, exists := h.gameRooms[gameID]; exists {
 		for client := range room {
 			// Create a copy of the message for each client with their personal vote count
-			clientMsg := *gameEndMsg
-			if playerVotes, ok := gameStats["playerTotalVotes"].(map[string]int); ok {
-				// Get the wallet address for this client
-				if walletAddress, found := h.clientWallets[client]; found {
-					if votes, found := playerVotes[walletAddress]; found {
-						clientMsg.PlayerVotes = votes
-					}
-				}
-			}
+			clientMsg := personalizeGameEnd(gameEndMsg, h.clientWallets[client])
 
 			// Send personalized message to each client
 			if data, err := json.Marshal(clientMsg); err == nil {
@@ -975,6 +1351,15 @@ func (h *Hub) handleGameEnd(gameID, winner, reason string, gameStats map[string]
 			}
 		}
 	}
+	h.eventsMu.Unlock()
+
+	// Stop placing late joiners and update matchmaking ratings from the final result
+	h.matchmaker.CloseOpenGame(gameID)
+	h.matchmaker.RecordResult(
+		humanWallets(gameStats["whiteTeamPlayers"]),
+		humanWallets(gameStats["blackTeamPlayers"]),
+		winner,
+	)
 
 	// Store the ended game info before cleaning up
 	endedGameInfo := &GameInfo{
@@ -996,13 +1381,13 @@ func (h *Hub) handleGameEnd(gameID, winner, reason string, gameStats map[string]
 	if currentMove, ok := gameStats["currentMove"].(int); ok {
 		endedGameInfo.CurrentMove = currentMove
 	}
-	if totalPot, ok := gameStats["totalPot"].(float64); ok {
+	if totalPot, ok := gameStats["totalPot"].(money.Amount); ok {
 		endedGameInfo.TotalPot = totalPot
 	}
-	if whitePot, ok := gameStats["whitePot"].(float64); ok {
+	if whitePot, ok := gameStats["whitePot"].(money.Amount); ok {
 		endedGameInfo.WhitePot = whitePot
 	}
-	if blackPot, ok := gameStats["blackPot"].(float64); ok {
+	if blackPot, ok := gameStats["blackPot"].(money.Amount); ok {
 		endedGameInfo.BlackPot = blackPot
 	}
 
@@ -1018,7 +1403,7 @@ func (h *Hub) handleGameEnd(gameID, winner, reason string, gameStats map[string]
 			endedGameInfo.WhiteTeamPlayers[i] = PlayerStats{
 				WalletAddress: player["walletAddress"].(string),
 				TotalVotes:    player["totalVotes"].(int),
-				TotalSpent:    player["totalSpent"].(float64),
+				TotalSpent:    player["totalSpent"].(money.Amount),
 			}
 		}
 	}
@@ -1029,7 +1414,7 @@ func (h *Hub) handleGameEnd(gameID, winner, reason string, gameStats map[string]
 			endedGameInfo.BlackTeamPlayers[i] = PlayerStats{
 				WalletAddress: player["walletAddress"].(string),
 				TotalVotes:    player["totalVotes"].(int),
-				TotalSpent:    player["totalSpent"].(float64),
+				TotalSpent:    player["totalSpent"].(money.Amount),
 			}
 		}
 	}
@@ -1044,6 +1429,24 @@ func (h *Hub) handleGameEnd(gameID, winner, reason string, gameStats map[string]
 	h.broadcastGamesListUpdate()
 }
 
+// humanWallets extracts the non-bot wallet addresses from a team player list
+func humanWallets(teamPlayers any) []string {
+	players, ok := teamPlayers.([]map[string]any)
+	if !ok {
+		return nil
+	}
+
+	wallets := make([]string, 0, len(players))
+	for _, player := range players {
+		walletAddress, ok := player["walletAddress"].(string)
+		if !ok || strings.HasPrefix(walletAddress, game.BotAddressPrefix) {
+			continue
+		}
+		wallets = append(wallets, walletAddress)
+	}
+	return wallets
+}
+
 // collectGamesInfo gathers information about games based on filter
 // filter can be "active", "ended", or "all" for all games
 func (h *Hub) collectGamesInfo(filter string) []GameInfo {
@@ -1106,13 +1509,13 @@ func (h *Hub) collectGamesInfo(filter string) []GameInfo {
 			if currentMove, ok := stats["currentMove"].(int); ok {
 				gameInfo.CurrentMove = currentMove
 			}
-			if totalPot, ok := stats["totalPot"].(float64); ok {
+			if totalPot, ok := stats["totalPot"].(money.Amount); ok {
 				gameInfo.TotalPot = totalPot
 			}
-			if whitePot, ok := stats["whitePot"].(float64); ok {
+			if whitePot, ok := stats["whitePot"].(money.Amount); ok {
 				gameInfo.WhitePot = whitePot
 			}
-			if blackPot, ok := stats["blackPot"].(float64); ok {
+			if blackPot, ok := stats["blackPot"].(money.Amount); ok {
 				gameInfo.BlackPot = blackPot
 			}
 			if currentTurn, ok := stats["currentTurn"].(string); ok {
@@ -1121,6 +1524,12 @@ func (h *Hub) collectGamesInfo(filter string) []GameInfo {
 			if board, ok := stats["board"].([][]string); ok {
 				gameInfo.Board = board
 			}
+			if maxTeamSize, ok := stats["maxTeamSize"].(int); ok {
+				gameInfo.MaxTeamSize = maxTeamSize
+			}
+			if teamsLocked, ok := stats["teamsLocked"].(bool); ok {
+				gameInfo.TeamsLocked = teamsLocked
+			}
 
 			gamesList = append(gamesList, gameInfo)
 		}
@@ -1178,14 +1587,14 @@ func (h *Hub) updateStats(stats map[string]any, moveMsg *Message) {
 	if bttv, ok := stats["blackTeamTotalVotes"].(int); ok {
 		moveMsg.BlackTeamTotalVotes = bttv
 	}
-	if tp, ok := stats["totalPot"].(float64); ok {
-		moveMsg.TotalPot = tp
+	if tp, ok := stats["totalPot"].(money.Amount); ok {
+		moveMsg.TotalPot = &tp
 	}
-	if whitePot, ok := stats["whitePot"].(float64); ok {
-		moveMsg.WhitePot = whitePot
+	if whitePot, ok := stats["whitePot"].(money.Amount); ok {
+		moveMsg.WhitePot = &whitePot
 	}
-	if blackPot, ok := stats["blackPot"].(float64); ok {
-		moveMsg.BlackPot = blackPot
+	if blackPot, ok := stats["blackPot"].(money.Amount); ok {
+		moveMsg.BlackPot = &blackPot
 	}
 	if ct, ok := stats["currentTurn"].(string); ok {
 		moveMsg.CurrentTurn = ct
@@ -1226,11 +1635,169 @@ func (h *Hub) broadcastGamesListUpdate() {
 	})
 }
 
+// handleVoteRejected tells a player their vote was withdrawn and refreshes the game's votes and pot
+func (h *Hub) handleVoteRejected(rejection game.VoteRejection) {
+	rejectedMsg := &Message{
+		Type:          TypeVoteRejected,
+		GameID:        rejection.GameID,
+		Move:          rejection.Move,
+		WalletAddress: rejection.WalletAddress,
+		Error:         rejection.Reason,
+		ErrorCode:     rejection.Code,
+		RolledBack:    rejection.RolledBack,
+	}
+	if funds := rejection.Funds; funds != nil {
+		rejectedMsg.Stake = &funds.Required
+		rejectedMsg.Available = &funds.Available
+		rejectedMsg.TopUp = &funds.TopUp
+	}
+
+	// A used up allowance needs a new permit and a vote permit is spent by the vote, but a low balance or a missing
+	// Permit2 approval needs the player to act on-chain first
+	needsPermit := rejection.Code != ""
+	if rejection.Funds != nil && !h.gameManager.UsesVotePermits(rejection.WalletAddress) {
+		needsPermit = rejection.Code == game.FundsErrorAllowance
+	}
+
+	if data, err := json.Marshal(rejectedMsg); err == nil {
+		permitRequested := false
+		for client, walletAddress := range h.clientWallets {
+			if !strings.EqualFold(walletAddress, rejection.WalletAddress) {
+				continue
+			}
+			select {
+			case client.send <- data:
+			default:
+				log.Printf("Failed to send vote rejection to client %s: channel full", client.id)
+			}
+			// One client asks for the new signature, each request replaces the player's pending permit
+			if needsPermit && !permitRequested {
+				h.requestPlayerPermit(client, walletAddress, rejection.GameID, rejection.ChainID)
+				permitRequested = true
+			}
+		}
+	} else {
+		log.Printf("Failed to marshal vote rejection for %s: %v", rejection.WalletAddress, err)
+	}
+
+	updateMsg := &Message{
+		Type:   TypeVoteUpdate,
+		GameID: rejection.GameID,
+		Votes:  h.gameManager.GetVotes(rejection.GameID),
+	}
+	h.updateStats(h.gameManager.GetGameStats(rejection.GameID), updateMsg)
+	h.broadcastToGame(rejection.GameID, updateMsg)
+
+	// The pot shrank, so the games list changed too
+	h.broadcastGamesListUpdate()
+}
+
+// requestPlayerPermit asks a player for a new signature in the permit mode they use
+func (h *Hub) requestPlayerPermit(client *Client, walletAddress, gameID string, chainID uint32) {
+	if h.gameManager.UsesVotePermits(walletAddress) {
+		h.sendVotePermitRequest(client, walletAddress, gameID, chainID)
+		return
+	}
+	h.sendPermitRequest(client, walletAddress, chainID)
+}
+
+// sendVotePermitRequest sends a player the typed data of a one-shot transfer of their next vote's stake in a game
+func (h *Hub) sendVotePermitRequest(client *Client, walletAddress, gameID string, chainID uint32) {
+	log.Printf("Creating vote permit request for wallet %s in game %s on chain %d", walletAddress, gameID, chainID)
+
+	_, typedData, err := h.gameManager.CreateVotePermit(gameID, walletAddress, chainID)
+	if err != nil {
+		log.Printf("Failed to create vote permit for player %s: %v", walletAddress, err)
+		h.sendErrorToClient(client, fmt.Sprintf("Failed to create vote permit: %v", err))
+		return
+	}
+
+	permitMsg := &Message{
+		Type:          TypeVotePermit,
+		GameID:        gameID,
+		WalletAddress: walletAddress,
+		ChainId:       chainID,
+		TypedData:     typedData,
+	}
+
+	if data, err := json.Marshal(permitMsg); err == nil {
+		select {
+		case client.send <- data:
+			log.Printf("Sent vote permit request to client %s", client.id)
+		default:
+			log.Printf("Failed to send vote permit request to client %s - channel full", client.id)
+		}
+	} else {
+		log.Printf("Failed to marshal vote permit request: %v", err)
+	}
+}
+
+// sendPermitRequest sends a player the typed data of a new permit to sign, or permit_valid if their permit still has allowance
+func (h *Hub) sendPermitRequest(client *Client, walletAddress string, chainID uint32) {
+	log.Printf("Creating permit signature request for wallet %s on chain %d", walletAddress, chainID)
+
+	// Get or create permit signature data using manager's function
+	permitData, typedData, err := h.gameManager.GetOrCreatePlayerPermit(walletAddress, chainID)
+	if err != nil {
+		log.Printf("Failed to get/create permit for player %s: %v", walletAddress, err)
+		h.sendErrorToClient(client, fmt.Sprintf("Failed to create permit: %v", err))
+		return
+	}
+
+	// If we got existing permit data (typedData will be nil), just confirm it's valid
+	if typedData == nil {
+		log.Printf("Player %s already has valid permit for chain %d", walletAddress, chainID)
+		// Send confirmation that permit is already valid
+		confirmMsg := &Message{
+			Type:          TypePermitValid,
+			WalletAddress: walletAddress,
+			ChainId:       chainID,
+		}
+		if data, err := json.Marshal(confirmMsg); err == nil {
+			select {
+			case client.send <- data:
+				log.Printf("Sent permit confirmation to client %s", client.id)
+			default:
+				log.Printf("Failed to send permit confirmation to client %s - channel full", client.id)
+			}
+		}
+		return
+	}
+
+	// Store the permit data in the game manager for later signature completion
+	h.gameManager.StorePlayerPermit(walletAddress, permitData)
+
+	// Send permit signature request to client
+	permitMsg := &Message{
+		Type:          TypePermitSignature,
+		WalletAddress: walletAddress,
+		ChainId:       chainID,
+		TypedData:     typedData,
+	}
+
+	if data, err := json.Marshal(permitMsg); err == nil {
+		select {
+		case client.send <- data:
+			log.Printf("Sent permit signature request to client %s", client.id)
+		default:
+			log.Printf("Failed to send permit signature request to client %s - channel full", client.id)
+		}
+	} else {
+		log.Printf("Failed to marshal permit signature request: %v", err)
+	}
+}
+
 // Send error message to a specific client
 func (h *Hub) sendErrorToClient(client *Client, errorMsg string) {
+	h.sendErrorCodeToClient(client, "", errorMsg)
+}
+
+// sendErrorCodeToClient sends an error message with a machine-readable code to a specific client
+func (h *Hub) sendErrorCodeToClient(client *Client, code, errorMsg string) {
 	errorMessage := &Message{
-		Type:  TypeError,
-		Error: errorMsg,
+		Type:      TypeError,
+		Error:     errorMsg,
+		ErrorCode: code,
 	}
 
 	if data, err := json.Marshal(errorMessage); err == nil {
@@ -0,0 +1,266 @@
+package websocket
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"blockchess/internal/auth"
+)
+
+// sessionSweepInterval is how often expired sessions and event logs are dropped
+const sessionSweepInterval = 10 * time.Second
+
+// session is the state a connection leaves behind so a reconnect can pick it up
+type session struct {
+	id         string
+	client     *Client   // Connection holding the session, nil while it waits to be resumed
+	wallet     string    // Address the connection signed in with
+	team       string    // Team of the signed-in wallet
+	games      []string  // Games the connection was watching
+	detachedAt time.Time // When the connection went away
+}
+
+// eventLog numbers one game's broadcasts and keeps the most recent ones for replay
+type eventLog struct {
+	seq     uint64
+	events  []*Message // Oldest first, ending with event seq
+	endedAt time.Time  // Set once the game ended, the log is dropped after the resume window
+}
+
+// append numbers an event and keeps it, dropping the oldest beyond the limit
+func (l *eventLog) append(msg *Message, limit int) {
+	l.seq++
+	msg.Seq = l.seq
+	l.events = append(l.events, msg)
+	if len(l.events) > limit {
+		l.events = l.events[len(l.events)-limit:]
+	}
+}
+
+// since returns the events after seq, or false when some of them are no longer kept
+func (l *eventLog) since(seq uint64) ([]*Message, bool) {
+	if seq > l.seq {
+		return nil, false
+	}
+	first := l.seq - uint64(len(l.events)) + 1
+	if seq+1 < first {
+		return nil, false
+	}
+	return l.events[seq+1-first:], true
+}
+
+// stampEventUnsafe numbers a game broadcast and keeps it for replay, timer ticks only carry the last event's number (caller must hold the lock)
+func (h *Hub) stampEventUnsafe(gameID string, msg *Message) {
+	events, exists := h.eventLogs[gameID]
+	if !exists {
+		events = &eventLog{}
+		h.eventLogs[gameID] = events
+	}
+
+	if msg.Type == TypeTimerTick {
+		msg.Seq = events.seq
+		return
+	}
+	events.append(msg, h.authConfig.ReplayEvents)
+}
+
+// eventSeqUnsafe returns the number of a game's last event (caller must hold the lock)
+func (h *Hub) eventSeqUnsafe(gameID string) uint64 {
+	if events, exists := h.eventLogs[gameID]; exists {
+		return events.seq
+	}
+	return 0
+}
+
+// openSession starts a new session for a connection and sends it the session ID and resume token
+func (h *Hub) openSession(client *Client) {
+	h.sessions[client.sessionID] = &session{id: client.sessionID, client: client}
+
+	h.sendToClient(client, &Message{
+		Type:        TypeClientConnected,
+		ClientID:    client.sessionID,
+		ResumeToken: h.sessionTokens.Issue(client.sessionID),
+	})
+}
+
+// detachSession keeps a disconnecting client's session for the resume window, must run before its state is removed
+func (h *Hub) detachSession(client *Client) {
+	sess, exists := h.sessions[client.sessionID]
+	if !exists || sess.client != client {
+		return
+	}
+
+	sess.client = nil
+	sess.detachedAt = time.Now()
+	sess.wallet = h.authWallets[client]
+	sess.team = h.clientTeams[sess.wallet]
+	sess.games = nil
+	for gameID, room := range h.gameRooms {
+		if room[client] {
+			sess.games = append(sess.games, gameID)
+		}
+	}
+}
+
+// handleResume moves a previous session onto this connection and replays the game events it missed. Only a session
+// whose connection dropped within the resume window can be taken, anything else needs a new sign-in.
+func (h *Hub) handleResume(msg *Message, client *Client) {
+	sessionID, err := h.sessionTokens.Verify(msg.ResumeToken)
+	if err != nil {
+		log.Printf("Client %s failed to resume: %v", client.id, err)
+		var authErr *auth.Error
+		if errors.As(err, &authErr) {
+			h.sendErrorCodeToClient(client, authErr.Code, authErr.Message)
+		} else {
+			h.sendErrorToClient(client, err.Error())
+		}
+		return
+	}
+
+	sess, exists := h.sessions[sessionID]
+	if !exists || (sess.client == nil && time.Since(sess.detachedAt) > h.authConfig.ResumeWindow) {
+		log.Printf("Client %s tried to resume expired session %s", client.id, sessionID)
+		h.sendErrorCodeToClient(client, auth.ErrorResumeExpired, "Session expired, continuing with a new session")
+		return
+	}
+
+	if sess.client != client {
+		// A token copied from a live connection must not take over its session
+		if sess.client != nil {
+			log.Printf("Client %s tried to resume session %s while its connection is still open", client.id, sessionID)
+			h.sendErrorCodeToClient(client, auth.ErrorRequired, "Session is still connected, please sign in with your wallet")
+			return
+		}
+
+		delete(h.sessions, client.sessionID)
+		client.sessionID = sess.id
+		sess.client = client
+	}
+
+	// Restore the sign-in unless this connection already signed in
+	walletAddress, signedIn := h.authWallets[client]
+	if !signedIn && sess.wallet != "" {
+		walletAddress = sess.wallet
+		h.authWallets[client] = walletAddress
+		h.clientWallets[client] = walletAddress
+	}
+	if walletAddress == sess.wallet && sess.team != "" && h.clientTeams[walletAddress] == "" {
+		h.clientTeams[walletAddress] = sess.team
+	}
+
+	log.Printf("Client %s resumed with wallet %q and %d games", sess.id, walletAddress, len(sess.games))
+	h.sendToClient(client, &Message{
+		Type:          TypeResumed,
+		ClientID:      sess.id,
+		ResumeToken:   h.sessionTokens.Issue(sess.id),
+		WalletAddress: walletAddress,
+	})
+
+	for _, gameID := range sess.games {
+		lastSeq, known := msg.LastSeq[gameID]
+		h.resumeGame(client, gameID, lastSeq, known)
+	}
+	sess.games = nil
+}
+
+// resumeGame rejoins a game and replays the events after lastSeq, or sends a snapshot when they are no longer kept
+func (h *Hub) resumeGame(client *Client, gameID string, lastSeq uint64, known bool) {
+	h.eventsMu.Lock()
+	defer h.eventsMu.Unlock()
+
+	if events, exists := h.eventLogs[gameID]; exists && known {
+		if missed, ok := events.since(lastSeq); ok {
+			walletAddress := h.clientWallets[client]
+			for _, event := range missed {
+				if event.Type == TypeGameEnd {
+					event = personalizeGameEnd(event, walletAddress)
+				}
+				h.sendToClient(client, event)
+			}
+			if _, ended := h.endedGames[gameID]; !ended {
+				h.AddClientToGame(client, gameID)
+			}
+			return
+		}
+	}
+
+	h.sendGameSnapshotUnsafe(client, gameID)
+}
+
+// joinGameRoom adds a client to a game room and sends it the game's current state
+func (h *Hub) joinGameRoom(client *Client, gameID string) {
+	h.eventsMu.Lock()
+	defer h.eventsMu.Unlock()
+
+	h.sendGameSnapshotUnsafe(client, gameID)
+}
+
+// sendGameSnapshotUnsafe sends the full state of a game numbered with its last event, joining its room while it runs (caller must hold the lock)
+func (h *Hub) sendGameSnapshotUnsafe(client *Client, gameID string) {
+	seq := h.eventSeqUnsafe(gameID)
+
+	// An ended game's last event is its game end, which already holds the final state
+	if events, exists := h.eventLogs[gameID]; exists && !events.endedAt.IsZero() && len(events.events) > 0 {
+		if last := events.events[len(events.events)-1]; last.Type == TypeGameEnd {
+			h.sendToClient(client, personalizeGameEnd(last, h.clientWallets[client]))
+			return
+		}
+	}
+
+	if ended, exists := h.endedGames[gameID]; exists {
+		h.sendToClient(client, &Message{
+			Type:          TypeGameEnd,
+			GameID:        gameID,
+			Winner:        ended.Winner,
+			GameEndReason: ended.EndReason,
+			Board:         ended.Board,
+			Seq:           seq,
+			Snapshot:      true,
+		})
+		return
+	}
+
+	if h.gameManager.GetGame(gameID) == nil {
+		return
+	}
+	h.AddClientToGame(client, gameID)
+
+	snapshot := &Message{
+		Type:     TypeVoteUpdate,
+		GameID:   gameID,
+		Votes:    h.gameManager.GetVotes(gameID),
+		Seq:      seq,
+		Snapshot: true,
+	}
+	h.updateStats(h.gameManager.GetGameStats(gameID), snapshot)
+	h.sendToClient(client, snapshot)
+}
+
+// personalizeGameEnd returns a copy of a game end message with the player's own vote count
+func personalizeGameEnd(msg *Message, walletAddress string) *Message {
+	clientMsg := *msg
+	if votes, found := msg.PlayerTotalVotes[walletAddress]; found && walletAddress != "" {
+		clientMsg.PlayerVotes = votes
+	}
+	return &clientMsg
+}
+
+// sweepSessions drops sessions and ended games' event logs that are past the resume window
+func (h *Hub) sweepSessions() {
+	cutoff := time.Now().Add(-h.authConfig.ResumeWindow)
+
+	for id, sess := range h.sessions {
+		if sess.client == nil && sess.detachedAt.Before(cutoff) {
+			delete(h.sessions, id)
+		}
+	}
+
+	h.eventsMu.Lock()
+	defer h.eventsMu.Unlock()
+	for gameID, events := range h.eventLogs {
+		if !events.endedAt.IsZero() && events.endedAt.Before(cutoff) {
+			delete(h.eventLogs, gameID)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/subtle"
+	"encoding/json"
 	"flag"
 	"log"
 	"net/http"
@@ -10,8 +12,11 @@ import (
 	"strings"
 	"syscall"
 
+	"blockchess/internal/cctp"
 	"blockchess/internal/client"
 	"blockchess/internal/game"
+	"blockchess/internal/ledger"
+	"blockchess/internal/matchmaking"
 	"blockchess/internal/websocket"
 
 	"github.com/gorilla/mux"
@@ -25,26 +30,55 @@ func main() {
 	log.Println("Initializing blockchain clients...")
 	clients, err := client.InitializeClients()
 	if err != nil {
-		log.Printf("Warning: Failed to initialize some blockchain clients: %v", err)
+		log.Fatalf("Failed to initialize blockchain clients: %v", err)
 	}
 
+	// Verify the deployed contracts and signer of every chain, disabling the chains that fail
+	clients.Preflight(client.LoadPreflightConfig())
+
+	// Open the accounting ledger
+	gameLedger, err := ledger.New(client.GetEnv("LEDGER_PATH", "data/ledger.jsonl"))
+	if err != nil {
+		log.Fatalf("Failed to open ledger: %v", err)
+	}
+
+	// Start relaying CCTP transfers to their destination chains
+	relayer, err := cctp.NewRelayer(clients, client.GetEnv("CCTP_TRANSFERS_PATH", "data/cctp_transfers.json"), cctp.LoadRelayerConfig())
+	if err != nil {
+		log.Fatalf("Failed to load CCTP transfers: %v", err)
+	}
+	go relayer.Run()
+
 	// Set up graceful shutdown
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-c
 		log.Println("Shutting down gracefully...")
-		if clients != nil {
-			clients.Close()
+		clients.Close()
+		if err := gameLedger.Close(); err != nil {
+			log.Printf("Warning: Failed to close ledger: %v", err)
 		}
 		os.Exit(0)
 	}()
 
 	// Create game manager with blockchain clients
-	gameManager := game.NewGamesManager(clients)
+	gameManager := game.NewGamesManager(clients, gameLedger, relayer)
+
+	// Matchmaking falls back to the default game options for settings the client leaves out
+	gameDefaults := game.DefaultGameOptions()
+	matchmaker := matchmaking.NewService(matchmaking.LoadConfig(matchmaking.Defaults{
+		StakeLevel:   gameDefaults.Stake.Units(),
+		TimeControl:  gameDefaults.TurnSeconds,
+		TeamSize:     1,
+		MaxImbalance: gameDefaults.MaxImbalance,
+	}))
 
 	// Create WebSocket hub
-	hub := websocket.NewHub(gameManager)
+	hub, err := websocket.NewHub(gameManager, matchmaker, *addr)
+	if err != nil {
+		log.Fatalf("Failed to create WebSocket hub: %v", err)
+	}
 	go hub.Run()
 
 	// Create router
@@ -55,6 +89,68 @@ func main() {
 		websocket.ServeWS(hub, w, r)
 	})
 
+	// Startup checks of every chain
+	r.HandleFunc("/api/status/preflight", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(clients.PreflightReports())
+	}).Methods(http.MethodGet)
+
+	// RPC health of every chain
+	r.HandleFunc("/api/status/chains", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(clients.ChainStatuses())
+	}).Methods(http.MethodGet)
+
+	// Operator endpoints
+	admin := requireAdmin(client.GetEnv("ADMIN_TOKEN", ""))
+
+	// Ledger statements expose every wallet's history, so only operators can read them
+	r.HandleFunc("/api/ledger/wallets/{wallet}", admin(gameLedger.HandleWalletStatement)).Methods(http.MethodGet)
+	r.HandleFunc("/api/ledger/games/{gameId}", admin(gameLedger.HandleGameBalanceSheet)).Methods(http.MethodGet)
+
+	r.HandleFunc("/api/admin/games/{gameId}/cancel", admin(func(w http.ResponseWriter, r *http.Request) {
+		gameID := mux.Vars(r)["gameId"]
+		refunds, err := gameManager.CancelGame(gameID)
+		if err != nil && len(refunds) == 0 {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		response := map[string]any{
+			"gameId":  gameID,
+			"refunds": refunds,
+		}
+		if err != nil {
+			response["error"] = err.Error()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	})).Methods(http.MethodPost)
+
+	r.HandleFunc("/api/admin/settlements", admin(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(gameManager.SettlementJobs())
+	})).Methods(http.MethodGet)
+
+	r.HandleFunc("/api/admin/settlements/{gameId}/retry", admin(func(w http.ResponseWriter, r *http.Request) {
+		if err := gameManager.RetrySettlement(mux.Vars(r)["gameId"]); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})).Methods(http.MethodPost)
+
+	r.HandleFunc("/api/admin/settlements/{gameId}/refund", admin(func(w http.ResponseWriter, r *http.Request) {
+		if err := gameManager.RefundSettlement(mux.Vars(r)["gameId"]); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})).Methods(http.MethodPost)
+
+	r.HandleFunc("/api/admin/transfers", admin(relayer.HandleTransfers)).Methods(http.MethodGet)
+	r.HandleFunc("/api/admin/transfers/{transferId}/retry", admin(relayer.HandleRetryTransfer)).Methods(http.MethodPost)
+
 	// Serve static files and handle client-side routing
 	r.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Get the path
@@ -83,3 +179,17 @@ func main() {
 		log.Fatal("ListenAndServe: ", err)
 	}
 }
+
+// requireAdmin wraps operator handlers so they only run with the admin bearer token
+func requireAdmin(token string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			provided := []byte(r.Header.Get("Authorization"))
+			if token == "" || subtle.ConstantTimeCompare(provided, []byte("Bearer "+token)) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
@@ -1,6 +1,6 @@
 import { useState, useEffect } from 'react';
 import { Crown, Users } from 'lucide-react';
-import { useAccount } from 'wagmi';
+import { useAccount, useChainId } from 'wagmi';
 import WalletConnect from './components/WalletConnect';
 import GameLobby from './components/GameLobby';
 import GameView from './components/GameView';
@@ -13,6 +13,7 @@ type AppView = 'lobby' | 'game' | 'game-not-found';
 
 function App() {
   const { isConnected, address } = useAccount();
+  const chainId = useChainId();
 
   // Application-level state
   const [currentView, setCurrentView] = useState<AppView>('lobby');
@@ -162,6 +163,17 @@ function App() {
     }
   }, [isConnected, address]);
 
+  // Sign the websocket connection in with the connected wallet
+  useEffect(() => {
+    if (isConnected && address && chainId) {
+      wsService.signIn(address, chainId).catch((error) => {
+        console.error('Wallet sign-in failed:', error);
+      });
+    } else {
+      wsService.signOut();
+    }
+  }, [isConnected, address, chainId]);
+
   // Set up game not found callback
   useEffect(() => {
     const unsubscribe = gameService.onGameNotFound((gameId: string) => {
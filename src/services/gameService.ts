@@ -49,6 +49,12 @@ class GameService {
             console.log('🔗 Updated playerId to match backend:', this.playerId);
         });
 
+        // A resumed session keeps its original ID
+        wsService.on('resumed', (data) => {
+            console.log('🔗 Session resumed with ID:', data.clientId);
+            this.playerId = data.clientId;
+        });
+
         // Set up matchmaking listener
         wsService.on('match_found', (data) => {
             console.log('🎯 Match found event received:', data);
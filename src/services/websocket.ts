@@ -72,11 +72,23 @@ export interface TotalNumberOfPlayers {
 export interface ClientConnected {
     type: 'client_connected';
     clientId: string;
+    resumeToken: string; // Signed token to resume this session after a reconnect
+}
+
+export interface SessionResumed {
+    type: 'resumed';
+    clientId: string;
+    resumeToken: string;
+    walletAddress?: string; // Signed-in wallet restored with the session
 }
 
 export interface ErrorMessage {
     type: 'error';
     error: string;
+    errorCode?: string;
+    stake?: number;     // Stake of a vote refused with insufficient_funds or allowance_exhausted
+    available?: number; // USDC balance or allowance the player has
+    topUp?: number;     // USDC missing to pay the stake
 }
 
 export interface PlayerStatus {
@@ -110,10 +122,32 @@ export interface PermitValid {
     chainId: number;
 }
 
-export type ServerMessage = VoteUpdate | MoveResult | TimerTick | MatchFound | GamesList | GamesListUpdate | TotalNumberOfPlayers | ClientConnected | ErrorMessage | PlayerStatus | ValidMovesResponse | Permit2Data | PermitSignature | PermitValid;
+export interface VotePermit {
+    type: 'vote_permit';
+    gameId: string;
+    walletAddress: string;
+    chainId: number;
+    typedData: any; // EIP-712 PermitTransferFrom of exactly one vote's stake
+}
+
+export interface AuthChallenge {
+    type: 'auth_challenge';
+    walletAddress: string;
+    chainId: number;
+    authMessage: string; // EIP-4361 message to sign with personal_sign
+    nonce: string;
+}
+
+export interface AuthSuccess {
+    type: 'auth_success';
+    walletAddress: string;
+    chainId: number;
+}
+
+export type ServerMessage = VoteUpdate | MoveResult | TimerTick | MatchFound | GamesList | GamesListUpdate | TotalNumberOfPlayers | ClientConnected | ErrorMessage | PlayerStatus | ValidMovesResponse | Permit2Data | PermitSignature | PermitValid | VotePermit | AuthChallenge | AuthSuccess | SessionResumed;
 
 export interface ClientMessage {
-    type: 'join_game' | 'vote_move' | 'join_team' | 'watch_game' | 'join_matchmaking' | 'leave_matchmaking' | 'request_games_list' | 'request_filtered_games_list' | 'check_player_status' | 'get_valid_moves' | 'request_permit2' | 'submit_permit2_signature' | 'request_permit_signature' | 'permit_signature';
+    type: 'join_game' | 'vote_move' | 'join_team' | 'watch_game' | 'join_matchmaking' | 'leave_matchmaking' | 'request_games_list' | 'request_filtered_games_list' | 'check_player_status' | 'get_valid_moves' | 'request_permit2' | 'submit_permit2_signature' | 'request_permit_signature' | 'permit_signature' | 'request_vote_permit' | 'auth_challenge' | 'auth_login' | 'resume';
     gameId?: string;
     move?: string;
     team?: 'white' | 'black';
@@ -123,6 +157,8 @@ export interface ClientMessage {
     signature?: string;
     typedData?: any;
     chainId?: number;
+    resumeToken?: string;
+    lastSeq?: Record<string, number>; // Last event seen per game, sent with resume
 }
 
 export class WebSocketService {
@@ -133,6 +169,10 @@ export class WebSocketService {
     private listeners: Map<string, Set<(data: any) => void>> = new Map();
     private currentGameId: string | null = null;
     private playerIdGetter: (() => string) | null = null;
+    private authAddress: string | null = null;
+    private authChainId: number | null = null;
+    private resumeToken: string | null = null;
+    private lastSeq: Map<string, number> = new Map();
 
     constructor() {
         this.connect();
@@ -154,9 +194,15 @@ export class WebSocketService {
                 console.log('WebSocket connected');
                 this.reconnectAttempts = 0;
 
-                // Rejoin current game if any
-                if (this.currentGameId) {
-                    this.joinGame(this.currentGameId);
+                // Resume the previous session and get the missed game events, or start over without one
+                if (this.resumeToken) {
+                    this.send({
+                        type: 'resume',
+                        resumeToken: this.resumeToken,
+                        lastSeq: Object.fromEntries(this.lastSeq)
+                    });
+                } else {
+                    this.restoreSession();
                 }
             };
 
@@ -196,10 +242,40 @@ export class WebSocketService {
         }
     }
 
+    // Sign in and rejoin the current game on a connection that did not resume them
+    private restoreSession(resumed?: SessionResumed) {
+        // A new connection is anonymous until the wallet signs in again
+        if (this.authAddress && this.authChainId &&
+            resumed?.walletAddress?.toLowerCase() !== this.authAddress.toLowerCase()) {
+            this.signIn(this.authAddress, this.authChainId).catch((error) => {
+                console.error('Failed to sign in after reconnect:', error);
+            });
+        }
+
+        // Rejoin current game if any, a resumed session is already back in its games
+        if (this.currentGameId && !resumed) {
+            this.joinGame(this.currentGameId);
+        }
+    }
+
     private handleMessage(data: any) {
         console.log('📨 WebSocket message received:', data);
         console.log('📨 Message type:', data.type);
 
+        // Track the session and the last event of each game for resuming after a reconnect
+        if (data.type === 'client_connected' || data.type === 'resumed') {
+            this.resumeToken = data.resumeToken;
+        }
+        if (data.type === 'resumed') {
+            this.restoreSession(data);
+        }
+        if (data.type === 'error' && data.errorCode?.startsWith('resume_')) {
+            this.restoreSession();
+        }
+        if (data.gameId && data.seq && data.seq > (this.lastSeq.get(data.gameId) ?? 0)) {
+            this.lastSeq.set(data.gameId, data.seq);
+        }
+
         // Special logging for matchmaking messages
         if (data.type === 'match_found') {
             console.log('🎯 MATCH_FOUND MESSAGE RECEIVED!');
@@ -246,12 +322,14 @@ export class WebSocketService {
         });
     }
 
-    public voteMove(gameId: string, move: string) {
+    // signature is the player's signature over the game's vote_permit when they stake each vote separately
+    public voteMove(gameId: string, move: string, signature?: string) {
         this.send({
             type: 'vote_move',
             gameId: gameId,
             move,
-            playerId: this.playerIdGetter?.() || ''
+            playerId: this.playerIdGetter?.() || '',
+            signature
         });
     }
 
@@ -335,6 +413,66 @@ export class WebSocketService {
         });
     }
 
+    // Sign in with Ethereum: the server issues a message, the wallet signs it and the connection acts as that address
+    public signIn(walletAddress: string, chainId: number): Promise<string> {
+        this.authAddress = walletAddress;
+        this.authChainId = chainId;
+
+        // The connection signs in as soon as it opens
+        if (!this.ws || this.ws.readyState !== WebSocket.OPEN) {
+            return Promise.reject(new Error('WebSocket is not connected yet, signing in once it is'));
+        }
+
+        return new Promise((resolve, reject) => {
+            const cleanup = () => {
+                unsubscribeChallenge();
+                unsubscribeSuccess();
+                unsubscribeError();
+            };
+
+            const unsubscribeChallenge = this.on('auth_challenge', async (data: AuthChallenge) => {
+                try {
+                    const signature = await window.ethereum.request({
+                        method: 'personal_sign',
+                        params: [data.authMessage, walletAddress],
+                    });
+                    this.send({ type: 'auth_login', signature });
+                } catch (error) {
+                    cleanup();
+                    reject(error);
+                }
+            });
+
+            const unsubscribeSuccess = this.on('auth_success', (data: AuthSuccess) => {
+                cleanup();
+                resolve(data.walletAddress);
+            });
+
+            const unsubscribeError = this.on('error', (data: ErrorMessage) => {
+                if (data.errorCode?.startsWith('auth_')) {
+                    cleanup();
+                    reject(new Error(data.error));
+                }
+            });
+
+            this.send({ type: 'auth_challenge', walletAddress, chainId });
+        });
+    }
+
+    public signOut() {
+        this.authAddress = null;
+        this.authChainId = null;
+    }
+
+    public requestVotePermit(gameId: string, walletAddress: string, chainId: number) {
+        this.send({
+            type: 'request_vote_permit',
+            gameId,
+            walletAddress,
+            chainId
+        });
+    }
+
     public on(type: string, callback: (data: any) => void): () => void {
         if (!this.listeners.has(type)) {
             this.listeners.set(type, new Set());